@@ -3,13 +3,17 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 
+	"frpgo/api/internal/errorx"
 	"frpgo/api/internal/handler"
 	"frpgo/api/internal/svc"
 	"frpgo/config"
+	"frpgo/pkg/metrics"
 
 	"github.com/zeromicro/go-zero/core/conf"
 	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 var configFile = flag.String("f", "etc/frpgo-api.yaml", "the config file")
@@ -25,6 +29,16 @@ func main() {
 
 	ctx := svc.NewServiceContext(c)
 	handler.RegisterHandlers(server, ctx)
+	if metrics.PrometheusEnabled() {
+		server.AddRoute(rest.Route{
+			Method:  http.MethodGet,
+			Path:    "/metrics",
+			Handler: metrics.Handler().ServeHTTP,
+		})
+	}
+
+	// 统一错误返回格式: {"errcode": "", "errtxt": ""}
+	httpx.SetErrorHandlerCtx(errorx.ErrorHandler)
 
 	fmt.Printf("\nStarting server at %s:%d...\n\n", c.Host, c.Port)
 	server.Start()
@@ -0,0 +1,118 @@
+package errorx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"frpgo/client"
+	"frpgo/client/inspect"
+	"frpgo/client/proxy"
+	"frpgo/fmgr/webhook"
+)
+
+// errcode返回值，与http状态码保持一致
+const (
+	CodeOK          = "0"
+	CodeBadRequest  = "400"
+	CodeNotFound    = "404"
+	CodeConflict    = "409"
+	CodeInternal    = "500"
+	CodeBadGateway  = "502"
+	CodeUnavailable = "503"
+)
+
+// CodeError 携带http状态码及errcode/errtxt的错误
+type CodeError struct {
+	Status  int
+	ErrCode string
+	ErrTxt  string
+}
+
+// ErrorResp 错误返回结构，字段与StopTunnelResp/ListCaptureRequestResp一致
+type ErrorResp struct {
+	ErrCode string `json:"errcode"`
+	ErrTxt  string `json:"errtxt"`
+}
+
+var ErrServiceUnavailable = NewUnavailable("frp service is not running")
+
+func New(status int, errCode string, errTxt string) *CodeError {
+	return &CodeError{
+		Status:  status,
+		ErrCode: errCode,
+		ErrTxt:  errTxt,
+	}
+}
+
+func NewBadRequest(errTxt string) *CodeError {
+	return New(http.StatusBadRequest, CodeBadRequest, errTxt)
+}
+
+func NewNotFound(errTxt string) *CodeError {
+	return New(http.StatusNotFound, CodeNotFound, errTxt)
+}
+
+func NewConflict(errTxt string) *CodeError {
+	return New(http.StatusConflict, CodeConflict, errTxt)
+}
+
+func NewInternal(errTxt string) *CodeError {
+	return New(http.StatusInternalServerError, CodeInternal, errTxt)
+}
+
+func NewBadGateway(errTxt string) *CodeError {
+	return New(http.StatusBadGateway, CodeBadGateway, errTxt)
+}
+
+func NewUnavailable(errTxt string) *CodeError {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, errTxt)
+}
+
+func (e *CodeError) Error() string {
+	return e.ErrTxt
+}
+
+func (e *CodeError) Resp() *ErrorResp {
+	return &ErrorResp{
+		ErrCode: e.ErrCode,
+		ErrTxt:  e.ErrTxt,
+	}
+}
+
+// FromClientError 将client.Service返回的错误转换为CodeError
+func FromClientError(err error) *CodeError {
+	var ce *CodeError
+	switch {
+	case errors.As(err, &ce):
+		return ce
+	case errors.Is(err, client.ErrInvalidConfig), errors.Is(err, inspect.ErrBodyTruncated), errors.Is(err, inspect.ErrInvalidRequestURI),
+		errors.Is(err, webhook.ErrInvalidSubscriber), errors.Is(err, client.ErrInvalidListOptions),
+		errors.Is(err, client.ErrInvalidProxyFilter):
+		return NewBadRequest(err.Error())
+	case errors.Is(err, proxy.ErrProxyExist), errors.Is(err, client.ErrVisitorExist),
+		errors.Is(err, webhook.ErrSubscriberExist), errors.Is(err, webhook.ErrSubscriberStatic):
+		return NewConflict(err.Error())
+	case errors.Is(err, proxy.ErrProxyNotFound), errors.Is(err, client.ErrVisitorNotFound),
+		errors.Is(err, inspect.ErrCaptureNotFound), errors.Is(err, webhook.ErrDeliveryNotFound),
+		errors.Is(err, webhook.ErrSubscriberNotFound):
+		return NewNotFound(err.Error())
+	case errors.Is(err, inspect.ErrReplay):
+		return NewBadGateway(err.Error())
+	case errors.Is(err, client.ErrControlNotReady), errors.Is(err, webhook.ErrNotSetup):
+		return NewUnavailable(err.Error())
+	default:
+		return NewInternal(err.Error())
+	}
+}
+
+// ErrorHandler 通过httpx.SetErrorHandlerCtx注册，统一错误返回格式。
+// 非CodeError的错误（如请求参数解析失败）按400处理
+func ErrorHandler(_ context.Context, err error) (int, any) {
+	var ce *CodeError
+	if errors.As(err, &ce) {
+		return ce.Status, ce.Resp()
+	}
+
+	return http.StatusBadRequest, NewBadRequest(err.Error()).Resp()
+}
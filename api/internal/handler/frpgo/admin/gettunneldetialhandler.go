@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"net/http"
+
+	"frpgo/api/internal/logic/frpgo/admin"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetTunnelDetialHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.GetTunnelDetailReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := admin.NewGetTunnelDetialLogic(r.Context(), svcCtx)
+		resp, err := l.GetTunnelDetial(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package events
+
+import (
+	"net/http"
+
+	"frpgo/api/internal/logic/frpgo/events"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func StreamEventsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.StreamEventsReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		// 推送过程中直接写w，仅在开始推送前出错时返回错误
+		l := events.NewStreamEventsLogic(r.Context(), svcCtx, w, r)
+		err := l.StreamEvents(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		}
+	}
+}
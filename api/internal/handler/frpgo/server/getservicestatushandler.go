@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"frpgo/api/internal/logic/frpgo/server"
+	"frpgo/api/internal/svc"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetServiceStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := server.NewGetServiceStatusLogic(r.Context(), svcCtx)
+		resp, err := l.GetServiceStatus()
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"net/http"
+
+	"frpgo/api/internal/logic/frpgo/webhook"
+	"frpgo/api/internal/svc"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func ListDeadLettersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := webhook.NewListDeadLettersLogic(r.Context(), svcCtx)
+		resp, err := l.ListDeadLetters()
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -3,9 +3,13 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	frpgoadmin "frpgo/api/internal/handler/frpgo/admin"
+	frpgoevents "frpgo/api/internal/handler/frpgo/events"
+	frpgoserver "frpgo/api/internal/handler/frpgo/server"
 	frpgotest "frpgo/api/internal/handler/frpgo/test"
+	frpgowebhook "frpgo/api/internal/handler/frpgo/webhook"
 	"frpgo/api/internal/svc"
 
 	"github.com/zeromicro/go-zero/rest"
@@ -35,16 +39,110 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/tunnels/:name",
 				Handler: frpgoadmin.StopTunnelHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/tunnels/:name/pause",
+				Handler: frpgoadmin.PauseTunnelHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/tunnels/:name/resume",
+				Handler: frpgoadmin.ResumeTunnelHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/tunnels/batch",
+				Handler: frpgoadmin.BatchTunnelsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/tunnels",
+				Handler: frpgoadmin.ListTunnelsHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/tunnels/:name",
 				Handler: frpgoadmin.GetTunnelDetialHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPatch,
+				Path:    "/tunnels/:name",
+				Handler: frpgoadmin.UpdateTunnelHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/requests/http/:limit/:tunnel_name",
 				Handler: frpgoadmin.ListCapturedRequestHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/requests/http/:tunnel_name/:id/replay",
+				Handler: frpgoadmin.ReplayCapturedRequestHandler(serverCtx),
+			},
+		},
+		rest.WithPrefix("/api"),
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/webhook/dead-letters",
+				Handler: frpgowebhook.ListDeadLettersHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/webhook/dead-letters/:id/redrive",
+				Handler: frpgowebhook.RedriveDeadLetterHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/webhook/dead-letters/redrive",
+				Handler: frpgowebhook.RedriveAllDeadLettersHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/webhook/subscribers",
+				Handler: frpgowebhook.ListSubscribersHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/webhook/subscribers",
+				Handler: frpgowebhook.CreateSubscriberHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/webhook/subscribers/:name",
+				Handler: frpgowebhook.DeleteSubscriberHandler(serverCtx),
+			},
+		},
+		rest.WithPrefix("/api"),
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/events",
+				Handler: frpgoevents.StreamEventsHandler(serverCtx),
+			},
+		},
+		rest.WithPrefix("/api"),
+		rest.WithTimeout(3600000*time.Millisecond),
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/servers",
+				Handler: frpgoserver.ListServersHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/status",
+				Handler: frpgoserver.GetServiceStatusHandler(serverCtx),
+			},
 		},
 		rest.WithPrefix("/api"),
 	)
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/client"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/frpstest"
+)
+
+// newTestServer serves the api of a client.Service connected to an in-process frps.
+func newTestServer(t *testing.T) (*httptest.Server, *frpstest.Server) {
+	require := require.New(t)
+	s, err := frpstest.NewServer(frpstest.Options{Token: "token"})
+	require.NoError(err)
+	t.Cleanup(func() { s.Close() })
+
+	svr, err := client.NewService(client.ServiceOptions{
+		Common: s.ClientConfig(),
+		ConnectorCreator: func(context.Context, *v1.ClientCommonConfig) client.Connector {
+			return s.NewConnector()
+		},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	t.Cleanup(svr.Close)
+
+	var c rest.RestConf
+	require.NoError(conf.FillDefault(&c))
+	server := rest.MustNewServer(c)
+	RegisterHandlers(server, &svc.ServiceContext{ProxyService: svr})
+	httpx.SetErrorHandlerCtx(errorx.ErrorHandler)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	require.Eventually(func() bool {
+		return svr.Status().State == client.StateLoggedIn
+	}, 10*time.Second, 20*time.Millisecond)
+	return ts, s
+}
+
+func doJSON(t *testing.T, ts *httptest.Server, method, path string, body, resp any) int {
+	var reader bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&reader).Encode(body))
+	}
+	req, err := http.NewRequest(method, ts.URL+path, &reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	if resp != nil {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(resp))
+	}
+	return res.StatusCode
+}
+
+func TestTunnelsAPI(t *testing.T) {
+	require := require.New(t)
+	ts, s := newTestServer(t)
+
+	var started types.StartTunnelResp
+	status := doJSON(t, ts, http.MethodPost, "/api/tunnels", types.StartTunnelReq{
+		Name:      "echo",
+		Type:      "tcp",
+		LocalIP:   "127.0.0.1",
+		LocalPort: frpstest.StartTCPEcho(t),
+	}, &started)
+	require.Equal(http.StatusOK, status)
+	require.Equal("/api/tunnels/echo", started.URI)
+	frpstest.RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+
+	var detail types.GetTunnelDetialResp
+	require.Eventually(func() bool {
+		status := doJSON(t, ts, http.MethodGet, "/api/tunnels/echo", nil, &detail)
+		return status == http.StatusOK && detail.Status == "running"
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Equal("api", detail.Origin)
+	require.NotEmpty(detail.RemoteAddr)
+
+	var list types.ListTunnelsResp
+	require.Equal(http.StatusOK, doJSON(t, ts, http.MethodGet, "/api/tunnels?type=tcp", nil, &list))
+	require.Equal(1, list.Respond.Total)
+	require.Equal("echo", list.Respond.Tunnels[0].Name)
+
+	// a conflicting tunnel is rejected with the unified error format
+	var errResp errorx.ErrorResp
+	status = doJSON(t, ts, http.MethodPost, "/api/tunnels", types.StartTunnelReq{
+		Name:      "echo",
+		Type:      "tcp",
+		LocalPort: 22,
+	}, &errResp)
+	require.Equal(http.StatusConflict, status)
+	require.NotEmpty(errResp.ErrTxt)
+
+	require.Equal(http.StatusOK, doJSON(t, ts, http.MethodPost, "/api/tunnels/echo/pause", nil, nil))
+	require.Eventually(func() bool {
+		_, ok := s.ProxyAddr("echo")
+		return !ok
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Equal(http.StatusOK, doJSON(t, ts, http.MethodPost, "/api/tunnels/echo/resume", nil, nil))
+	frpstest.RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+
+	require.Equal(http.StatusOK, doJSON(t, ts, http.MethodDelete, "/api/tunnels/echo", nil, nil))
+	require.Eventually(func() bool {
+		_, ok := s.ProxyAddr("echo")
+		return !ok
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Equal(http.StatusNotFound, doJSON(t, ts, http.MethodGet, "/api/tunnels/echo", nil, nil))
+	require.Equal(http.StatusNotFound, doJSON(t, ts, http.MethodDelete, "/api/tunnels/echo", nil, nil))
+}
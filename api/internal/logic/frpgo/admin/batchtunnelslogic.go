@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/client"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type BatchTunnelsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatchTunnelsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatchTunnelsLogic {
+	return &BatchTunnelsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *BatchTunnelsLogic) BatchTunnels(req *types.BatchTunnelsReq) (resp *types.BatchTunnelsResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	var op func(client.ProxyFilter) (*client.ProxyBatchResult, error)
+	switch req.Action {
+	case "stop", "delete":
+		op = svr.DeleteProxies
+	case "pause":
+		op = svr.PauseProxies
+	case "resume":
+		op = svr.ResumeProxies
+	default:
+		return nil, errorx.NewBadRequest(fmt.Sprintf("unknown action [%s]", req.Action))
+	}
+
+	f, err := toProxyFilter(req.Type, req.Phase, req.Name, req.Metadata, req.Annotation, req.Origin)
+	if err != nil {
+		return nil, errorx.NewBadRequest(err.Error())
+	}
+	result, err := op(f)
+	if err != nil {
+		l.Errorf("BatchTunnels req: %+v, err: %v", req, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	errs := make(map[string]string, len(result.Errors))
+	for name, err := range result.Errors {
+		errs[name] = err.Error()
+	}
+	return &types.BatchTunnelsResp{
+		ErrCode: errorx.CodeOK,
+		Respond: types.BatchTunnelsResult{
+			Tunnels: result.Names,
+			Errors:  errs,
+		},
+	}, nil
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/client/proxy"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetTunnelDetialLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetTunnelDetialLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetTunnelDetialLogic {
+	return &GetTunnelDetialLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetTunnelDetialLogic) GetTunnelDetial(req *types.GetTunnelDetailReq) (resp *types.GetTunnelDetialResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	detail, ok := svr.GetProxyDetail(req.Name)
+	if !ok {
+		return nil, errorx.NewNotFound(fmt.Sprintf("tunnel [%s] not found", req.Name))
+	}
+
+	tunnel := toTunnelDetail(detail)
+	return &tunnel, nil
+}
+
+func toTunnelDetail(detail *proxy.WorkingDetial) types.GetTunnelDetialResp {
+	out := types.GetTunnelDetialResp{
+		Name:       detail.Name,
+		URI:        tunnelURI(detail.Name),
+		PublicUrl:  detail.PublicUrl,
+		Type:       detail.Type,
+		Status:     detail.Status,
+		RemoteAddr: detail.RemoteAddr,
+		Err:        detail.Err,
+		Origin:     detail.Origin,
+		Config: types.ConfigInfo{
+			LocalIP:   detail.Config.LocalIP,
+			LocalPort: detail.Config.LocalPort,
+			Inspect:   detail.Config.Inspect,
+		},
+		Stats: toTunnelStats(detail.Stats),
+	}
+	if !detail.CreatedAt.IsZero() {
+		out.CreatedAt = detail.CreatedAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func toTunnelStats(s proxy.StatsSnapshot) types.TunnelStats {
+	out := types.TunnelStats{
+		TrafficIn:         s.TrafficIn,
+		TrafficOut:        s.TrafficOut,
+		TrafficInHistory:  s.TrafficInHistory,
+		TrafficOutHistory: s.TrafficOutHistory,
+		CurConns:          s.CurConns,
+		TotalConns:        s.TotalConns,
+		DialFailures:      s.DialFailures,
+		AvgConnDurationMs: s.AvgConnDurationMs,
+	}
+	if !s.LastConnAt.IsZero() {
+		out.LastConnAt = s.LastConnAt.Format(time.RFC3339)
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/client/inspect"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListCapturedRequestLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListCapturedRequestLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListCapturedRequestLogic {
+	return &ListCapturedRequestLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListCapturedRequestLogic) ListCapturedRequest(req *types.ListCaptureRequestReq) (resp *types.ListCaptureRequestResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	if req.Limit < 0 {
+		return nil, errorx.NewBadRequest(fmt.Sprintf("invalid limit [%d]", req.Limit))
+	}
+
+	// 隧道删除时抓取记录一并清除，暂停的隧道保留
+	if _, ok := svr.StatusExporter().GetProxyStatus(req.TunnelName); !ok {
+		return nil, errorx.NewNotFound(fmt.Sprintf("tunnel [%s] not found", req.TunnelName))
+	}
+
+	captures := svr.Inspector().List(req.TunnelName, inspect.ListOptions{
+		Limit:  req.Limit,
+		Method: req.Method,
+		Path:   req.Path,
+		Status: req.Status,
+	})
+
+	resp = &types.ListCaptureRequestResp{
+		ErrCode: errorx.CodeOK,
+		Respond: make([]types.CapturedRequest, 0, len(captures)),
+	}
+	for _, c := range captures {
+		resp.Respond = append(resp.Respond, toCapturedRequest(c))
+	}
+	return resp, nil
+}
+
+func toCapturedRequest(c *inspect.Capture) types.CapturedRequest {
+	out := types.CapturedRequest{
+		ID:         c.ID,
+		TunnelName: c.TunnelName,
+		RemoteAddr: c.RemoteAddr,
+		Start:      c.Start.Format(time.RFC3339Nano),
+		DurationMs: c.Duration.Milliseconds(),
+		Request: types.CapturedHTTPRequest{
+			Method:    c.Request.Method,
+			URI:       c.Request.URI,
+			Proto:     c.Request.Proto,
+			Host:      c.Request.Host,
+			Headers:   c.Request.Header,
+			Body:      c.Request.Body,
+			BodySize:  c.Request.BodySize,
+			Truncated: c.Request.Truncated,
+		},
+		Err:      c.Err,
+		ReplayOf: c.ReplayOf,
+	}
+	if c.Response != nil {
+		out.Response = &types.CapturedHTTPResponse{
+			Status:     c.Response.Status,
+			StatusCode: c.Response.StatusCode,
+			Proto:      c.Response.Proto,
+			Headers:    c.Response.Header,
+			Body:       c.Response.Body,
+			BodySize:   c.Response.BodySize,
+			Truncated:  c.Response.Truncated,
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/client"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListTunnelsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListTunnelsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListTunnelsLogic {
+	return &ListTunnelsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListTunnelsLogic) ListTunnels(req *types.ListTunnelsReq) (resp *types.ListTunnelsResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	opts, err := toProxyListOptions(req)
+	if err != nil {
+		return nil, errorx.NewBadRequest(err.Error())
+	}
+	list, err := svr.ListProxies(opts)
+	if err != nil {
+		l.Errorf("ListTunnels req: %+v, err: %v", req, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	tunnels := make([]types.GetTunnelDetialResp, 0, len(list.Proxies))
+	for _, detail := range list.Proxies {
+		tunnels = append(tunnels, toTunnelDetail(detail))
+	}
+	return &types.ListTunnelsResp{
+		ErrCode: errorx.CodeOK,
+		Respond: types.TunnelList{
+			Tunnels:    tunnels,
+			Total:      list.Total,
+			NextCursor: list.NextCursor,
+		},
+	}, nil
+}
+
+func toProxyListOptions(req *types.ListTunnelsReq) (client.ProxyListOptions, error) {
+	opts := client.ProxyListOptions{
+		Cursor: req.Cursor,
+		Limit:  req.Limit,
+	}
+	opts.SortBy, opts.Desc = strings.CutPrefix(req.Sort, "-")
+
+	var err error
+	opts.ProxyFilter, err = toProxyFilter(req.Type, req.Phase, req.Name, req.Metadata, req.Annotation, req.Origin)
+	return opts, err
+}
+
+// 逗号分隔的类型、状态及名称，metadata和annotation为标签选择器
+func toProxyFilter(typ, phase, name, metadata, annotation, origin string) (client.ProxyFilter, error) {
+	f := client.ProxyFilter{
+		Types:  splitList(strings.ToLower(typ)),
+		Phases: splitList(phase),
+		Names:  splitList(name),
+		Origin: origin,
+	}
+
+	var err error
+	if f.Metadatas, err = client.ParseProxySelector(metadata); err != nil {
+		return f, fmt.Errorf("metadata: %w", err)
+	}
+	if f.Annotations, err = client.ParseProxySelector(annotation); err != nil {
+		return f, fmt.Errorf("annotation: %w", err)
+	}
+	return f, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
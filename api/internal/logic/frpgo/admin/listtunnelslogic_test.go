@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"frpgo/api/internal/types"
+	"frpgo/client"
+)
+
+func TestToProxyListOptions(t *testing.T) {
+	require := require.New(t)
+
+	opts, err := toProxyListOptions(&types.ListTunnelsReq{
+		Type:     "TCP, udp",
+		Phase:    "running,paused",
+		Name:     "web-*",
+		Metadata: "env=dev, team in (a,b)",
+		Sort:     "-created",
+		Limit:    10,
+	})
+	require.NoError(err)
+	require.Equal([]string{"tcp", "udp"}, opts.Types)
+	require.Equal([]string{"running", "paused"}, opts.Phases)
+	require.Equal([]string{"web-*"}, opts.Names)
+	require.Equal(client.ProxySortByCreated, opts.SortBy)
+	require.True(opts.Desc)
+	require.Equal(10, opts.Limit)
+	require.True(opts.Metadatas.Matches(labels.Set{"env": "dev", "team": "b"}))
+	require.False(opts.Metadatas.Matches(labels.Set{"env": "dev", "team": "c"}))
+	require.True(opts.Annotations.Empty())
+
+	_, err = toProxyListOptions(&types.ListTunnelsReq{Annotation: "team in a"})
+	require.ErrorIs(err, client.ErrInvalidProxyFilter)
+}
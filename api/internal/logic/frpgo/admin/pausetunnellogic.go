@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type PauseTunnelLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewPauseTunnelLogic(ctx context.Context, svcCtx *svc.ServiceContext) *PauseTunnelLogic {
+	return &PauseTunnelLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *PauseTunnelLogic) PauseTunnel(req *types.PauseTunnelReq) (resp *types.PauseTunnelResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	if err = svr.PauseProxy(req.Name); err != nil {
+		l.Errorf("PauseTunnel name: %v, err: %v", req.Name, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	return &types.PauseTunnelResp{
+		ErrCode: errorx.CodeOK,
+		Respond: fmt.Sprintf("tunnel [%s] paused", req.Name),
+	}, nil
+}
@@ -0,0 +1,311 @@
+package admin
+
+import (
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/types"
+	configtypes "frpgo/pkg/config/types"
+	v1 "frpgo/pkg/config/v1"
+)
+
+// newProxyConfigurer 将StartTunnelReq转换为对应类型的v1.ProxyConfigurer
+// 只做类型转换，配置校验由client.Service.AddProxy完成
+func newProxyConfigurer(req *types.StartTunnelReq) (v1.ProxyConfigurer, error) {
+	cfg := v1.NewProxyConfigurerByType(v1.ProxyType(req.Type))
+	if cfg == nil {
+		return nil, errorx.NewBadRequest(fmt.Sprintf("unsupported proxy type [%s]", req.Type))
+	}
+
+	if req.Inspect && cfg.GetBaseConfig().Type != string(v1.ProxyTypeHTTP) {
+		return nil, errorx.NewBadRequest("inspect is only supported by http proxy")
+	}
+
+	base := cfg.GetBaseConfig()
+	base.Name = req.Name
+	base.LocalIP = req.LocalIP
+	base.LocalPort = req.LocalPort
+	base.Metadatas = req.Metadatas
+	base.Annotations = req.Annotations
+
+	base.Transport.UseEncryption = req.Transport.UseEncryption
+	base.Transport.UseCompression = req.Transport.UseCompression
+	base.Transport.BandwidthLimitMode = req.Transport.BandwidthLimitMode
+	base.Transport.ProxyProtocolVersion = req.Transport.ProxyProtocolVersion
+	if req.Transport.BandwidthLimit != "" {
+		limit, err := newBandwidthLimit(req.Transport.BandwidthLimit)
+		if err != nil {
+			return nil, err
+		}
+		base.Transport.BandwidthLimit = limit
+	}
+
+	base.HealthCheck = newHealthCheckConfig(&req.HealthCheck)
+	base.LoadBalancer = v1.LoadBalancerConfig{
+		Group:    req.LoadBalancer.Group,
+		GroupKey: req.LoadBalancer.GroupKey,
+	}
+
+	if req.Plugin.Type != "" {
+		plugin, err := newTypedClientPluginOptions(&req.Plugin)
+		if err != nil {
+			return nil, err
+		}
+		base.Plugin = plugin
+	}
+
+	domain := v1.DomainConfig{
+		CustomDomains: req.CustomDomains,
+		SubDomain:     req.SubDomain,
+	}
+	switch c := cfg.(type) {
+	case *v1.TCPProxyConfig:
+		c.RemotePort = req.RemotePort
+	case *v1.UDPProxyConfig:
+		c.RemotePort = req.RemotePort
+	case *v1.HTTPProxyConfig:
+		c.DomainConfig = domain
+		c.Locations = req.Locations
+		c.HTTPUser = req.HTTPUser
+		c.HTTPPassword = req.HTTPPassword
+		c.HostHeaderRewrite = req.HostHeaderRewrite
+		c.RequestHeaders.Set = req.RequestHeaders
+		c.ResponseHeaders.Set = req.ResponseHeaders
+		c.RouteByHTTPUser = req.RouteByHTTPUser
+		c.Inspect = req.Inspect
+	case *v1.HTTPSProxyConfig:
+		c.DomainConfig = domain
+	case *v1.TCPMuxProxyConfig:
+		c.DomainConfig = domain
+		c.HTTPUser = req.HTTPUser
+		c.HTTPPassword = req.HTTPPassword
+		c.RouteByHTTPUser = req.RouteByHTTPUser
+		c.Multiplexer = req.Multiplexer
+	case *v1.STCPProxyConfig:
+		c.Secretkey = req.SecretKey
+		c.AllowUsers = req.AllowUsers
+	case *v1.XTCPProxyConfig:
+		c.Secretkey = req.SecretKey
+		c.AllowUsers = req.AllowUsers
+	case *v1.SUDPProxyConfig:
+		c.Secretkey = req.SecretKey
+		c.AllowUsers = req.AllowUsers
+	}
+	return cfg, nil
+}
+
+// applyTunnelPatch 将UpdateTunnelReq中出现的字段修改到cfg上，未出现的字段保持不变
+// health_check, load_balancer, plugin整体替换，plugin.type为空时移除插件
+func applyTunnelPatch(cfg v1.ProxyConfigurer, req *types.UpdateTunnelReq) error {
+	base := cfg.GetBaseConfig()
+	if req.Inspect != nil && *req.Inspect && base.Type != string(v1.ProxyTypeHTTP) {
+		return errorx.NewBadRequest("inspect is only supported by http proxy")
+	}
+
+	setIfNotNil(&base.LocalIP, req.LocalIP)
+	setIfNotNil(&base.LocalPort, req.LocalPort)
+	if req.Metadatas != nil {
+		base.Metadatas = req.Metadatas
+	}
+	if req.Annotations != nil {
+		base.Annotations = req.Annotations
+	}
+
+	if t := req.Transport; t != nil {
+		setIfNotNil(&base.Transport.UseEncryption, t.UseEncryption)
+		setIfNotNil(&base.Transport.UseCompression, t.UseCompression)
+		setIfNotNil(&base.Transport.BandwidthLimitMode, t.BandwidthLimitMode)
+		setIfNotNil(&base.Transport.ProxyProtocolVersion, t.ProxyProtocolVersion)
+		if t.BandwidthLimit != nil {
+			limit, err := newBandwidthLimit(*t.BandwidthLimit)
+			if err != nil {
+				return err
+			}
+			base.Transport.BandwidthLimit = limit
+		}
+	}
+	if req.HealthCheck != nil {
+		base.HealthCheck = newHealthCheckConfig(req.HealthCheck)
+	}
+	if req.LoadBalancer != nil {
+		base.LoadBalancer = v1.LoadBalancerConfig{
+			Group:    req.LoadBalancer.Group,
+			GroupKey: req.LoadBalancer.GroupKey,
+		}
+	}
+	if req.Plugin != nil {
+		base.Plugin = v1.TypedClientPluginOptions{}
+		if req.Plugin.Type != "" {
+			plugin, err := newTypedClientPluginOptions(req.Plugin)
+			if err != nil {
+				return err
+			}
+			base.Plugin = plugin
+		}
+	}
+
+	applyDomainPatch := func(domain *v1.DomainConfig) {
+		setIfNotNil(&domain.SubDomain, req.SubDomain)
+		if req.CustomDomains != nil {
+			domain.CustomDomains = req.CustomDomains
+		}
+	}
+	switch c := cfg.(type) {
+	case *v1.TCPProxyConfig:
+		setIfNotNil(&c.RemotePort, req.RemotePort)
+	case *v1.UDPProxyConfig:
+		setIfNotNil(&c.RemotePort, req.RemotePort)
+	case *v1.HTTPProxyConfig:
+		applyDomainPatch(&c.DomainConfig)
+		if req.Locations != nil {
+			c.Locations = req.Locations
+		}
+		setIfNotNil(&c.HTTPUser, req.HTTPUser)
+		setIfNotNil(&c.HTTPPassword, req.HTTPPassword)
+		setIfNotNil(&c.HostHeaderRewrite, req.HostHeaderRewrite)
+		if req.RequestHeaders != nil {
+			c.RequestHeaders.Set = req.RequestHeaders
+		}
+		if req.ResponseHeaders != nil {
+			c.ResponseHeaders.Set = req.ResponseHeaders
+		}
+		setIfNotNil(&c.RouteByHTTPUser, req.RouteByHTTPUser)
+		setIfNotNil(&c.Inspect, req.Inspect)
+	case *v1.HTTPSProxyConfig:
+		applyDomainPatch(&c.DomainConfig)
+	case *v1.TCPMuxProxyConfig:
+		applyDomainPatch(&c.DomainConfig)
+		setIfNotNil(&c.HTTPUser, req.HTTPUser)
+		setIfNotNil(&c.HTTPPassword, req.HTTPPassword)
+		setIfNotNil(&c.RouteByHTTPUser, req.RouteByHTTPUser)
+	case *v1.STCPProxyConfig:
+		setIfNotNil(&c.Secretkey, req.SecretKey)
+		if req.AllowUsers != nil {
+			c.AllowUsers = req.AllowUsers
+		}
+	case *v1.XTCPProxyConfig:
+		setIfNotNil(&c.Secretkey, req.SecretKey)
+		if req.AllowUsers != nil {
+			c.AllowUsers = req.AllowUsers
+		}
+	case *v1.SUDPProxyConfig:
+		setIfNotNil(&c.Secretkey, req.SecretKey)
+		if req.AllowUsers != nil {
+			c.AllowUsers = req.AllowUsers
+		}
+	}
+	return nil
+}
+
+func setIfNotNil[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func newBandwidthLimit(s string) (configtypes.BandwidthQuantity, error) {
+	limit, err := configtypes.NewBandwidthQuantity(s)
+	if err != nil {
+		return limit, errorx.NewBadRequest(fmt.Sprintf("invalid bandwidth_limit: %v", err))
+	}
+	return limit, nil
+}
+
+func newHealthCheckConfig(h *types.HealthCheckInfo) v1.HealthCheckConfig {
+	cfg := v1.HealthCheckConfig{
+		Type:            h.Type,
+		TimeoutSeconds:  h.TimeoutSeconds,
+		MaxFailed:       h.MaxFailed,
+		IntervalSeconds: h.IntervalSeconds,
+		Path:            h.Path,
+	}
+	for _, header := range h.HTTPHeaders {
+		cfg.HTTPHeaders = append(cfg.HTTPHeaders, v1.HTTPHeader{Name: header.Name, Value: header.Value})
+	}
+	return cfg
+}
+
+func newTypedClientPluginOptions(p *types.PluginInfo) (v1.TypedClientPluginOptions, error) {
+	options, err := newClientPluginOptions(p)
+	if err != nil {
+		return v1.TypedClientPluginOptions{}, err
+	}
+	return v1.TypedClientPluginOptions{
+		Type:                p.Type,
+		ClientPluginOptions: options,
+	}, nil
+}
+
+func newClientPluginOptions(p *types.PluginInfo) (v1.ClientPluginOptions, error) {
+	requestHeaders := v1.HeaderOperations{Set: p.RequestHeaders}
+
+	switch p.Type {
+	case v1.PluginHTTP2HTTPS:
+		return &v1.HTTP2HTTPSPluginOptions{
+			Type:              p.Type,
+			LocalAddr:         p.LocalAddr,
+			HostHeaderRewrite: p.HostHeaderRewrite,
+			RequestHeaders:    requestHeaders,
+		}, nil
+	case v1.PluginHTTPProxy:
+		return &v1.HTTPProxyPluginOptions{
+			Type:         p.Type,
+			HTTPUser:     p.HTTPUser,
+			HTTPPassword: p.HTTPPassword,
+		}, nil
+	case v1.PluginHTTPS2HTTP:
+		return &v1.HTTPS2HTTPPluginOptions{
+			Type:              p.Type,
+			LocalAddr:         p.LocalAddr,
+			HostHeaderRewrite: p.HostHeaderRewrite,
+			RequestHeaders:    requestHeaders,
+			EnableHTTP2:       p.EnableHTTP2,
+			CrtPath:           p.CrtPath,
+			KeyPath:           p.KeyPath,
+		}, nil
+	case v1.PluginHTTPS2HTTPS:
+		return &v1.HTTPS2HTTPSPluginOptions{
+			Type:              p.Type,
+			LocalAddr:         p.LocalAddr,
+			HostHeaderRewrite: p.HostHeaderRewrite,
+			RequestHeaders:    requestHeaders,
+			EnableHTTP2:       p.EnableHTTP2,
+			CrtPath:           p.CrtPath,
+			KeyPath:           p.KeyPath,
+		}, nil
+	case v1.PluginHTTP2HTTP:
+		return &v1.HTTP2HTTPPluginOptions{
+			Type:              p.Type,
+			LocalAddr:         p.LocalAddr,
+			HostHeaderRewrite: p.HostHeaderRewrite,
+			RequestHeaders:    requestHeaders,
+		}, nil
+	case v1.PluginSocks5:
+		return &v1.Socks5PluginOptions{
+			Type:     p.Type,
+			Username: p.Username,
+			Password: p.Password,
+		}, nil
+	case v1.PluginStaticFile:
+		return &v1.StaticFilePluginOptions{
+			Type:         p.Type,
+			LocalPath:    p.LocalPath,
+			StripPrefix:  p.StripPrefix,
+			HTTPUser:     p.HTTPUser,
+			HTTPPassword: p.HTTPPassword,
+		}, nil
+	case v1.PluginUnixDomainSocket:
+		return &v1.UnixDomainSocketPluginOptions{
+			Type:     p.Type,
+			UnixPath: p.UnixPath,
+		}, nil
+	case v1.PluginTLS2Raw:
+		return &v1.TLS2RawPluginOptions{
+			Type:      p.Type,
+			LocalAddr: p.LocalAddr,
+			CrtPath:   p.CrtPath,
+			KeyPath:   p.KeyPath,
+		}, nil
+	}
+	return nil, errorx.NewBadRequest(fmt.Sprintf("unsupported plugin type [%s]", p.Type))
+}
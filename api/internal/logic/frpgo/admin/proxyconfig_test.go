@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"frpgo/api/internal/types"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/config/v1/validation"
+)
+
+func TestNewProxyConfigurer(t *testing.T) {
+	require := require.New(t)
+
+	cfg, err := newProxyConfigurer(&types.StartTunnelReq{
+		Name:            "web",
+		Type:            "http",
+		LocalPort:       8080,
+		SubDomain:       "web",
+		Locations:       []string{"/api"},
+		RequestHeaders:  map[string]string{"x-from": "frpgo"},
+		Transport:       types.TransportInfo{UseCompression: true, BandwidthLimit: "1MB"},
+		HealthCheck:     types.HealthCheckInfo{Type: "http", Path: "/healthz"},
+		LoadBalancer:    types.LoadBalancerInfo{Group: "web", GroupKey: "key"},
+		Metadatas:       map[string]string{"env": "dev"},
+		ResponseHeaders: map[string]string{},
+	})
+	require.NoError(err)
+	cfg.Complete("")
+	require.NoError(validation.ValidateProxyConfigurerForClient(cfg))
+
+	httpCfg, ok := cfg.(*v1.HTTPProxyConfig)
+	require.True(ok)
+	require.Equal("web", httpCfg.SubDomain)
+	require.Equal([]string{"/api"}, httpCfg.Locations)
+	require.Equal("frpgo", httpCfg.RequestHeaders.Set["x-from"])
+	require.Equal(int64(1024*1024), httpCfg.Transport.BandwidthLimit.Bytes())
+	require.Equal("/healthz", httpCfg.HealthCheck.Path)
+	require.Equal("web", httpCfg.LoadBalancer.Group)
+	require.Equal("127.0.0.1", httpCfg.LocalIP)
+
+	cfg, err = newProxyConfigurer(&types.StartTunnelReq{
+		Name:       "secret-ssh",
+		Type:       "stcp",
+		SecretKey:  "abc",
+		AllowUsers: []string{"*"},
+		Plugin:     types.PluginInfo{Type: v1.PluginUnixDomainSocket, UnixPath: "/var/run/docker.sock"},
+	})
+	require.NoError(err)
+	cfg.Complete("")
+	require.NoError(validation.ValidateProxyConfigurerForClient(cfg))
+	stcpCfg, ok := cfg.(*v1.STCPProxyConfig)
+	require.True(ok)
+	require.Equal("abc", stcpCfg.Secretkey)
+	require.Equal("/var/run/docker.sock", stcpCfg.Plugin.ClientPluginOptions.(*v1.UnixDomainSocketPluginOptions).UnixPath)
+
+	_, err = newProxyConfigurer(&types.StartTunnelReq{Name: "x", Type: "unknown"})
+	require.Error(err)
+	_, err = newProxyConfigurer(&types.StartTunnelReq{Name: "x", Type: "tcp", Plugin: types.PluginInfo{Type: "unknown"}})
+	require.Error(err)
+}
+
+func TestApplyTunnelPatch(t *testing.T) {
+	require := require.New(t)
+
+	cfg, err := newProxyConfigurer(&types.StartTunnelReq{
+		Name:          "web",
+		Type:          "http",
+		LocalPort:     8080,
+		CustomDomains: []string{"a.example.com"},
+		Locations:     []string{"/api"},
+		Transport:     types.TransportInfo{UseCompression: true},
+		Plugin:        types.PluginInfo{Type: v1.PluginHTTP2HTTPS, LocalAddr: "127.0.0.1:443"},
+		Metadatas:     map[string]string{"env": "dev"},
+	})
+	require.NoError(err)
+
+	err = applyTunnelPatch(cfg, &types.UpdateTunnelReq{
+		Name:          "web",
+		LocalPort:     lo.ToPtr(9090),
+		CustomDomains: []string{},
+		SubDomain:     lo.ToPtr("web"),
+		Transport:     &types.TransportPatch{BandwidthLimit: lo.ToPtr("512KB")},
+		Plugin:        &types.PluginInfo{},
+		Inspect:       lo.ToPtr(true),
+	})
+	require.NoError(err)
+
+	httpCfg := cfg.(*v1.HTTPProxyConfig)
+	require.Equal(9090, httpCfg.LocalPort)
+	require.Empty(httpCfg.CustomDomains)
+	require.Equal("web", httpCfg.SubDomain)
+	require.Equal([]string{"/api"}, httpCfg.Locations)
+	require.True(httpCfg.Transport.UseCompression)
+	require.Equal(int64(512*1024), httpCfg.Transport.BandwidthLimit.Bytes())
+	require.Empty(httpCfg.Plugin.Type)
+	require.Equal("dev", httpCfg.Metadatas["env"])
+	require.True(httpCfg.Inspect)
+
+	cfg, err = newProxyConfigurer(&types.StartTunnelReq{Name: "ssh", Type: "tcp", LocalPort: 22})
+	require.NoError(err)
+	require.Error(applyTunnelPatch(cfg, &types.UpdateTunnelReq{Inspect: lo.ToPtr(true)}))
+	require.Error(applyTunnelPatch(cfg, &types.UpdateTunnelReq{Transport: &types.TransportPatch{BandwidthLimit: lo.ToPtr("1GB")}}))
+	require.NoError(applyTunnelPatch(cfg, &types.UpdateTunnelReq{RemotePort: lo.ToPtr(6000)}))
+	require.Equal(6000, cfg.(*v1.TCPProxyConfig).RemotePort)
+	require.Equal(22, cfg.(*v1.TCPProxyConfig).LocalPort)
+}
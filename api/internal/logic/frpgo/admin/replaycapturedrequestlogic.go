@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"context"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/client/inspect"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ReplayCapturedRequestLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewReplayCapturedRequestLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ReplayCapturedRequestLogic {
+	return &ReplayCapturedRequestLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ReplayCapturedRequestLogic) ReplayCapturedRequest(req *types.ReplayCapturedRequestReq) (resp *types.ReplayCapturedRequestResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	opts := inspect.ReplayOptions{
+		Method: req.Method,
+		URI:    req.Path,
+		Header: req.Headers,
+	}
+	if req.Body != nil {
+		body := []byte(*req.Body)
+		opts.Body = &body
+	}
+
+	capture, err := svr.ReplayCapturedRequest(l.ctx, req.TunnelName, req.ID, opts)
+	if err != nil {
+		l.Errorf("replay request [%s] of tunnel [%s] error: %v", req.ID, req.TunnelName, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	return &types.ReplayCapturedRequestResp{
+		ErrCode: errorx.CodeOK,
+		Respond: toCapturedRequest(capture),
+	}, nil
+}
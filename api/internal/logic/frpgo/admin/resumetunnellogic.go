@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ResumeTunnelLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewResumeTunnelLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ResumeTunnelLogic {
+	return &ResumeTunnelLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ResumeTunnelLogic) ResumeTunnel(req *types.ResumeTunnelReq) (resp *types.ResumeTunnelResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	if err = svr.ResumeProxy(req.Name); err != nil {
+		l.Errorf("ResumeTunnel name: %v, err: %v", req.Name, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	return &types.ResumeTunnelResp{
+		ErrCode: errorx.CodeOK,
+		Respond: fmt.Sprintf("tunnel [%s] resumed", req.Name),
+	}, nil
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type StartTunnelLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewStartTunnelLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StartTunnelLogic {
+	return &StartTunnelLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *StartTunnelLogic) StartTunnel(req *types.StartTunnelReq) (resp *types.StartTunnelResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	cfg, err := newProxyConfigurer(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svr.AddProxy(cfg)
+	if err != nil {
+		l.Errorf("StartTunnel name: %v, err: %v", req.Name, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	detail, ok := svr.GetProxyDetail(req.Name)
+	if !ok {
+		return nil, errorx.NewNotFound(fmt.Sprintf("tunnel [%s] not found", req.Name))
+	}
+
+	return &types.StartTunnelResp{
+		Name:      detail.Name,
+		URI:       tunnelURI(detail.Name),
+		PublicUrl: detail.PublicUrl,
+		Proto:     detail.Type,
+		Config: types.ConfigInfo{
+			LocalIP:   detail.Config.LocalIP,
+			LocalPort: detail.Config.LocalPort,
+			Inspect:   detail.Config.Inspect,
+		},
+	}, nil
+}
+
+func tunnelURI(name string) string {
+	return "/api/tunnels/" + name
+}
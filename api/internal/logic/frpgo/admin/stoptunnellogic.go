@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type StopTunnelLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewStopTunnelLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StopTunnelLogic {
+	return &StopTunnelLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *StopTunnelLogic) StopTunnel(req *types.StopTunnelReq) (resp *types.StopTunnelResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	if err = svr.DeleteProxy(req.Name); err != nil {
+		l.Errorf("StopTunnel name: %v, err: %v", req.Name, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	return &types.StopTunnelResp{
+		ErrCode: errorx.CodeOK,
+		Respond: fmt.Sprintf("tunnel [%s] stopped", req.Name),
+	}, nil
+}
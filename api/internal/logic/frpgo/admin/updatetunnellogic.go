@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	v1 "frpgo/pkg/config/v1"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdateTunnelLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdateTunnelLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdateTunnelLogic {
+	return &UpdateTunnelLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UpdateTunnelLogic) UpdateTunnel(req *types.UpdateTunnelReq) (resp *types.UpdateTunnelResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	mode, err := svr.UpdateProxy(req.Name, func(cfg v1.ProxyConfigurer) error {
+		return applyTunnelPatch(cfg, req)
+	})
+	if err != nil {
+		l.Errorf("UpdateTunnel name: %v, err: %v", req.Name, err)
+		return nil, errorx.FromClientError(err)
+	}
+
+	detail, ok := svr.GetProxyDetail(req.Name)
+	if !ok {
+		return nil, errorx.NewNotFound(fmt.Sprintf("tunnel [%s] not found", req.Name))
+	}
+
+	return &types.UpdateTunnelResp{
+		ErrCode: errorx.CodeOK,
+		Respond: types.UpdateTunnelResult{
+			Mode:   string(mode),
+			Tunnel: toTunnelDetail(detail),
+		},
+	}, nil
+}
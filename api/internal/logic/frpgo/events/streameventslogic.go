@@ -0,0 +1,264 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	sseContentType = "text/event-stream"
+	// 推送心跳，防止代理等中间设备断开空闲连接
+	pingInterval = 15 * time.Second
+	// 须小于go-zero的读超时（路由超时的4/5），到期后断开，客户端带Last-Event-ID重连
+	maxStreamDuration = 45 * time.Minute
+	// 断开后浏览器的重连间隔
+	retryMs = 3000
+
+	// 部分事件已移出缓冲无法续传时推送
+	eventTruncated = "stream.truncated"
+)
+
+type StreamEventsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+	w      http.ResponseWriter
+	r      *http.Request
+}
+
+func NewStreamEventsLogic(ctx context.Context, svcCtx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) *StreamEventsLogic {
+	return &StreamEventsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+		w:      w,
+		r:      r,
+	}
+}
+
+// eventWriter 以SSE或WebSocket写出事件
+type eventWriter interface {
+	writeEvent(id uint64, typ string, data []byte) error
+	ping() error
+}
+
+func (l *StreamEventsLogic) StreamEvents(req *types.StreamEventsReq) error {
+	lastEventID := req.LastEventID
+	if lastEventID == "" {
+		lastEventID = req.LastEventIDQuery
+	}
+	var lastSeq uint64
+	if lastEventID != "" {
+		var err error
+		if lastSeq, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			return errorx.NewBadRequest(fmt.Sprintf("invalid last event id: %s", lastEventID))
+		}
+	}
+
+	filter := webhook.StreamFilter{
+		Events: splitList(req.Events),
+		Names:  splitList(req.Name),
+		Types:  splitList(strings.ToLower(req.Type)),
+	}
+
+	if strings.EqualFold(l.r.Header.Get("Upgrade"), "websocket") {
+		websocket.Server{
+			Handshake: l.checkOrigin,
+			Handler: func(ws *websocket.Conn) {
+				l.serveWebSocket(ws, filter, lastSeq)
+			},
+		}.ServeHTTP(l.w, l.r)
+		return nil
+	}
+
+	// go-zero仅对该Accept跳过超时处理，否则响应会被缓冲到请求结束
+	if l.r.Header.Get("Accept") != sseContentType {
+		return errorx.NewBadRequest("Accept: text/event-stream or websocket is required")
+	}
+	flusher, ok := l.w.(http.Flusher)
+	if !ok {
+		return errorx.NewInternal("streaming is not supported")
+	}
+
+	h := l.w.Header()
+	h.Set("Content-Type", sseContentType)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	l.w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(l.w, "retry: %d\n\n", retryMs)
+	flusher.Flush()
+
+	l.stream(l.ctx, &sseWriter{w: l.w, flusher: flusher}, filter, lastSeq)
+	return nil
+}
+
+// checkOrigin 浏览器发起的跨站WebSocket连接不受同源策略限制，须校验Origin，
+// 仅允许与请求Host相同或配置中允许的来源，返回错误时响应403
+func (l *StreamEventsLogic) checkOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return fmt.Errorf("null origin")
+	}
+	config.Origin = origin
+
+	if strings.EqualFold(origin.Host, r.Host) {
+		return nil
+	}
+	var allowed []string
+	if l.svcCtx != nil {
+		allowed = l.svcCtx.Config.Events.AllowedOrigins
+	}
+	u := url.URL{Scheme: origin.Scheme, Host: origin.Host}
+	if slices.ContainsFunc(allowed, func(s string) bool {
+		return strings.EqualFold(strings.TrimSuffix(s, "/"), u.String())
+	}) {
+		return nil
+	}
+	l.Infof("websocket origin [%s] is not allowed, host: %s", origin, r.Host)
+	return fmt.Errorf("origin [%s] is not allowed", origin)
+}
+
+func (l *StreamEventsLogic) serveWebSocket(ws *websocket.Conn, filter webhook.StreamFilter, lastSeq uint64) {
+	// 连接已被接管，清除http服务端设置的超时
+	_ = ws.SetDeadline(time.Time{})
+
+	// 客户端无需发送消息，读取仅用于发现断开
+	ctx, cancel := context.WithCancel(l.ctx)
+	defer cancel()
+	go func() {
+		defer cancel()
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	l.stream(ctx, &wsWriter{ws: ws}, filter, lastSeq)
+}
+
+func (l *StreamEventsLogic) stream(ctx context.Context, ew eventWriter, filter webhook.StreamFilter, lastSeq uint64) {
+	stream, replay, truncated := webhook.Subscribe(filter, lastSeq)
+	defer webhook.Unsubscribe(stream)
+
+	if truncated {
+		if err := ew.writeEvent(0, eventTruncated, []byte("{}")); err != nil {
+			return
+		}
+	}
+	for _, se := range replay {
+		if err := l.writeEvent(ew, se); err != nil {
+			return
+		}
+	}
+
+	timer := time.NewTimer(maxStreamDuration)
+	defer timer.Stop()
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-ticker.C:
+			if err := ew.ping(); err != nil {
+				return
+			}
+		case se, ok := <-stream.C:
+			if !ok {
+				l.Infof("event stream is dropped for reading too slow")
+				return
+			}
+			if err := l.writeEvent(ew, se); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (l *StreamEventsLogic) writeEvent(ew eventWriter, se webhook.StreamEvent) error {
+	data, err := json.Marshal(se.Event)
+	if err != nil {
+		l.Errorf("marshal event [%s] error: %v", se.Event.ID, err)
+		return nil
+	}
+	return ew.writeEvent(se.Seq, string(se.Event.Type), data)
+}
+
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) writeEvent(id uint64, typ string, data []byte) error {
+	var err error
+	if id > 0 {
+		_, err = fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", id, typ, data)
+	} else {
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", typ, data)
+	}
+	if err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// wsMessage WebSocket消息，字段与SSE一致
+type wsMessage struct {
+	ID    uint64          `json:"id,omitempty"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type wsWriter struct {
+	ws *websocket.Conn
+}
+
+func (s *wsWriter) writeEvent(id uint64, typ string, data []byte) error {
+	return websocket.JSON.Send(s.ws, wsMessage{ID: id, Event: typ, Data: data})
+}
+
+func (s *wsWriter) ping() error {
+	s.ws.PayloadType = websocket.PingFrame
+	defer func() { s.ws.PayloadType = websocket.TextFrame }()
+	_, err := s.ws.Write(nil)
+	return err
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/config"
+	"frpgo/fmgr/webhook"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func newEventsServer(t *testing.T, req *types.StreamEventsReq) *httptest.Server {
+	return newEventsServerWithContext(t, req, nil)
+}
+
+func newEventsServerWithContext(t *testing.T, req *types.StreamEventsReq, svcCtx *svc.ServiceContext) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := NewStreamEventsLogic(r.Context(), svcCtx, w, r).StreamEvents(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readSSE 读取一个SSE帧，跳过retry与注释行
+func readSSE(t *testing.T, r *bufio.Reader) map[string]string {
+	frame := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if len(frame) > 0 {
+				return frame
+			}
+			continue
+		}
+		k, v, _ := strings.Cut(line, ": ")
+		if k == "retry" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		frame[k] = v
+	}
+}
+
+func TestStreamEventsSSE(t *testing.T) {
+	require := require.New(t)
+	srv := newEventsServer(t, &types.StreamEventsReq{Name: "sse-web"})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", sseContentType)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(err)
+	defer resp.Body.Close()
+	require.Equal(http.StatusOK, resp.StatusCode)
+	require.Equal(sseContentType, resp.Header.Get("Content-Type"))
+
+	go func() {
+		// 等待订阅完成
+		time.Sleep(100 * time.Millisecond)
+		e := webhook.NewEvent(webhook.EventProxyStarted, "")
+		e.ProxyName = "sse-other"
+		webhook.Push(e)
+		e = webhook.NewEvent(webhook.EventProxyStarted, "")
+		e.ProxyName = "sse-web"
+		webhook.Push(e)
+	}()
+
+	frame := readSSE(t, bufio.NewReader(resp.Body))
+	require.Equal(string(webhook.EventProxyStarted), frame["event"])
+	require.NotEmpty(frame["id"])
+	require.Contains(frame["data"], `"proxy_name":"sse-web"`)
+}
+
+func TestStreamEventsRequireAccept(t *testing.T) {
+	srv := newEventsServer(t, &types.StreamEventsReq{})
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestStreamEventsWebSocketReplay(t *testing.T) {
+	require := require.New(t)
+	s, _, _ := webhook.Subscribe(webhook.StreamFilter{Names: []string{"ws-web"}}, 0)
+	for _, typ := range []webhook.EventType{webhook.EventProxyStarted, webhook.EventProxyClosed} {
+		e := webhook.NewEvent(typ, "")
+		e.ProxyName = "ws-web"
+		webhook.Push(e)
+	}
+	first := <-s.C
+	<-s.C
+	webhook.Unsubscribe(s)
+
+	// 从第一条之后续传
+	srv := newEventsServer(t, &types.StreamEventsReq{Name: "ws-web", LastEventIDQuery: strconv.FormatUint(first.Seq, 10)})
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	require.NoError(err)
+	defer ws.Close()
+
+	var msg wsMessage
+	require.NoError(ws.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	require.NoError(websocket.JSON.Receive(ws, &msg))
+	require.Equal(first.Seq+1, msg.ID)
+	require.Equal(string(webhook.EventProxyClosed), msg.Event)
+	require.Contains(string(msg.Data), `"proxy_name":"ws-web"`)
+}
+
+func TestStreamEventsWebSocketOrigin(t *testing.T) {
+	require := require.New(t)
+	var c config.Config
+	c.Events.AllowedOrigins = []string{"https://console.example.com/"}
+	srv := newEventsServerWithContext(t, &types.StreamEventsReq{}, &svc.ServiceContext{Config: c})
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// 跨站来源被拒绝
+	_, err := websocket.Dial(wsURL, "", "https://evil.example.com")
+	require.Error(err)
+
+	// 同源及配置允许的来源
+	for _, origin := range []string{srv.URL, "https://console.example.com"} {
+		ws, err := websocket.Dial(wsURL, "", origin)
+		require.NoError(err, origin)
+		ws.Close()
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetServiceStatusLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetServiceStatusLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetServiceStatusLogic {
+	return &GetServiceStatusLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetServiceStatusLogic) GetServiceStatus() (resp *types.GetServiceStatusResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	st := svr.Status()
+	return &types.GetServiceStatusResp{
+		ErrCode: errorx.CodeOK,
+		Respond: types.ServiceStatus{
+			State:       st.State,
+			RunID:       st.RunID,
+			Server:      st.Server,
+			ServerAddr:  st.ServerAddr,
+			Protocol:    st.Protocol,
+			TCPMux:      st.TCPMux,
+			Streams:     st.Streams,
+			LoginAt:     formatTime(st.LoginAt),
+			Reconnects:  st.Reconnects,
+			LastError:   st.LastError,
+			LastErrorAt: formatTime(st.LastErrorAt),
+			HeartbeatRTT: types.HeartbeatRTT{
+				Last:    st.HeartbeatRTT.Last,
+				Avg:     st.HeartbeatRTT.Avg,
+				Min:     st.HeartbeatRTT.Min,
+				Max:     st.HeartbeatRTT.Max,
+				Samples: st.HeartbeatRTT.Samples,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListServersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListServersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListServersLogic {
+	return &ListServersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListServersLogic) ListServers() (resp *types.ListServersResp, err error) {
+	svr := l.svcCtx.ProxyService
+	if svr == nil {
+		return nil, errorx.ErrServiceUnavailable
+	}
+
+	st := svr.ServersStatus()
+	info := types.ServersInfo{
+		Strategy:  st.Strategy,
+		Active:    st.Active,
+		Servers:   make([]types.ServerStatus, 0, len(st.Servers)),
+		Failovers: make([]types.FailoverRecord, 0, len(st.Failovers)),
+	}
+	for _, s := range st.Servers {
+		info.Servers = append(info.Servers, types.ServerStatus{
+			Name:                s.Name,
+			Addr:                s.Addr,
+			Protocol:            s.Protocol,
+			Protocols:           s.Protocols,
+			Weight:              s.Weight,
+			Active:              s.Active,
+			Healthy:             s.Healthy,
+			Score:               s.Score,
+			ConsecutiveFailures: s.ConsecutiveFailures,
+			TotalFailures:       s.TotalFailures,
+			TotalSuccesses:      s.TotalSuccesses,
+			LastError:           s.LastError,
+			LastFailureAt:       formatTime(s.LastFailureAt),
+			LastSuccessAt:       formatTime(s.LastSuccessAt),
+			RetryAfter:          formatTime(s.RetryAfter),
+		})
+	}
+	for _, r := range st.Failovers {
+		info.Failovers = append(info.Failovers, types.FailoverRecord{
+			Time:   formatTime(r.Time),
+			From:   r.From,
+			To:     r.To,
+			Reason: r.Reason,
+		})
+	}
+
+	return &types.ListServersResp{
+		ErrCode: errorx.CodeOK,
+		Respond: info,
+	}, nil
+}
+
+// formatTime 零值返回空串
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/config"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type CreateSubscriberLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewCreateSubscriberLogic(ctx context.Context, svcCtx *svc.ServiceContext) *CreateSubscriberLogic {
+	return &CreateSubscriberLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *CreateSubscriberLogic) CreateSubscriber(req *types.CreateSubscriberReq) (resp *types.CreateSubscriberResp, err error) {
+	err = webhook.AddSubscriber(config.WebhookSubscriberConf{
+		Name:        req.Name,
+		Url:         req.Url,
+		Events:      req.Events,
+		Names:       req.Names,
+		Headers:     req.Headers,
+		Timeout:     time.Duration(req.TimeoutMs) * time.Millisecond,
+		Format:      req.Format,
+		Template:    req.Template,
+		ContentType: req.ContentType,
+		Secret:      req.Secret,
+		Secrets:     req.Secrets,
+	})
+	if err != nil {
+		return nil, errorx.FromClientError(err)
+	}
+
+	for _, s := range webhook.Subscribers() {
+		if s.Conf.Name == req.Name {
+			return &types.CreateSubscriberResp{
+				ErrCode: errorx.CodeOK,
+				Respond: toWebhookSubscriber(s),
+			}, nil
+		}
+	}
+	// 创建后立即被删除
+	return nil, errorx.FromClientError(webhook.ErrSubscriberNotFound)
+}
@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeleteSubscriberLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeleteSubscriberLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeleteSubscriberLogic {
+	return &DeleteSubscriberLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *DeleteSubscriberLogic) DeleteSubscriber(req *types.DeleteSubscriberReq) (resp *types.DeleteSubscriberResp, err error) {
+	if err = webhook.RemoveSubscriber(req.Name); err != nil {
+		return nil, errorx.FromClientError(err)
+	}
+
+	return &types.DeleteSubscriberResp{
+		ErrCode: errorx.CodeOK,
+		Respond: fmt.Sprintf("subscriber [%s] deleted", req.Name),
+	}, nil
+}
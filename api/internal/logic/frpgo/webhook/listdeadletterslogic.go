@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListDeadLettersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListDeadLettersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListDeadLettersLogic {
+	return &ListDeadLettersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListDeadLettersLogic) ListDeadLetters() (resp *types.ListDeadLettersResp, err error) {
+	dead := webhook.DeadLetters()
+	resp = &types.ListDeadLettersResp{
+		ErrCode: errorx.CodeOK,
+		Respond: make([]types.WebhookDelivery, 0, len(dead)),
+	}
+	for _, dl := range dead {
+		resp.Respond = append(resp.Respond, toWebhookDelivery(dl))
+	}
+	return resp, nil
+}
+
+func toWebhookDelivery(dl *webhook.Delivery) types.WebhookDelivery {
+	out := types.WebhookDelivery{
+		ID:            dl.ID,
+		Subscriber:    dl.Subscriber,
+		URL:           redactURL(dl.URL),
+		Attempts:      dl.Attempts,
+		LastError:     redactError(dl.LastError, dl.URL),
+		CreatedAt:     dl.CreatedAt.Format(time.RFC3339Nano),
+		LastAttemptAt: dl.LastAttemptAt.Format(time.RFC3339Nano),
+	}
+	if e := dl.Event; e != nil {
+		out.Event = types.WebhookEvent{
+			ID:          e.ID,
+			Type:        string(e.Type),
+			Timestamp:   e.Timestamp.Format(time.RFC3339Nano),
+			RunID:       e.RunID,
+			ProxyName:   e.ProxyName,
+			ProxyType:   e.ProxyType,
+			VisitorName: e.VisitorName,
+			VisitorType: e.VisitorType,
+			PrevPhase:   e.PrevPhase,
+			Phase:       e.Phase,
+			Error:       e.Error,
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListSubscribersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListSubscribersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListSubscribersLogic {
+	return &ListSubscribersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListSubscribersLogic) ListSubscribers() (resp *types.ListSubscribersResp, err error) {
+	subs := webhook.Subscribers()
+	resp = &types.ListSubscribersResp{
+		ErrCode: errorx.CodeOK,
+		Respond: make([]types.WebhookSubscriber, 0, len(subs)),
+	}
+	for _, s := range subs {
+		resp.Respond = append(resp.Respond, toWebhookSubscriber(s))
+	}
+	return resp, nil
+}
+
+const redacted = "***"
+
+func toWebhookSubscriber(s webhook.SubscriberInfo) types.WebhookSubscriber {
+	format := s.Conf.Format
+	if format == "" {
+		format = webhook.FormatRaw
+	}
+	return types.WebhookSubscriber{
+		Name:        s.Conf.Name,
+		Url:         redactURL(s.Conf.Url),
+		Events:      s.Conf.Events,
+		Names:       s.Conf.Names,
+		Headers:     redactHeaders(s.Conf.Headers),
+		TimeoutMs:   s.Conf.Timeout.Milliseconds(),
+		Format:      format,
+		Template:    s.Conf.Template,
+		ContentType: s.Conf.ContentType,
+		Signed:      s.Signed,
+		Static:      s.Static,
+		Stats: types.WebhookSubscriberStats{
+			Delivered:       s.Stats.Delivered,
+			Failed:          s.Stats.Failed,
+			DeadLetters:     s.Stats.DeadLetters,
+			Pending:         s.Stats.Pending,
+			LastDeliveredAt: formatTime(s.Stats.LastDeliveredAt),
+			LastError:       redactError(s.Stats.LastError, s.Conf.Url),
+			LastErrorAt:     formatTime(s.Stats.LastErrorAt),
+		},
+	}
+}
+
+// redactHeaders 隐藏header的值，可能含有Authorization等凭据
+func redactHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k := range headers {
+		out[k] = redacted
+	}
+	return out
+}
+
+// redactURL 仅保留scheme及host，用户信息、路径及参数显示为***，
+// Slack等incoming webhook的密钥在路径中
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	out := u.Scheme + "://"
+	if u.User != nil {
+		out += redacted + "@"
+	}
+	out += u.Host
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" || u.Fragment != "" {
+		out += "/" + redacted
+	}
+	return out
+}
+
+// redactError 隐藏错误信息中的url，http请求的错误包含完整url
+func redactError(s string, rawURL string) string {
+	if s == "" || rawURL == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, rawURL, redactURL(rawURL))
+}
+
+// formatTime 零值返回空字符串
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
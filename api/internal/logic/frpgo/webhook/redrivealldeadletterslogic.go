@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"context"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RedriveAllDeadLettersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRedriveAllDeadLettersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RedriveAllDeadLettersLogic {
+	return &RedriveAllDeadLettersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *RedriveAllDeadLettersLogic) RedriveAllDeadLetters() (resp *types.RedriveDeadLetterResp, err error) {
+	return &types.RedriveDeadLetterResp{
+		ErrCode: errorx.CodeOK,
+		Respond: webhook.RedriveAll(),
+	}, nil
+}
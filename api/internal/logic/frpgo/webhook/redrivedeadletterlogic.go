@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+
+	"frpgo/api/internal/errorx"
+	"frpgo/api/internal/svc"
+	"frpgo/api/internal/types"
+	"frpgo/fmgr/webhook"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RedriveDeadLetterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRedriveDeadLetterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RedriveDeadLetterLogic {
+	return &RedriveDeadLetterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *RedriveDeadLetterLogic) RedriveDeadLetter(req *types.RedriveDeadLetterReq) (resp *types.RedriveDeadLetterResp, err error) {
+	if err = webhook.Redrive(req.ID); err != nil {
+		return nil, errorx.FromClientError(err)
+	}
+
+	return &types.RedriveDeadLetterResp{
+		ErrCode: errorx.CodeOK,
+		Respond: 1,
+	}, nil
+}
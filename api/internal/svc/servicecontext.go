@@ -15,10 +15,10 @@ type ServiceContext struct {
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
+	// 创建失败时ProxyService为nil，接口统一返回503
 	svr, err := fmgr.CreateService(c)
 	if err != nil {
-		logx.Errorf("FMGR CreateServer error %v", c.Frp.Conf)
-		return nil
+		logx.Errorf("FMGR CreateServer error %v, conf: %v", err, c.Frp.Conf)
 	}
 
 	return &ServiceContext{
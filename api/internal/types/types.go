@@ -7,20 +7,88 @@ type ConfigInfo struct {
 	Inspect   bool   `json:"inspect"`
 }
 
+type HeaderInfo struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type TransportInfo struct {
+	UseEncryption        bool   `json:"use_encryption,optional"`
+	UseCompression       bool   `json:"use_compression,optional"`
+	BandwidthLimit       string `json:"bandwidth_limit,optional"`        // 1MB, 512KB
+	BandwidthLimitMode   string `json:"bandwidth_limit_mode,optional"`   // client | server
+	ProxyProtocolVersion string `json:"proxy_protocol_version,optional"` // v1 | v2
+}
+
+type HealthCheckInfo struct {
+	Type            string       `json:"type,optional"` // tcp | http
+	TimeoutSeconds  int          `json:"timeout_seconds,optional"`
+	MaxFailed       int          `json:"max_failed,optional"`
+	IntervalSeconds int          `json:"interval_seconds,optional"`
+	Path            string       `json:"path,optional"`
+	HTTPHeaders     []HeaderInfo `json:"http_headers,optional"`
+}
+
+type LoadBalancerInfo struct {
+	Group    string `json:"group,optional"`
+	GroupKey string `json:"group_key,optional"`
+}
+
+type PluginInfo struct {
+	Type              string            `json:"type,optional"`
+	LocalAddr         string            `json:"local_addr,optional"`
+	HostHeaderRewrite string            `json:"host_header_rewrite,optional"`
+	RequestHeaders    map[string]string `json:"request_headers,optional"`
+	EnableHTTP2       *bool             `json:"enable_http2,optional"`
+	CrtPath           string            `json:"crt_path,optional"`
+	KeyPath           string            `json:"key_path,optional"`
+	HTTPUser          string            `json:"http_user,optional"`
+	HTTPPassword      string            `json:"http_password,optional"`
+	Username          string            `json:"username,optional"`
+	Password          string            `json:"password,optional"`
+	LocalPath         string            `json:"local_path,optional"`
+	StripPrefix       string            `json:"strip_prefix,optional"`
+	UnixPath          string            `json:"unix_path,optional"`
+}
+
 type StartTunnelReq struct {
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	LocalIP    string `json:"local_ip"`
-	LocalPort  int    `json:"local_port"`
-	RemotePort int    `json:"remote_port"`
+	Name              string            `json:"name"`
+	Type              string            `json:"type"` // tcp | udp | http | https | tcpmux | stcp | xtcp | sudp
+	LocalIP           string            `json:"local_ip,optional"`
+	LocalPort         int               `json:"local_port,optional"`
+	RemotePort        int               `json:"remote_port,optional"` // tcp | udp
+	SubDomain         string            `json:"subdomain,optional"`
+	CustomDomains     []string          `json:"custom_domains,optional"`
+	Locations         []string          `json:"locations,optional"`           // http
+	HTTPUser          string            `json:"http_user,optional"`           // http | tcpmux
+	HTTPPassword      string            `json:"http_password,optional"`       // http | tcpmux
+	RouteByHTTPUser   string            `json:"route_by_http_user,optional"`  // http | tcpmux
+	HostHeaderRewrite string            `json:"host_header_rewrite,optional"` // http
+	RequestHeaders    map[string]string `json:"request_headers,optional"`     // http
+	ResponseHeaders   map[string]string `json:"response_headers,optional"`    // http
+	Multiplexer       string            `json:"multiplexer,optional"`         // tcpmux
+	SecretKey         string            `json:"secret_key,optional"`
+	AllowUsers        []string          `json:"allow_users,optional"`
+	Transport         TransportInfo     `json:"transport,optional"`
+	HealthCheck       HealthCheckInfo   `json:"health_check,optional"`
+	LoadBalancer      LoadBalancerInfo  `json:"load_balancer,optional"`
+	Plugin            PluginInfo        `json:"plugin,optional"`
+	Metadatas         map[string]string `json:"metadatas,optional"`
+	Annotations       map[string]string `json:"annotations,optional"`
+	Inspect           bool              `json:"inspect,optional"` // http, 抓取请求
 }
 
 type StartTunnelResp struct {
-	Name      string     `json:"name"`       //
-	URI       string     `json:"uri"`        // /api/tunnels
-	PublicUrl string     `json:"public_url"` // tcp://****.3232
-	Proto     string     `json:"proto"`      // tcp
-	Config    ConfigInfo `json:"config"`     //
+	Name      string      `json:"name"`       //
+	URI       string      `json:"uri"`        // /api/tunnels
+	PublicUrl string      `json:"public_url"` // tcp://****.3232
+	Proto     string      `json:"proto"`      // tcp
+	Config    ConfigInfo  `json:"config"`     //
+	Stats     TunnelStats `json:"stats"`
+}
+
+type StopTunnelReq struct {
+	Name string `path:"name"`
 }
 
 type StopTunnelResp struct {
@@ -29,21 +97,380 @@ type StopTunnelResp struct {
 	Respond string `json:"respond"`
 }
 
+type PauseTunnelReq struct {
+	Name string `path:"name"`
+}
+
+type PauseTunnelResp struct {
+	ErrCode string `json:"errcode"`
+	ErrTxt  string `json:"errtxt"`
+	Respond string `json:"respond"`
+}
+
+type ResumeTunnelReq struct {
+	Name string `path:"name"`
+}
+
+type ResumeTunnelResp struct {
+	ErrCode string `json:"errcode"`
+	ErrTxt  string `json:"errtxt"`
+	Respond string `json:"respond"`
+}
+
+type TransportPatch struct {
+	UseEncryption        *bool   `json:"use_encryption,optional"`
+	UseCompression       *bool   `json:"use_compression,optional"`
+	BandwidthLimit       *string `json:"bandwidth_limit,optional"`
+	BandwidthLimitMode   *string `json:"bandwidth_limit_mode,optional"`
+	ProxyProtocolVersion *string `json:"proxy_protocol_version,optional"`
+}
+
+type UpdateTunnelReq struct {
+	Name              string            `path:"name"`
+	LocalIP           *string           `json:"local_ip,optional"`
+	LocalPort         *int              `json:"local_port,optional"`
+	RemotePort        *int              `json:"remote_port,optional"` // tcp | udp
+	SubDomain         *string           `json:"subdomain,optional"`
+	CustomDomains     []string          `json:"custom_domains,optional"`
+	Locations         []string          `json:"locations,optional"`
+	HTTPUser          *string           `json:"http_user,optional"`
+	HTTPPassword      *string           `json:"http_password,optional"`
+	RouteByHTTPUser   *string           `json:"route_by_http_user,optional"`
+	HostHeaderRewrite *string           `json:"host_header_rewrite,optional"`
+	RequestHeaders    map[string]string `json:"request_headers,optional"`
+	ResponseHeaders   map[string]string `json:"response_headers,optional"`
+	SecretKey         *string           `json:"secret_key,optional"`
+	AllowUsers        []string          `json:"allow_users,optional"`
+	Transport         *TransportPatch   `json:"transport,optional"`
+	HealthCheck       *HealthCheckInfo  `json:"health_check,optional"`  // 整体替换
+	LoadBalancer      *LoadBalancerInfo `json:"load_balancer,optional"` // 整体替换
+	Plugin            *PluginInfo       `json:"plugin,optional"`        // 整体替换，type为空时移除插件
+	Metadatas         map[string]string `json:"metadatas,optional"`
+	Annotations       map[string]string `json:"annotations,optional"`
+	Inspect           *bool             `json:"inspect,optional"` // http
+}
+
+type UpdateTunnelResult struct {
+	Mode   string              `json:"mode"` // none | hot-swap | reopen
+	Tunnel GetTunnelDetialResp `json:"tunnel"`
+}
+
+type UpdateTunnelResp struct {
+	ErrCode string             `json:"errcode"`
+	ErrTxt  string             `json:"errtxt"`
+	Respond UpdateTunnelResult `json:"respond"`
+}
+
 type GetTunnelDetailReq struct {
 	Name string `path:"name"`
 }
 
 type GetTunnelDetialResp struct {
-	Name      string     `json:"name"`       //
-	URI       string     `json:"uri"`        // /api/tunnels
-	PublicUrl string     `json:"public_url"` // tcp://****.3232
-	Type      string     `json:"type"`       // tcp
-	Status    string     `json:"status"`     // tcp
-	Config    ConfigInfo `json:"config"`     //
+	Name       string      `json:"name"`       //
+	URI        string      `json:"uri"`        // /api/tunnels
+	PublicUrl  string      `json:"public_url"` // tcp://****.3232
+	Type       string      `json:"type"`       // tcp
+	Status     string      `json:"status"`     // tcp
+	RemoteAddr string      `json:"remote_addr"`
+	Err        string      `json:"err"`
+	CreatedAt  string      `json:"created_at"` // RFC3339
+	Origin     string      `json:"origin"`     // file | api
+	Config     ConfigInfo  `json:"config"`     //
+	Stats      TunnelStats `json:"stats"`
 }
 
-type ListCaptureRequestResp struct {
+type ListTunnelsReq struct {
+	Type       string `form:"type,optional"`       // 逗号分隔
+	Phase      string `form:"phase,optional"`      // 逗号分隔，如running,paused
+	Name       string `form:"name,optional"`       // glob，逗号分隔
+	Metadata   string `form:"metadata,optional"`   // metadatas的标签选择器，如env=dev,team in (a,b)
+	Annotation string `form:"annotation,optional"` // annotations的标签选择器
+	Origin     string `form:"origin,optional"`     // file | api
+	Sort       string `form:"sort,optional"`       // name(默认) | created | traffic，前缀-表示倒序；traffic不分页，仅返回前limit个
+	Cursor     string `form:"cursor,optional"`     // 上一页返回的next_cursor，sort=traffic时不支持
+	Limit      int    `form:"limit,optional"`      // 0: 不限制
+}
+
+type TunnelList struct {
+	Tunnels    []GetTunnelDetialResp `json:"tunnels"`
+	Total      int                   `json:"total"`       // 满足条件的总数
+	NextCursor string                `json:"next_cursor"` // 为空时没有下一页
+}
+
+type ListTunnelsResp struct {
 	ErrCode string     `json:"errcode"`
 	ErrTxt  string     `json:"errtxt"`
-	Respond ConfigInfo `json:"respond"`
+	Respond TunnelList `json:"respond"`
+}
+
+type BatchTunnelsReq struct {
+	Action     string `json:"action"`              // stop(同delete) | pause | resume
+	Type       string `json:"type,optional"`       // 以下过滤条件同ListTunnelsReq，不能全部为空
+	Phase      string `json:"phase,optional"`      //
+	Name       string `json:"name,optional"`       //
+	Metadata   string `json:"metadata,optional"`   //
+	Annotation string `json:"annotation,optional"` //
+	Origin     string `json:"origin,optional"`     //
+}
+
+type BatchTunnelsResult struct {
+	Tunnels []string          `json:"tunnels"` // 执行成功的隧道
+	Errors  map[string]string `json:"errors"`  // 执行失败的隧道及原因
+}
+
+type BatchTunnelsResp struct {
+	ErrCode string             `json:"errcode"`
+	ErrTxt  string             `json:"errtxt"`
+	Respond BatchTunnelsResult `json:"respond"`
+}
+
+type ListCaptureRequestReq struct {
+	Limit      int    `path:"limit"` // 0: 不限制
+	TunnelName string `path:"tunnel_name"`
+	Method     string `form:"method,optional"`
+	Path       string `form:"path,optional"` // uri前缀
+	Status     int    `form:"status,optional"`
+}
+
+type CapturedHTTPRequest struct {
+	Method    string              `json:"method"`
+	URI       string              `json:"uri"`
+	Proto     string              `json:"proto"`
+	Host      string              `json:"host"`
+	Headers   map[string][]string `json:"headers"`
+	Body      []byte              `json:"body"` // base64
+	BodySize  int64               `json:"body_size"`
+	Truncated bool                `json:"truncated"`
+}
+
+type CapturedHTTPResponse struct {
+	Status     string              `json:"status"`
+	StatusCode int                 `json:"status_code"`
+	Proto      string              `json:"proto"`
+	Headers    map[string][]string `json:"headers"`
+	Body       []byte              `json:"body"` // base64
+	BodySize   int64               `json:"body_size"`
+	Truncated  bool                `json:"truncated"`
+}
+
+type CapturedRequest struct {
+	ID         string                `json:"id"`
+	TunnelName string                `json:"tunnel_name"`
+	RemoteAddr string                `json:"remote_addr"`
+	Start      string                `json:"start"` // RFC3339
+	DurationMs int64                 `json:"duration_ms"`
+	Request    CapturedHTTPRequest   `json:"request"`
+	Response   *CapturedHTTPResponse `json:"response"`
+	Err        string                `json:"err"`
+	ReplayOf   string                `json:"replay_of,omitempty"` // 重放的原始请求id
+}
+
+type ListCaptureRequestResp struct {
+	ErrCode string            `json:"errcode"`
+	ErrTxt  string            `json:"errtxt"`
+	Respond []CapturedRequest `json:"respond"`
+}
+
+type ReplayCapturedRequestReq struct {
+	TunnelName string            `path:"tunnel_name"`
+	ID         string            `path:"id"`
+	Method     string            `json:"method,optional"`
+	Path       string            `json:"path,optional"`    // 包含query
+	Headers    map[string]string `json:"headers,optional"` // 覆盖同名header
+	Body       *string           `json:"body,optional"`
+}
+
+type ReplayCapturedRequestResp struct {
+	ErrCode string          `json:"errcode"`
+	ErrTxt  string          `json:"errtxt"`
+	Respond CapturedRequest `json:"respond"`
+}
+
+type WebhookEvent struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Timestamp   string `json:"timestamp"` // RFC3339
+	RunID       string `json:"run_id"`
+	ProxyName   string `json:"proxy_name,omitempty"`
+	ProxyType   string `json:"proxy_type,omitempty"`
+	VisitorName string `json:"visitor_name,omitempty"`
+	VisitorType string `json:"visitor_type,omitempty"`
+	PrevPhase   string `json:"prev_phase,omitempty"`
+	Phase       string `json:"phase,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID            string       `json:"id"`
+	Subscriber    string       `json:"subscriber"`
+	URL           string       `json:"url"` // 仅显示scheme及host，其余为***
+	Event         WebhookEvent `json:"event"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error"`
+	CreatedAt     string       `json:"created_at"`      // RFC3339
+	LastAttemptAt string       `json:"last_attempt_at"` // RFC3339
+}
+
+type ListDeadLettersResp struct {
+	ErrCode string            `json:"errcode"`
+	ErrTxt  string            `json:"errtxt"`
+	Respond []WebhookDelivery `json:"respond"` // 从新到旧
+}
+
+type RedriveDeadLetterReq struct {
+	ID string `path:"id"`
+}
+
+type RedriveDeadLetterResp struct {
+	ErrCode string `json:"errcode"`
+	ErrTxt  string `json:"errtxt"`
+	Respond int    `json:"respond"` // 重新投递的数量
+}
+
+type WebhookSubscriberStats struct {
+	Delivered       int64  `json:"delivered"`
+	Failed          int64  `json:"failed"` // 失败的尝试次数
+	DeadLetters     int64  `json:"dead_letters"`
+	Pending         int64  `json:"pending"`
+	LastDeliveredAt string `json:"last_delivered_at"` // RFC3339
+	LastError       string `json:"last_error"`
+	LastErrorAt     string `json:"last_error_at"` // RFC3339
+}
+
+type WebhookSubscriber struct {
+	Name        string                 `json:"name"`
+	Url         string                 `json:"url"` // 仅显示scheme及host，其余为***
+	Events      []string               `json:"events"`
+	Names       []string               `json:"names"`
+	Headers     map[string]string      `json:"headers"` // 值显示为***
+	TimeoutMs   int64                  `json:"timeout_ms"`
+	Format      string                 `json:"format"` // raw, cloudevents, template
+	Template    string                 `json:"template"`
+	ContentType string                 `json:"content_type"`
+	Signed      bool                   `json:"signed"` // 是否配置了密钥
+	Static      bool                   `json:"static"` // 来自配置文件，不能删除
+	Stats       WebhookSubscriberStats `json:"stats"`
+}
+
+type ListSubscribersResp struct {
+	ErrCode string              `json:"errcode"`
+	ErrTxt  string              `json:"errtxt"`
+	Respond []WebhookSubscriber `json:"respond"`
+}
+
+type CreateSubscriberReq struct {
+	Name        string            `json:"name"`
+	Url         string            `json:"url"`
+	Events      []string          `json:"events,optional"` // glob，为空时接收全部事件
+	Names       []string          `json:"names,optional"`  // 代理及访问者名glob
+	Headers     map[string]string `json:"headers,optional"`
+	TimeoutMs   int64             `json:"timeout_ms,optional"`
+	Format      string            `json:"format,optional"`       // raw(默认), cloudevents, template
+	Template    string            `json:"template,optional"`     // text/template，以事件为数据，不能引用.Envs
+	ContentType string            `json:"content_type,optional"` // template格式的Content-Type，默认application/json
+	Secret      string            `json:"secret,optional"`
+	Secrets     []string          `json:"secrets,optional"`
+}
+
+type CreateSubscriberResp struct {
+	ErrCode string            `json:"errcode"`
+	ErrTxt  string            `json:"errtxt"`
+	Respond WebhookSubscriber `json:"respond"`
+}
+
+type DeleteSubscriberReq struct {
+	Name string `path:"name"`
+}
+
+type DeleteSubscriberResp struct {
+	ErrCode string `json:"errcode"`
+	ErrTxt  string `json:"errtxt"`
+	Respond string `json:"respond"`
+}
+
+type TunnelStats struct {
+	TrafficIn         int64   `json:"traffic_in"` // 字节，连接关闭时统计
+	TrafficOut        int64   `json:"traffic_out"`
+	TrafficInHistory  []int64 `json:"traffic_in_history"` // 最近7天，今天在前
+	TrafficOutHistory []int64 `json:"traffic_out_history"`
+	CurConns          int64   `json:"cur_conns"`
+	TotalConns        int64   `json:"total_conns"`
+	DialFailures      int64   `json:"dial_failures"` // 连接本地服务失败次数
+	AvgConnDurationMs int64   `json:"avg_conn_duration_ms"`
+	LastConnAt        string  `json:"last_conn_at"` // RFC3339
+}
+
+type StreamEventsReq struct {
+	Name             string `form:"name,optional"`   // 隧道名，逗号分隔，支持glob
+	Type             string `form:"type,optional"`   // 隧道类型，逗号分隔，如tcp,http
+	Events           string `form:"events,optional"` // 事件类型，逗号分隔，支持glob如proxy.*
+	LastEventID      string `header:"Last-Event-ID,optional"`
+	LastEventIDQuery string `form:"last_event_id,optional"`
+}
+
+type ServerStatus struct {
+	Name                string   `json:"name"`
+	Addr                string   `json:"addr"`
+	Protocol            string   `json:"protocol"`  // 当前使用的传输协议
+	Protocols           []string `json:"protocols"` // 协议回退链，优先的在前
+	Weight              int      `json:"weight"`
+	Active              bool     `json:"active"`  // 当前控制连接所在的服务端
+	Healthy             bool     `json:"healthy"` // 不在登录失败的退避期内
+	Score               float64  `json:"score"`   // 权重按连续失败次数折算
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	TotalFailures       int64    `json:"total_failures"`
+	TotalSuccesses      int64    `json:"total_successes"`
+	LastError           string   `json:"last_error,omitempty"`
+	LastFailureAt       string   `json:"last_failure_at,omitempty"` // RFC3339
+	LastSuccessAt       string   `json:"last_success_at,omitempty"` // RFC3339
+	RetryAfter          string   `json:"retry_after,omitempty"`     // RFC3339，退避结束时间
+}
+
+type FailoverRecord struct {
+	Time   string `json:"time"` // RFC3339
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"` // 原服务端最后一次登录失败的原因
+}
+
+type ServersInfo struct {
+	Strategy  string           `json:"strategy"` // ordered | weighted
+	Active    string           `json:"active"`
+	Servers   []ServerStatus   `json:"servers"`
+	Failovers []FailoverRecord `json:"failovers"` // 从旧到新
+}
+
+type ListServersResp struct {
+	ErrCode string      `json:"errcode"`
+	ErrTxt  string      `json:"errtxt"`
+	Respond ServersInfo `json:"respond"`
+}
+
+type HeartbeatRTT struct {
+	Last    float64 `json:"last_ms"`
+	Avg     float64 `json:"avg_ms"`
+	Min     float64 `json:"min_ms"`
+	Max     float64 `json:"max_ms"`
+	Samples int     `json:"samples"` // 最近的心跳次数，未开启心跳时为0
+}
+
+type ServiceStatus struct {
+	State        string       `json:"state"` // connecting | logged-in | reconnecting | stopped
+	RunID        string       `json:"run_id"`
+	Server       string       `json:"server"`
+	ServerAddr   string       `json:"server_addr"`
+	Protocol     string       `json:"protocol"`
+	TCPMux       bool         `json:"tcp_mux"`
+	Streams      int          `json:"streams"`            // 多路复用连接上打开的流数，未复用时为-1
+	LoginAt      string       `json:"login_at,omitempty"` // RFC3339
+	Reconnects   int64        `json:"reconnects"`
+	LastError    string       `json:"last_error,omitempty"`
+	LastErrorAt  string       `json:"last_error_at,omitempty"` // RFC3339
+	HeartbeatRTT HeartbeatRTT `json:"heartbeat_rtt"`
+}
+
+type GetServiceStatusResp struct {
+	ErrCode string        `json:"errcode"`
+	ErrTxt  string        `json:"errtxt"`
+	Respond ServiceStatus `json:"respond"`
 }
@@ -30,6 +30,7 @@ import (
 
 	"frpgo/pkg/config"
 	"frpgo/pkg/config/v1/validation"
+	"frpgo/pkg/metrics"
 	httppkg "frpgo/pkg/util/http"
 	"frpgo/pkg/util/log"
 	netpkg "frpgo/pkg/util/net"
@@ -50,9 +51,15 @@ func (svr *Service) registerRouteHandlers(helper *httppkg.RouterRegisterHelper)
 	subRouter.HandleFunc("/api/reload", svr.apiReload).Methods("GET")
 	subRouter.HandleFunc("/api/stop", svr.apiStop).Methods("POST")
 	subRouter.HandleFunc("/api/status", svr.apiStatus).Methods("GET")
+	subRouter.HandleFunc("/api/servers", svr.apiServers).Methods("GET")
+	subRouter.HandleFunc("/api/service/status", svr.apiServiceStatus).Methods("GET")
 	subRouter.HandleFunc("/api/config", svr.apiGetConfig).Methods("GET")
 	subRouter.HandleFunc("/api/config", svr.apiPutConfig).Methods("PUT")
 
+	if metrics.PrometheusEnabled() {
+		subRouter.Handle("/metrics", metrics.Handler()).Methods("GET")
+	}
+
 	// view
 	subRouter.Handle("/favicon.ico", http.FileServer(helper.AssetsFS)).Methods("GET")
 	subRouter.PathPrefix("/static/").Handler(
@@ -76,6 +83,12 @@ func (svr *Service) apiReload(w http.ResponseWriter, r *http.Request) {
 	if strictStr != "" {
 		strictConfigMode, _ = strconv.ParseBool(strictStr)
 	}
+	// proxies created by api are kept unless clearRuntime is set
+	clearRuntime := false
+	clearRuntimeStr := r.URL.Query().Get("clearRuntime")
+	if clearRuntimeStr != "" {
+		clearRuntime, _ = strconv.ParseBool(clearRuntimeStr)
+	}
 
 	log.Infof("api request [/api/reload]")
 	defer func() {
@@ -100,7 +113,11 @@ func (svr *Service) apiReload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := svr.UpdateAllConfigurer(proxyCfgs, visitorCfgs); err != nil {
+	updateFn := svr.UpdateAllConfigurer
+	if clearRuntime {
+		updateFn = svr.ResetAllConfigurer
+	}
+	if err := updateFn(proxyCfgs, visitorCfgs); err != nil {
 		res.Code = 500
 		res.Msg = err.Error()
 		log.Warnf("reload frpc proxy config error: %s", res.Msg)
@@ -135,6 +152,8 @@ type ProxyStatusResp struct {
 	LocalAddr  string `json:"local_addr"`
 	Plugin     string `json:"plugin"`
 	RemoteAddr string `json:"remote_addr"`
+
+	Stats proxy.StatsSnapshot `json:"stats"`
 }
 
 func NewProxyStatusResp(status *proxy.WorkingStatus, serverAddr string) ProxyStatusResp {
@@ -143,6 +162,7 @@ func NewProxyStatusResp(status *proxy.WorkingStatus, serverAddr string) ProxySta
 		Type:   status.Type,
 		Status: status.Phase,
 		Err:    status.Err,
+		Stats:  status.Stats,
 	}
 	baseCfg := status.Cfg.GetBaseConfig()
 	if baseCfg.LocalPort != 0 {
@@ -182,7 +202,7 @@ func (svr *Service) apiStatus(w http.ResponseWriter, _ *http.Request) {
 
 	ps := ctl.pm.GetAllProxyStatus()
 	for _, status := range ps {
-		res[status.Type] = append(res[status.Type], NewProxyStatusResp(status, svr.common.ServerAddr))
+		res[status.Type] = append(res[status.Type], NewProxyStatusResp(status, ctl.sessionCtx.Common.ServerAddr))
 	}
 
 	for _, arrs := range res {
@@ -195,6 +215,20 @@ func (svr *Service) apiStatus(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// GET /api/servers
+func (svr *Service) apiServers(w http.ResponseWriter, _ *http.Request) {
+	log.Infof("Http request [/api/servers]")
+	buf, _ := json.Marshal(svr.ServersStatus())
+	_, _ = w.Write(buf)
+}
+
+// GET /api/service/status
+func (svr *Service) apiServiceStatus(w http.ResponseWriter, _ *http.Request) {
+	log.Infof("Http request [/api/service/status]")
+	buf, _ := json.Marshal(svr.Status())
+	_, _ = w.Write(buf)
+}
+
 // GET /api/config
 func (svr *Service) apiGetConfig(w http.ResponseWriter, _ *http.Request) {
 	res := GeneralResponse{Code: 200}
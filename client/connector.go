@@ -22,12 +22,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	libnet "github.com/fatedier/golib/net"
 	fmux "github.com/hashicorp/yamux"
 	quic "github.com/quic-go/quic-go"
 	"github.com/samber/lo"
+	kcp "github.com/xtaci/kcp-go/v5"
 
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/transport"
@@ -50,6 +52,9 @@ type defaultConnectorImpl struct {
 	muxSession *fmux.Session
 	quicConn   quic.Connection
 	closeOnce  sync.Once
+
+	// open streams on quicConn
+	quicStreams atomic.Int64
 }
 
 func NewConnector(ctx context.Context, cfg *v1.ClientCommonConfig) Connector {
@@ -132,7 +137,11 @@ func (c *defaultConnectorImpl) Connect() (net.Conn, error) {
 		if err != nil {
 			return nil, err
 		}
-		return netpkg.QuicStreamToNetConn(stream, c.quicConn), nil
+		c.quicStreams.Add(1)
+		return &countedConn{
+			Conn:    netpkg.QuicStreamToNetConn(stream, c.quicConn),
+			counter: &c.quicStreams,
+		}, nil
 	} else if c.muxSession != nil {
 		stream, err := c.muxSession.OpenStream()
 		if err != nil {
@@ -144,6 +153,30 @@ func (c *defaultConnectorImpl) Connect() (net.Conn, error) {
 	return c.realConnect()
 }
 
+// NumStreams returns the number of open streams on the multiplexed connection.
+func (c *defaultConnectorImpl) NumStreams() int {
+	if c.quicConn != nil {
+		return int(c.quicStreams.Load())
+	} else if c.muxSession != nil {
+		return c.muxSession.NumStreams()
+	}
+	return -1
+}
+
+// countedConn decreases the counter once it is closed.
+type countedConn struct {
+	net.Conn
+	counter   *atomic.Int64
+	closeOnce sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.counter.Add(-1)
+	})
+	return c.Conn.Close()
+}
+
 func (c *defaultConnectorImpl) realConnect() (net.Conn, error) {
 	xl := xlog.FromContextSafe(c.ctx)
 	var tlsConfig *tls.Config
@@ -169,6 +202,10 @@ func (c *defaultConnectorImpl) realConnect() (net.Conn, error) {
 		}
 	}
 
+	if c.cfg.Transport.Protocol == "kcp" {
+		return c.dialKCP(tlsConfig)
+	}
+
 	proxyType, addr, auth, err := libnet.ParseProxyURL(c.cfg.Transport.ProxyURL)
 	if err != nil {
 		xl.Errorf("fail to parse proxy url")
@@ -214,6 +251,45 @@ func (c *defaultConnectorImpl) realConnect() (net.Conn, error) {
 	return conn, err
 }
 
+// dialKCP connects to the server over kcp with the options in transport.kcp.
+// ProxyURL and ConnectServerLocalIP are not supported in KCP protocol.
+func (c *defaultConnectorImpl) dialKCP(tlsConfig *tls.Config) (net.Conn, error) {
+	opts := c.cfg.Transport.KCP
+	kcpConn, err := kcp.DialWithOptions(
+		net.JoinHostPort(c.cfg.ServerAddr, strconv.Itoa(c.cfg.ServerPort)),
+		nil,
+		max(opts.DataShards, 0),
+		max(opts.ParityShards, 0),
+	)
+	if err != nil {
+		return nil, err
+	}
+	kcpConn.SetStreamMode(true)
+	kcpConn.SetWriteDelay(true)
+	kcpConn.SetNoDelay(
+		lo.Ternary(lo.FromPtr(opts.NoDelay), 1, 0),
+		opts.Interval,
+		max(opts.Resend, 0),
+		lo.Ternary(lo.FromPtr(opts.NoCongestion), 1, 0),
+	)
+	kcpConn.SetWindowSize(opts.SendWindow, opts.RecvWindow)
+	kcpConn.SetMtu(opts.MTU)
+	kcpConn.SetACKNoDelay(false)
+	_ = kcpConn.SetReadBuffer(opts.ReadBuffer)
+	_ = kcpConn.SetWriteBuffer(opts.WriteBuffer)
+
+	// same as the after hooks of tcp
+	_, conn, err := netpkg.DialHookCustomTLSHeadByte(tlsConfig != nil, lo.FromPtr(c.cfg.Transport.TLS.DisableCustomTLSFirstByte))(c.ctx, kcpConn, "")
+	if err != nil {
+		kcpConn.Close()
+		return nil, err
+	}
+	if tlsConfig != nil {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	return conn, nil
+}
+
 func (c *defaultConnectorImpl) Close() error {
 	c.closeOnce.Do(func() {
 		if c.quicConn != nil {
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	libnet "github.com/fatedier/golib/net"
+	fmux "github.com/hashicorp/yamux"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/transport"
+	netpkg "frpgo/pkg/util/net"
+)
+
+// serveKCPEcho accepts kcp connections in the same way as frps and echoes every stream.
+func serveKCPEcho(t *testing.T, l net.Listener) {
+	tlsConfig, err := transport.NewServerTLSConfig("", "", "")
+	require.NoError(t, err)
+
+	echo := func(c net.Conn) {
+		defer c.Close()
+		_, _ = io.Copy(c, c)
+	}
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			c, _, _, err := netpkg.CheckAndEnableTLSServerConnWithTimeout(c, tlsConfig, false, 5*time.Second)
+			if err != nil {
+				c.Close()
+				return
+			}
+			// first byte of a yamux frame is the protocol version 0
+			sc, r := libnet.NewSharedConn(c)
+			buf := make([]byte, 1)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				c.Close()
+				return
+			}
+			if buf[0] != 0 {
+				echo(sc)
+				return
+			}
+			session, err := fmux.Server(sc, nil)
+			if err != nil {
+				sc.Close()
+				return
+			}
+			for {
+				stream, err := session.AcceptStream()
+				if err != nil {
+					return
+				}
+				go echo(stream)
+			}
+		}()
+	}
+}
+
+func TestConnectorKCP(t *testing.T) {
+	l, err := netpkg.ListenKcp("127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go serveKCPEcho(t, l)
+	port := l.Addr().(*net.UDPAddr).Port
+
+	for _, tc := range []struct {
+		name   string
+		tcpMux bool
+		tls    bool
+	}{
+		{"mux", true, false},
+		{"mux-tls", true, true},
+		{"no-mux", false, false},
+		{"no-mux-tls", false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			cfg := &v1.ClientCommonConfig{
+				ServerAddr: "127.0.0.1",
+				ServerPort: port,
+				Transport: v1.ClientTransportConfig{
+					Protocol: "kcp",
+					TCPMux:   lo.ToPtr(tc.tcpMux),
+					KCP: v1.KCPOptions{
+						SendWindow: 256,
+						RecvWindow: 256,
+						MTU:        1200,
+					},
+					TLS: v1.TLSClientConfig{Enable: lo.ToPtr(tc.tls)},
+				},
+			}
+			cfg.Complete()
+
+			connector := NewConnector(context.Background(), cfg)
+			require.NoError(connector.Open())
+			defer connector.Close()
+
+			for i := 0; i < 2; i++ {
+				conn, err := connector.Connect()
+				require.NoError(err)
+				require.NoError(conn.SetDeadline(time.Now().Add(5 * time.Second)))
+
+				data := []byte("hello kcp " + strconv.Itoa(i))
+				_, err = conn.Write(data)
+				require.NoError(err)
+				buf := make([]byte, len(data))
+				_, err = io.ReadFull(conn, buf)
+				require.NoError(err)
+				require.Equal(data, buf)
+				conn.Close()
+			}
+		})
+	}
+}
@@ -16,13 +16,16 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"sync/atomic"
 	"time"
 
+	"frpgo/client/inspect"
+	"frpgo/client/metrics"
 	"frpgo/client/proxy"
+	"frpgo/client/tracing"
 	"frpgo/client/visitor"
-	"frpgo/fmgr/webhook"
 	"frpgo/pkg/auth"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
@@ -33,6 +36,8 @@ import (
 	"frpgo/pkg2/utils2"
 
 	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SessionContext struct {
@@ -70,6 +75,12 @@ type Control struct {
 
 	// of time.Time, last time got the Pong message
 	lastPong atomic.Value
+	// of time.Time, last time sent the Ping message
+	lastPing atomic.Value
+	// set if the control connection is closed due to heartbeat timeout
+	heartbeatTimedOut atomic.Bool
+	// RTT of the recent heartbeats
+	rtt rttWindow
 
 	// The role of msgTransporter is similar to HTTP2.
 	// It allows multiple messages to be sent simultaneously on the same control connection.
@@ -103,7 +114,7 @@ func NewControl(ctx context.Context, sessionCtx *SessionContext) (*Control, erro
 	ctl.registerMsgHandlers()
 	ctl.msgTransporter = transport.NewMessageTransporter(ctl.msgDispatcher.SendChannel())
 
-	ctl.pm = proxy.NewManager(ctl.ctx, sessionCtx.Common, ctl.msgTransporter)
+	ctl.pm = proxy.NewManager(ctl.ctx, sessionCtx.RunID, sessionCtx.Common, ctl.msgTransporter)
 	ctl.vm = visitor.NewManager(ctl.ctx, sessionCtx.RunID, sessionCtx.Common, ctl.connectServer, ctl.msgTransporter)
 	return ctl, nil
 }
@@ -122,11 +133,32 @@ func (ctl *Control) SetInWorkConnCallback(cb func(*v1.ProxyBaseConfig, net.Conn,
 	ctl.pm.SetInWorkConnCallback(cb)
 }
 
+func (ctl *Control) SetInspector(r *inspect.Recorder) {
+	ctl.pm.SetInspector(r)
+}
+
+func (ctl *Control) SetStatsRegistry(r *proxy.StatsRegistry) {
+	ctl.pm.SetStatsRegistry(r)
+}
+
+func (ctl *Control) SetPausedFunc(fn func(name string) bool) {
+	ctl.pm.SetPausedFunc(fn)
+}
+
 func (ctl *Control) handleReqWorkConn(_ msg.Message) {
 	logx.Debugf("handleReqWorkConn")
 
-	xl := ctl.xl
+	var err error
+	ctx, span := tracing.StartWorkConn(ctl.ctx, "control.req_work_conn", trace.WithAttributes(
+		attribute.String("run_id", ctl.sessionCtx.RunID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	xl := xlog.FromContextSafe(ctx)
+	start := time.Now()
+	_, connectSpan := tracing.StartChild(ctx, "connector.connect")
 	workConn, err := ctl.connectServer()
+	tracing.End(connectSpan, err)
 	if err != nil {
 		xl.Warnf("start new connection to server error: %v", err)
 		return
@@ -145,20 +177,31 @@ func (ctl *Control) handleReqWorkConn(_ msg.Message) {
 		workConn.Close()
 		return
 	}
+	metrics.Client.WorkConnLatency(time.Since(start))
 
+	// the work connection is pooled by the server until a user connection comes
+	_, waitSpan := tracing.StartChild(ctx, "workconn.wait_start")
 	var startMsg msg.StartWorkConn
-	if err = msg.ReadMsgInto(workConn, &startMsg); err != nil {
-		xl.Tracef("work connection closed before response StartWorkConn message: %v", err)
-		workConn.Close()
-		return
+	err = msg.ReadMsgInto(workConn, &startMsg)
+	if err == nil && startMsg.Error != "" {
+		err = fmt.Errorf("%s", startMsg.Error)
 	}
-	if startMsg.Error != "" {
-		xl.Errorf("StartWorkConn contains error: %s", startMsg.Error)
+	tracing.End(waitSpan, err)
+	if err != nil {
+		if startMsg.Error != "" {
+			xl.Errorf("StartWorkConn contains error: %s", startMsg.Error)
+		} else {
+			xl.Tracef("work connection closed before response StartWorkConn message: %v", err)
+		}
 		workConn.Close()
 		return
 	}
+	span.SetAttributes(attribute.String("proxy.name", startMsg.ProxyName))
 
-	// dispatch this work connection to related proxy
+	// dispatch this work connection to related proxy, the span goes with it
+	if span.SpanContext().IsValid() {
+		workConn = netpkg.NewContextConn(ctx, workConn)
+	}
 	ctl.pm.HandleWorkConn(startMsg.ProxyName, workConn, &startMsg)
 }
 
@@ -174,12 +217,6 @@ func (ctl *Control) handleNewProxyResp(m msg.Message) {
 		xl.Warnf("[%s] start error: %v", inMsg.ProxyName, err)
 	} else {
 		xl.Infof("[%s] start proxy success", inMsg.ProxyName)
-
-		// webhook
-		proxyDetial, isSuccess := ctl.pm.GetProxyDetail(inMsg.ProxyName)
-		if isSuccess {
-			webhook.PushProxyDetail(proxyDetial)
-		}
 	}
 }
 
@@ -203,7 +240,12 @@ func (ctl *Control) handlePong(m msg.Message) {
 		ctl.closeSession()
 		return
 	}
-	ctl.lastPong.Store(time.Now())
+	now := time.Now()
+	ctl.lastPong.Store(now)
+	if lastPing, ok := ctl.lastPing.Load().(time.Time); ok {
+		metrics.Client.HeartbeatRTT(now.Sub(lastPing))
+		ctl.rtt.add(now.Sub(lastPing))
+	}
 	xl.Debugf("receive heartbeat from server")
 }
 
@@ -232,6 +274,11 @@ func (ctl *Control) Done() <-chan struct{} {
 	return ctl.doneCh
 }
 
+// HeartbeatTimedOut returns true if the control connection is closed due to heartbeat timeout.
+func (ctl *Control) HeartbeatTimedOut() bool {
+	return ctl.heartbeatTimedOut.Load()
+}
+
 // connectServer return a new connection to frps
 func (ctl *Control) connectServer() (net.Conn, error) {
 	return ctl.sessionCtx.Connector.Connect()
@@ -257,6 +304,7 @@ func (ctl *Control) heartbeatWorker() {
 				xl.Warnf("error during ping authentication: %v, skip sending ping message", err)
 				return false, err
 			}
+			ctl.lastPing.Store(time.Now())
 			_ = ctl.msgDispatcher.Send(pingMsg)
 			return false, nil
 		}
@@ -278,6 +326,7 @@ func (ctl *Control) heartbeatWorker() {
 		go wait.Until(func() {
 			if time.Since(ctl.lastPong.Load().(time.Time)) > time.Duration(ctl.sessionCtx.Common.Transport.HeartbeatTimeout)*time.Second {
 				xl.Warnf("heartbeat timeout")
+				ctl.heartbeatTimedOut.Store(true)
 				ctl.closeSession()
 				return
 			}
@@ -0,0 +1,306 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"frpgo/client"
+	"frpgo/client/proxy"
+	"frpgo/fmgr/webhook"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/frpstest"
+)
+
+// Tests in this file run the service against the in-process frps of frpstest.
+
+func newProxyCfg(t *testing.T, proxyType, name string, localPort int) v1.ProxyConfigurer {
+	cfg, err := proxy.NewProxyConfigurer(proxyType, name, "127.0.0.1", localPort, 0)
+	require.NoError(t, err)
+	cfg.Complete("")
+	cfg.GetBaseConfig().Transport.UseEncryption = true
+	cfg.GetBaseConfig().Transport.UseCompression = true
+	return cfg
+}
+
+func TestServicePausedProxyAcrossReconnect(t *testing.T) {
+	require := require.New(t)
+	s, err := frpstest.NewServer(frpstest.Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "tcp", "echo", frpstest.StartTCPEcho(t))},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	frpstest.RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+	require.NoError(svr.PauseProxy("echo"))
+	require.Eventually(func() bool {
+		_, ok := s.ProxyAddr("echo")
+		return !ok
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// still paused after logging in again
+	runIDs := s.RunIDs()
+	require.Len(runIDs, 1)
+	require.True(s.CloseClient(runIDs[0]))
+	require.Eventually(func() bool { return svr.Status().Reconnects == 1 }, 10*time.Second, 50*time.Millisecond)
+	status, ok := svr.StatusExporter().GetProxyStatus("echo")
+	require.True(ok)
+	require.Equal(proxy.ProxyPhasePaused, status.Phase)
+	_, ok = s.ProxyAddr("echo")
+	require.False(ok)
+
+	require.NoError(svr.ResumeProxy("echo"))
+	frpstest.RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+	require.ErrorIs(svr.PauseProxy("unknown"), proxy.ErrProxyNotFound)
+}
+
+func TestServiceUpdateProxy(t *testing.T) {
+	require := require.New(t)
+	s, err := frpstest.NewServer(frpstest.Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "tcp", "echo", frpstest.StartTCPEcho(t))},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	addr := s.WaitProxyAddr(t, "echo")
+	frpstest.RequireEcho(t, "tcp", addr)
+
+	// switching to another local service keeps the remote port
+	localPort := frpstest.StartTCPEcho(t)
+	mode, err := svr.UpdateProxy("echo", func(cfg v1.ProxyConfigurer) error {
+		cfg.GetBaseConfig().LocalPort = localPort
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(proxy.UpdateModeHotSwap, mode)
+	frpstest.RequireEcho(t, "tcp", addr)
+	status, ok := svr.StatusExporter().GetProxyStatus("echo")
+	require.True(ok)
+	require.Equal(localPort, status.Cfg.GetBaseConfig().LocalPort)
+
+	mode, err = svr.UpdateProxy("echo", func(cfg v1.ProxyConfigurer) error {
+		cfg.GetBaseConfig().Transport.UseEncryption = false
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(proxy.UpdateModeReopen, mode)
+	require.Eventually(func() bool {
+		status, ok := svr.StatusExporter().GetProxyStatus("echo")
+		return ok && status.Phase == proxy.ProxyPhaseRunning
+	}, 5*time.Second, 20*time.Millisecond)
+	frpstest.RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+
+	_, err = svr.UpdateProxy("echo", func(cfg v1.ProxyConfigurer) error {
+		cfg.GetBaseConfig().Type = "udp"
+		return nil
+	})
+	require.ErrorIs(err, client.ErrInvalidConfig)
+}
+
+func TestServiceListProxies(t *testing.T) {
+	require := require.New(t)
+	s, err := frpstest.NewServer(frpstest.Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	webA := newProxyCfg(t, "tcp", "web-a", frpstest.StartTCPEcho(t))
+	webA.GetBaseConfig().Metadatas = map[string]string{"env": "dev"}
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "tcp", "web-b", frpstest.StartTCPEcho(t)), webA},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	s.WaitProxyAddr(t, "web-a")
+	s.WaitProxyAddr(t, "web-b")
+	dns := newProxyCfg(t, "udp", "dns", frpstest.StartUDPEcho(t))
+	dns.GetBaseConfig().Metadatas = map[string]string{"env": "dev"}
+	require.NoError(svr.AddProxy(dns))
+	s.WaitProxyAddr(t, "dns")
+	require.NoError(svr.PauseProxy("web-b"))
+
+	names := func(list *client.ProxyList) []string {
+		return lo.Map(list.Proxies, func(d *proxy.WorkingDetial, _ int) string { return d.Name })
+	}
+
+	// walk all pages sorted by name
+	var got []string
+	opts := client.ProxyListOptions{Limit: 2}
+	for {
+		list, err := svr.ListProxies(opts)
+		require.NoError(err)
+		require.Equal(3, list.Total)
+		got = append(got, names(list)...)
+		if list.NextCursor == "" {
+			break
+		}
+		opts.Cursor = list.NextCursor
+	}
+	require.Equal([]string{"dns", "web-a", "web-b"}, got)
+
+	list, err := svr.ListProxies(client.ProxyListOptions{SortBy: client.ProxySortByName, Desc: true})
+	require.NoError(err)
+	require.Equal([]string{"web-b", "web-a", "dns"}, names(list))
+
+	list, err = svr.ListProxies(client.ProxyListOptions{SortBy: client.ProxySortByCreated, Desc: true, Limit: 1})
+	require.NoError(err)
+	require.Equal([]string{"dns"}, names(list))
+	require.Equal(client.ProxyOriginAPI, list.Proxies[0].Origin)
+	require.NotEmpty(list.Proxies[0].RemoteAddr)
+	require.False(list.Proxies[0].CreatedAt.IsZero())
+
+	// the list sorted by traffic is not paginated
+	list, err = svr.ListProxies(client.ProxyListOptions{SortBy: client.ProxySortByTraffic, Desc: true, Limit: 2})
+	require.NoError(err)
+	require.Len(list.Proxies, 2)
+	require.Equal(3, list.Total)
+	require.Empty(list.NextCursor)
+
+	selector := func(s string) labels.Selector {
+		sel, err := client.ParseProxySelector(s)
+		require.NoError(err)
+		return sel
+	}
+	for _, c := range []struct {
+		filter client.ProxyFilter
+		want   []string
+	}{
+		{client.ProxyFilter{Types: []string{"tcp"}}, []string{"web-a", "web-b"}},
+		{client.ProxyFilter{Phases: []string{proxy.ProxyPhasePaused}}, []string{"web-b"}},
+		{client.ProxyFilter{Names: []string{"web-*"}}, []string{"web-a", "web-b"}},
+		{client.ProxyFilter{Metadatas: selector("env=dev")}, []string{"dns", "web-a"}},
+		{client.ProxyFilter{Metadatas: selector("env notin (dev)")}, []string{"web-b"}},
+		{client.ProxyFilter{Origin: client.ProxyOriginFile}, []string{"web-a", "web-b"}},
+		{client.ProxyFilter{Names: []string{"web-*"}, Metadatas: selector("env=dev")}, []string{"web-a"}},
+	} {
+		list, err := svr.ListProxies(client.ProxyListOptions{ProxyFilter: c.filter})
+		require.NoError(err)
+		require.Equal(c.want, names(list), "%+v", c.filter)
+	}
+
+	_, err = svr.ListProxies(client.ProxyListOptions{SortBy: "size"})
+	require.ErrorIs(err, client.ErrInvalidListOptions)
+	_, err = svr.ListProxies(client.ProxyListOptions{SortBy: client.ProxySortByTraffic, Cursor: opts.Cursor})
+	require.ErrorIs(err, client.ErrInvalidListOptions)
+}
+
+func TestServiceBatchProxies(t *testing.T) {
+	require := require.New(t)
+	s, err := frpstest.NewServer(frpstest.Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "tcp", "web", frpstest.StartTCPEcho(t))},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	s.WaitProxyAddr(t, "web")
+	// proxies created by two ci jobs
+	for _, c := range []struct{ name, job string }{{"ci-1-a", "1"}, {"ci-1-b", "1"}, {"ci-2-a", "2"}} {
+		cfg := newProxyCfg(t, "tcp", c.name, frpstest.StartTCPEcho(t))
+		cfg.GetBaseConfig().Metadatas = map[string]string{"owner": "ci", "job": c.job}
+		require.NoError(svr.AddProxy(cfg))
+		s.WaitProxyAddr(t, c.name)
+	}
+
+	job1, err := client.ParseProxySelector("owner=ci,job in (1)")
+	require.NoError(err)
+	result, err := svr.PauseProxies(client.ProxyFilter{Metadatas: job1})
+	require.NoError(err)
+	require.Equal([]string{"ci-1-a", "ci-1-b"}, result.Names)
+	require.Empty(result.Errors)
+	for _, name := range result.Names {
+		status, ok := svr.StatusExporter().GetProxyStatus(name)
+		require.True(ok)
+		require.Equal(proxy.ProxyPhasePaused, status.Phase)
+	}
+
+	result, err = svr.ResumeProxies(client.ProxyFilter{Phases: []string{proxy.ProxyPhasePaused}})
+	require.NoError(err)
+	require.Equal([]string{"ci-1-a", "ci-1-b"}, result.Names)
+	s.WaitProxyAddr(t, "ci-1-a")
+
+	ci, err := client.ParseProxySelector("owner=ci")
+	require.NoError(err)
+	result, err = svr.DeleteProxies(client.ProxyFilter{Metadatas: ci})
+	require.NoError(err)
+	require.Equal([]string{"ci-1-a", "ci-1-b", "ci-2-a"}, result.Names)
+	list, err := svr.ListProxies(client.ProxyListOptions{})
+	require.NoError(err)
+	require.Equal(1, list.Total)
+	require.Equal("web", list.Proxies[0].Name)
+
+	// never select all proxies by accident
+	_, err = svr.DeleteProxies(client.ProxyFilter{Metadatas: labels.Everything()})
+	require.ErrorIs(err, client.ErrInvalidProxyFilter)
+}
+
+func TestServiceProxyCreatedOnce(t *testing.T) {
+	require := require.New(t)
+	s, err := frpstest.NewServer(frpstest.Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	stream, _, _ := webhook.Subscribe(webhook.StreamFilter{
+		Events: []string{string(webhook.EventProxyCreated)},
+		Names:  []string{"created-*"},
+	}, 0)
+	defer webhook.Unsubscribe(stream)
+	requireCreated := func(name string) {
+		select {
+		case se := <-stream.C:
+			require.Equal(name, se.Event.ProxyName)
+		case <-time.After(5 * time.Second):
+			require.Fail("no proxy.created event", name)
+		}
+	}
+
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "tcp", "created-file", frpstest.StartTCPEcho(t))},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	requireCreated("created-file")
+	s.WaitProxyAddr(t, "created-file")
+	require.NoError(svr.AddProxy(newProxyCfg(t, "tcp", "created-api", frpstest.StartTCPEcho(t))))
+	requireCreated("created-api")
+	s.WaitProxyAddr(t, "created-api")
+
+	// registering again after reconnecting is not a creation
+	runIDs := s.RunIDs()
+	require.Len(runIDs, 1)
+	require.True(s.CloseClient(runIDs[0]))
+	require.Eventually(func() bool { return svr.Status().Reconnects == 1 }, 10*time.Second, 50*time.Millisecond)
+	s.WaitProxyAddr(t, "created-file")
+	s.WaitProxyAddr(t, "created-api")
+	select {
+	case se := <-stream.C:
+		require.Fail("unexpected proxy.created event", se.Event.ProxyName)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
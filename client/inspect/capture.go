@@ -0,0 +1,211 @@
+package inspect
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	libio "github.com/fatedier/golib/io"
+
+	"frpgo/pkg/util/util"
+)
+
+// maxPendingBytes limits the bytes buffered for parsing of one direction.
+// Once exceeded, capturing of the connection is given up, the data flow is never blocked.
+const maxPendingBytes = 4 * 1024 * 1024
+
+// WrapConn returns a ReadWriteCloser which captures http requests read from rwc
+// and http responses written to rwc. Parsing runs in background goroutines.
+func (r *Recorder) WrapConn(tunnelName string, remoteAddr string, rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	c := &connCapture{
+		recorder:   r,
+		tunnelName: tunnelName,
+		remoteAddr: remoteAddr,
+		reqStream:  newStream(maxPendingBytes),
+		respStream: newStream(maxPendingBytes),
+		pending:    make(chan *Capture, 64),
+		doneCh:     make(chan struct{}),
+	}
+	go c.readRequests()
+	go c.readResponses()
+
+	return libio.WrapReadWriteCloser(
+		io.TeeReader(rwc, c.reqStream),
+		io.MultiWriter(rwc, c.respStream),
+		func() error {
+			c.reqStream.Close()
+			c.respStream.Close()
+			return rwc.Close()
+		},
+	)
+}
+
+type connCapture struct {
+	recorder   *Recorder
+	tunnelName string
+	remoteAddr string
+
+	reqStream  *stream
+	respStream *stream
+	// requests waiting for responses, in order
+	pending chan *Capture
+	// closed when response parsing exits
+	doneCh chan struct{}
+}
+
+func (c *connCapture) readRequests() {
+	defer close(c.pending)
+	defer c.reqStream.Close()
+
+	br := bufio.NewReader(c.reqStream)
+	for {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+
+		id, _ := util.RandID()
+		capture := &Capture{
+			ID:         id,
+			TunnelName: c.tunnelName,
+			RemoteAddr: c.remoteAddr,
+			Start:      time.Now(),
+			Request: Request{
+				Method: req.Method,
+				URI:    req.RequestURI,
+				Proto:  req.Proto,
+				Host:   req.Host,
+				Header: req.Header,
+			},
+		}
+		capture.Request.Body, capture.Request.BodySize, capture.Request.Truncated, err = readBody(req.Body, c.recorder.maxBodySize)
+		if err != nil {
+			capture.Err = err.Error()
+			c.recorder.Record(capture)
+			return
+		}
+
+		select {
+		case c.pending <- capture:
+		case <-c.doneCh:
+			return
+		}
+	}
+}
+
+func (c *connCapture) readResponses() {
+	defer close(c.doneCh)
+	defer c.respStream.Close()
+	// stop buffering requests which will never be paired
+	defer c.reqStream.Close()
+
+	br := bufio.NewReader(c.respStream)
+	for capture := range c.pending {
+		req := &http.Request{Method: capture.Request.Method}
+		resp, err := http.ReadResponse(br, req)
+		// skip informational responses such as 100 Continue
+		for err == nil && resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != http.StatusSwitchingProtocols {
+			resp, err = http.ReadResponse(br, req)
+		}
+		if err != nil {
+			capture.Err = "read response error: " + err.Error()
+			capture.Duration = time.Since(capture.Start)
+			c.recorder.Record(capture)
+			return
+		}
+
+		capture.Response = &Response{
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Proto:      resp.Proto,
+			Header:     resp.Header,
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			capture.Response.Body, capture.Response.BodySize, capture.Response.Truncated, err = readBody(resp.Body, c.recorder.maxBodySize)
+			if err != nil {
+				capture.Err = "read response body error: " + err.Error()
+			}
+		}
+		capture.Duration = time.Since(capture.Start)
+		c.recorder.Record(capture)
+
+		// the connection is no longer http after protocol switching or a broken response
+		if resp.StatusCode == http.StatusSwitchingProtocols || err != nil {
+			return
+		}
+	}
+}
+
+// readBody reads at most maxSize bytes of body and discards the rest.
+func readBody(body io.ReadCloser, maxSize int) ([]byte, int64, bool, error) {
+	defer body.Close()
+
+	buf := bytes.NewBuffer(nil)
+	n, err := io.CopyN(buf, body, int64(maxSize))
+	if err == io.EOF {
+		return buf.Bytes(), n, false, nil
+	}
+	if err != nil {
+		return buf.Bytes(), n, false, err
+	}
+
+	rest, err := io.Copy(io.Discard, body)
+	return buf.Bytes(), n + rest, rest > 0, err
+}
+
+// stream is an in-memory pipe which never blocks the writer. Data is dropped and the
+// stream is closed if the reader falls behind more than maxSize bytes.
+type stream struct {
+	buf     bytes.Buffer
+	maxSize int
+	closed  bool
+	mu      sync.Mutex
+	cond    *sync.Cond
+}
+
+func newStream(maxSize int) *stream {
+	s := &stream{maxSize: maxSize}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return len(p), nil
+	}
+	if s.buf.Len()+len(p) > s.maxSize {
+		s.closed = true
+		s.buf.Reset()
+		s.cond.Broadcast()
+		return len(p), nil
+	}
+	s.buf.Write(p)
+	s.cond.Broadcast()
+	return len(p), nil
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.buf.Len() == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return s.buf.Read(p)
+}
+
+func (s *stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+}
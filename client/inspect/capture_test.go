@@ -0,0 +1,100 @@
+package inspect
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapConnCapture(t *testing.T) {
+	require := require.New(t)
+	recorder := NewRecorder(10, 8)
+
+	userConn, workConn := net.Pipe()
+	wrapped := recorder.WrapConn("web", "1.2.3.4:5678", workConn)
+
+	// local service, answers every request read from the work connection
+	go func() {
+		br := bufio.NewReader(wrapped)
+		for {
+			req, err := http.ReadRequest(br)
+			if err != nil {
+				wrapped.Close()
+				return
+			}
+			_, _ = io.Copy(io.Discard, req.Body)
+			resp := &http.Response{
+				StatusCode:    http.StatusCreated,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{"X-Path": []string{req.URL.Path}},
+				Body:          io.NopCloser(strings.NewReader("response body")),
+				ContentLength: int64(len("response body")),
+			}
+			_ = resp.Write(wrapped)
+		}
+	}()
+
+	br := bufio.NewReader(userConn)
+	for _, path := range []string{"/hook", "/other"} {
+		req, err := http.NewRequest("POST", "http://example.com"+path, strings.NewReader("request body"))
+		require.NoError(err)
+		require.NoError(req.Write(userConn))
+		resp, err := http.ReadResponse(br, req)
+		require.NoError(err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.Equal(http.StatusCreated, resp.StatusCode)
+	}
+	userConn.Close()
+
+	require.Eventually(func() bool {
+		return len(recorder.List("web", ListOptions{})) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	captures := recorder.List("web", ListOptions{})
+	// newest first
+	require.Equal("/other", captures[0].Request.URI)
+	c := captures[1]
+	require.Equal("/hook", c.Request.URI)
+	require.Equal("POST", c.Request.Method)
+	require.Equal("example.com", c.Request.Host)
+	require.Equal("1.2.3.4:5678", c.RemoteAddr)
+	require.Equal([]byte("request "), c.Request.Body)
+	require.EqualValues(len("request body"), c.Request.BodySize)
+	require.True(c.Request.Truncated)
+	require.NotNil(c.Response)
+	require.Equal(http.StatusCreated, c.Response.StatusCode)
+	require.Equal("/hook", c.Response.Header.Get("X-Path"))
+	require.Equal([]byte("response"), c.Response.Body)
+	require.Empty(c.Err)
+
+	got, ok := recorder.Get("web", c.ID)
+	require.True(ok)
+	require.Equal(c, got)
+
+	require.Len(recorder.List("web", ListOptions{Path: "/ho"}), 1)
+	require.Len(recorder.List("web", ListOptions{Method: "get"}), 0)
+	require.Len(recorder.List("web", ListOptions{Status: http.StatusCreated, Limit: 1}), 1)
+}
+
+func TestRecorderKeepLatest(t *testing.T) {
+	require := require.New(t)
+	recorder := NewRecorder(2, 0)
+	for _, id := range []string{"1", "2", "3"} {
+		recorder.Record(&Capture{ID: id, TunnelName: "web"})
+	}
+
+	captures := recorder.List("web", ListOptions{})
+	require.Len(captures, 2)
+	require.Equal("3", captures[0].ID)
+	require.Equal("2", captures[1].ID)
+
+	recorder.Clear("web")
+	require.Empty(recorder.List("web", ListOptions{}))
+}
@@ -0,0 +1,188 @@
+package inspect
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"frpgo/fmgr/webhook"
+)
+
+const (
+	defaultMaxRequests = 100
+	defaultMaxBodySize = 64 * 1024
+)
+
+type Request struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Proto  string      `json:"proto"`
+	Host   string      `json:"host"`
+	Header http.Header `json:"header"`
+	// Body is truncated to the max body size of the recorder, BodySize is the real size.
+	Body      []byte `json:"body"`
+	BodySize  int64  `json:"body_size"`
+	Truncated bool   `json:"truncated"`
+}
+
+type Response struct {
+	Status     string      `json:"status"`
+	StatusCode int         `json:"status_code"`
+	Proto      string      `json:"proto"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	BodySize   int64       `json:"body_size"`
+	Truncated  bool        `json:"truncated"`
+}
+
+// Capture is a request and response pair flowing through a http tunnel.
+type Capture struct {
+	ID         string        `json:"id"`
+	TunnelName string        `json:"tunnel_name"`
+	RemoteAddr string        `json:"remote_addr"`
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+	Request    Request       `json:"request"`
+	// Response is nil if no response is got.
+	Response *Response `json:"response"`
+	Err      string    `json:"err"`
+	// ReplayOf is the id of the original capture if it's a replay.
+	ReplayOf string `json:"replay_of,omitempty"`
+}
+
+// CaptureSummary is a capture without headers and bodies.
+type CaptureSummary struct {
+	ID         string `json:"id"`
+	TunnelName string `json:"tunnel_name"`
+	RemoteAddr string `json:"remote_addr"`
+	Start      int64  `json:"start"` // unix milliseconds
+	DurationMs int64  `json:"duration_ms"`
+	Method     string `json:"method"`
+	URI        string `json:"uri"`
+	StatusCode int    `json:"status_code"` // 0 if no response is got
+	Err        string `json:"err,omitempty"`
+	ReplayOf   string `json:"replay_of,omitempty"`
+}
+
+func (c *Capture) Summary() CaptureSummary {
+	s := CaptureSummary{
+		ID:         c.ID,
+		TunnelName: c.TunnelName,
+		RemoteAddr: c.RemoteAddr,
+		Start:      c.Start.UnixMilli(),
+		DurationMs: c.Duration.Milliseconds(),
+		Method:     c.Request.Method,
+		URI:        c.Request.URI,
+		Err:        c.Err,
+		ReplayOf:   c.ReplayOf,
+	}
+	if c.Response != nil {
+		s.StatusCode = c.Response.StatusCode
+	}
+	return s
+}
+
+type ListOptions struct {
+	// Limit is the max number of captures returned, 0 means no limit.
+	Limit int
+	// Method matches the request method, case insensitive.
+	Method string
+	// Path matches the prefix of the request uri.
+	Path string
+	// Status matches the response status code.
+	Status int
+}
+
+func (o *ListOptions) match(c *Capture) bool {
+	if o.Method != "" && !strings.EqualFold(o.Method, c.Request.Method) {
+		return false
+	}
+	if o.Path != "" && !strings.HasPrefix(c.Request.URI, o.Path) {
+		return false
+	}
+	if o.Status != 0 && (c.Response == nil || c.Response.StatusCode != o.Status) {
+		return false
+	}
+	return true
+}
+
+// Recorder keeps the latest captures of each tunnel in memory.
+type Recorder struct {
+	maxRequests int
+	maxBodySize int
+
+	// captures of each tunnel, from oldest to newest
+	tunnels map[string][]*Capture
+	mu      sync.RWMutex
+}
+
+// NewRecorder creates a Recorder which keeps at most maxRequests captures for each tunnel,
+// and at most maxBodySize bytes for each body. Zero values mean the default.
+func NewRecorder(maxRequests int, maxBodySize int) *Recorder {
+	if maxRequests <= 0 {
+		maxRequests = defaultMaxRequests
+	}
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+	return &Recorder{
+		maxRequests: maxRequests,
+		maxBodySize: maxBodySize,
+		tunnels:     make(map[string][]*Capture),
+	}
+}
+
+func (r *Recorder) Record(c *Capture) {
+	r.mu.Lock()
+	captures := append(r.tunnels[c.TunnelName], c)
+	if len(captures) > r.maxRequests {
+		captures = slices.Delete(captures, 0, len(captures)-r.maxRequests)
+	}
+	r.tunnels[c.TunnelName] = captures
+	r.mu.Unlock()
+
+	e := webhook.NewEvent(webhook.EventRequestCaptured, "")
+	e.ProxyName = c.TunnelName
+	e.ProxyType = "http"
+	e.Detail = c.Summary()
+	webhook.Push(e)
+}
+
+// List returns captures of the tunnel matched by opts, newest first.
+func (r *Recorder) List(tunnelName string, opts ListOptions) []*Capture {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	captures := r.tunnels[tunnelName]
+	out := make([]*Capture, 0)
+	for i := len(captures) - 1; i >= 0; i-- {
+		if opts.Limit > 0 && len(out) >= opts.Limit {
+			break
+		}
+		if opts.match(captures[i]) {
+			out = append(out, captures[i])
+		}
+	}
+	return out
+}
+
+func (r *Recorder) Get(tunnelName string, id string) (*Capture, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.tunnels[tunnelName] {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Clear removes all captures of the tunnel.
+func (r *Recorder) Clear(tunnelName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tunnels, tunnelName)
+}
@@ -0,0 +1,173 @@
+package inspect
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"frpgo/pkg/util/util"
+)
+
+var (
+	ErrCaptureNotFound = errors.New("capture not found")
+	ErrBodyTruncated   = errors.New("captured body is truncated, replay it with a new body")
+	ErrReplay          = errors.New("replay error")
+	// the request URI is neither in origin form nor in absolute form
+	ErrInvalidRequestURI = errors.New("invalid request uri")
+)
+
+// ReplayOptions are the edits applied to the captured request, zero values keep the original.
+type ReplayOptions struct {
+	Method string
+	// URI replaces the request uri, including the query.
+	URI    string
+	Header map[string]string
+	Body   *[]byte
+}
+
+// Replay sends the captured request again through the connection returned by dial,
+// and records the result as a new capture linked to the original one.
+func (r *Recorder) Replay(
+	ctx context.Context,
+	tunnelName string,
+	id string,
+	opts ReplayOptions,
+	dial func() (net.Conn, error),
+) (*Capture, error) {
+	orig, ok := r.Get(tunnelName, id)
+	if !ok {
+		return nil, ErrCaptureNotFound
+	}
+
+	body := orig.Request.Body
+	if opts.Body != nil {
+		body = *opts.Body
+	} else if orig.Request.Truncated {
+		return nil, ErrBodyTruncated
+	}
+
+	method := orig.Request.Method
+	if opts.Method != "" {
+		method = opts.Method
+	}
+	uri := orig.Request.URI
+	if opts.URI != "" {
+		uri = opts.URI
+	}
+
+	// the request URI is in absolute form if the request is sent to a proxy
+	target, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w [%s]: %v", ErrInvalidRequestURI, uri, err)
+	}
+	host := orig.Request.Host
+	if !target.IsAbs() {
+		target, err = url.Parse("http://" + host + uri)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReplay, err)
+		}
+	} else if host == "" {
+		host = target.Host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReplay, err)
+	}
+	req.Host = host
+	req.Header = orig.Request.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	// recomputed from the body
+	req.Header.Del("Content-Length")
+	req.Header.Del("Transfer-Encoding")
+	for k, v := range opts.Header {
+		req.Header.Set(k, v)
+	}
+	req.Close = true
+
+	newID, _ := util.RandID()
+	capture := &Capture{
+		ID:         newID,
+		TunnelName: tunnelName,
+		Start:      time.Now(),
+		ReplayOf:   orig.ID,
+		Request: Request{
+			Method:   method,
+			URI:      uri,
+			Proto:    req.Proto,
+			Host:     req.Host,
+			Header:   req.Header,
+			BodySize: int64(len(body)),
+		},
+	}
+	capture.Request.Body = body
+	if len(body) > r.maxBodySize {
+		capture.Request.Body = body[:r.maxBodySize]
+		capture.Request.Truncated = true
+	}
+
+	if err := r.roundTrip(ctx, req, capture, dial); err != nil {
+		capture.Err = err.Error()
+		capture.Duration = time.Since(capture.Start)
+		r.Record(capture)
+		return capture, fmt.Errorf("%w: %v", ErrReplay, err)
+	}
+	capture.Duration = time.Since(capture.Start)
+	r.Record(capture)
+	return capture, nil
+}
+
+func (r *Recorder) roundTrip(ctx context.Context, req *http.Request, capture *Capture, dial func() (net.Conn, error)) error {
+	conn, err := dial()
+	if err != nil {
+		return fmt.Errorf("dial local service error: %v", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// write in background, the local service may respond before reading the whole body
+	writeErrCh := make(chan error, 1)
+	go func() {
+		writeErrCh <- req.Write(conn)
+	}()
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	for err == nil && resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != http.StatusSwitchingProtocols {
+		resp, err = http.ReadResponse(br, req)
+	}
+	if err != nil {
+		if werr := <-writeErrCh; werr != nil {
+			return fmt.Errorf("write request error: %v", werr)
+		}
+		return fmt.Errorf("read response error: %v", err)
+	}
+
+	capture.Response = &Response{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Proto:      resp.Proto,
+		Header:     resp.Header,
+	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return nil
+	}
+	capture.Response.Body, capture.Response.BodySize, capture.Response.Truncated, err = readBody(resp.Body, r.maxBodySize)
+	if err != nil {
+		return fmt.Errorf("read response body error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package inspect
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	require := require.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	defer ln.Close()
+	go func() {
+		_ = http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Method", r.Method)
+			w.Header().Set("X-Token", r.Header.Get("X-Token"))
+			w.Header().Set("X-Host", r.Host)
+			_, _ = w.Write([]byte(r.URL.RequestURI() + " " + string(body)))
+		}))
+	}()
+	dial := func() (net.Conn, error) {
+		return net.Dial("tcp", ln.Addr().String())
+	}
+
+	recorder := NewRecorder(10, 1024)
+	recorder.Record(&Capture{
+		ID:         "orig",
+		TunnelName: "web",
+		Request: Request{
+			Method: "POST",
+			URI:    "/hook?a=1",
+			Host:   "example.com",
+			Header: http.Header{"X-Token": []string{"old"}, "Content-Length": []string{"5"}},
+			Body:   []byte("hello"),
+		},
+	})
+
+	c, err := recorder.Replay(context.Background(), "web", "orig", ReplayOptions{}, dial)
+	require.NoError(err)
+	require.Equal("orig", c.ReplayOf)
+	require.NotEqual("orig", c.ID)
+	require.Equal(http.StatusOK, c.Response.StatusCode)
+	require.Equal("POST", c.Response.Header.Get("X-Method"))
+	require.Equal("old", c.Response.Header.Get("X-Token"))
+	require.Equal("example.com", c.Response.Header.Get("X-Host"))
+	require.Equal("/hook?a=1 hello", string(c.Response.Body))
+
+	body := []byte("edited body")
+	c, err = recorder.Replay(context.Background(), "web", "orig", ReplayOptions{
+		Method: "PUT",
+		URI:    "/other",
+		Header: map[string]string{"X-Token": "new"},
+		Body:   &body,
+	}, dial)
+	require.NoError(err)
+	require.Equal("PUT", c.Response.Header.Get("X-Method"))
+	require.Equal("new", c.Response.Header.Get("X-Token"))
+	require.Equal("/other edited body", string(c.Response.Body))
+
+	// replays are recorded as new captures
+	require.Len(recorder.List("web", ListOptions{}), 3)
+
+	// request uri in absolute form
+	recorder.Record(&Capture{
+		ID:         "abs",
+		TunnelName: "web",
+		Request: Request{
+			Method: "GET",
+			URI:    "http://example.com/abs?b=2",
+			Host:   "example.com",
+		},
+	})
+	c, err = recorder.Replay(context.Background(), "web", "abs", ReplayOptions{}, dial)
+	require.NoError(err)
+	require.Equal(http.StatusOK, c.Response.StatusCode)
+	require.Equal("example.com", c.Response.Header.Get("X-Host"))
+	require.Equal("/abs?b=2 ", string(c.Response.Body))
+
+	_, err = recorder.Replay(context.Background(), "web", "abs", ReplayOptions{URI: "no-slash"}, dial)
+	require.ErrorIs(err, ErrInvalidRequestURI)
+
+	_, err = recorder.Replay(context.Background(), "web", "unknown", ReplayOptions{}, dial)
+	require.ErrorIs(err, ErrCaptureNotFound)
+
+	recorder.Record(&Capture{ID: "big", TunnelName: "web", Request: Request{Method: "POST", URI: "/", Truncated: true}})
+	_, err = recorder.Replay(context.Background(), "web", "big", ReplayOptions{}, dial)
+	require.ErrorIs(err, ErrBodyTruncated)
+}
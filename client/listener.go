@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	libio "github.com/fatedier/golib/io"
+	pp "github.com/pires/go-proxyproto"
+	"github.com/samber/lo"
+
+	"frpgo/client/proxy"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/msg"
+	plugin "frpgo/pkg/plugin/client"
+	netpkg "frpgo/pkg/util/net"
+	"frpgo/pkg/util/xlog"
+)
+
+var (
+	ErrListenerExist = errors.New("listener of the proxy is already exist")
+	// ErrListenerNotSupported is returned by Listen for udp, sudp and xtcp proxies.
+	ErrListenerNotSupported = errors.New("listener is not supported by the proxy type")
+)
+
+// Listen returns a listener which accepts the work connections of the proxy, instead of
+// dialing to the local service. The proxy itself is not created, it should be in the
+// config file or be added by AddProxy. Only tcp work connections (tcp, http, https,
+// tcpmux and stcp proxies) are dispatched to the listener, it returns
+// ErrListenerNotSupported if the proxy is configured with another type.
+// Listeners are closed when the service is stopped.
+func (svr *Service) Listen(name string) (*ProxyListener, error) {
+	svr.cfgMu.RLock()
+	cfg, ok := lo.Find(svr.allProxyCfgs(), func(c v1.ProxyConfigurer) bool {
+		return c.GetBaseConfig().Name == name
+	})
+	svr.cfgMu.RUnlock()
+	if ok {
+		switch typ := v1.ProxyType(cfg.GetBaseConfig().Type); typ {
+		case v1.ProxyTypeUDP, v1.ProxyTypeSUDP, v1.ProxyTypeXTCP:
+			return nil, fmt.Errorf("%w: proxy [%s] is %s", ErrListenerNotSupported, name, typ)
+		}
+	}
+
+	svr.listenerMu.Lock()
+	defer svr.listenerMu.Unlock()
+	if _, ok := svr.listeners[name]; ok {
+		return nil, ErrListenerExist
+	}
+	l := &ProxyListener{
+		InternalListener: netpkg.NewInternalListener(),
+		name:             name,
+	}
+	l.closeFn = func() {
+		svr.listenerMu.Lock()
+		defer svr.listenerMu.Unlock()
+		if svr.listeners[name] == l {
+			delete(svr.listeners, name)
+		}
+	}
+	if svr.listeners == nil {
+		svr.listeners = make(map[string]*ProxyListener)
+	}
+	svr.listeners[name] = l
+	return l, nil
+}
+
+func (svr *Service) getListener(name string) *ProxyListener {
+	svr.listenerMu.Lock()
+	defer svr.listenerMu.Unlock()
+	return svr.listeners[name]
+}
+
+func (svr *Service) closeListeners() {
+	svr.listenerMu.Lock()
+	listeners := lo.Values(svr.listeners)
+	svr.listenerMu.Unlock()
+	// Close removes the listener with listenerMu
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// inWorkConnCallback returns the work connection callback of a control logged in with
+// common. Work connections of proxies with a listener are dispatched to it, others are
+// passed to handleWorkConnCb. The work connections are already counted in stats and
+// limited by the bandwidth limit of the proxy.
+func (svr *Service) inWorkConnCallback(common *v1.ClientCommonConfig) func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) bool {
+	encKey := []byte(common.Auth.Token)
+	return func(baseCfg *v1.ProxyBaseConfig, workConn net.Conn, m *msg.StartWorkConn) bool {
+		if l := svr.getListener(baseCfg.Name); l != nil {
+			l.put(baseCfg, workConn, m, encKey)
+			return false
+		}
+		if svr.handleWorkConnCb != nil {
+			return svr.handleWorkConnCb(baseCfg, workConn, m)
+		}
+		return true
+	}
+}
+
+// ProxyListener is a net.Listener of the work connections of a proxy. The accepted
+// connections are *ProxyConn.
+type ProxyListener struct {
+	*netpkg.InternalListener
+
+	name      string
+	closeFn   func()
+	closeOnce sync.Once
+}
+
+// Name returns the name of the proxy.
+func (l *ProxyListener) Name() string {
+	return l.name
+}
+
+// Close stops dispatching work connections to the listener. Work connections of the
+// proxy are handled by the local service again.
+func (l *ProxyListener) Close() error {
+	l.closeOnce.Do(l.closeFn)
+	return l.InternalListener.Close()
+}
+
+func (l *ProxyListener) put(baseCfg *v1.ProxyBaseConfig, workConn net.Conn, m *msg.StartWorkConn, encKey []byte) {
+	xl := xlog.FromContextSafe(netpkg.NewContextFromConn(workConn))
+
+	var rwc io.ReadWriteCloser = workConn
+	if baseCfg.Transport.UseEncryption {
+		var err error
+		rwc, err = libio.WithEncryption(rwc, encKey)
+		if err != nil {
+			workConn.Close()
+			xl.Errorf("create encryption stream error: %v", err)
+			return
+		}
+	}
+	if baseCfg.Transport.UseCompression {
+		rwc = libio.WithCompression(rwc)
+	}
+
+	conn := &ProxyConn{
+		Conn:      workConn,
+		rwc:       rwc,
+		extraInfo: proxy.NewExtraInfo(baseCfg, m),
+	}
+	if err := l.PutConn(conn); err != nil {
+		workConn.Close()
+		xl.Warnf("dispatch work connection to listener of proxy [%s] error: %v", l.name, err)
+	}
+}
+
+// Serve serves http requests of the proxy with h until the listener is closed.
+// The ProxyConn of a request can be got by ProxyConnFromContext.
+func (l *ProxyListener) Serve(h http.Handler) error {
+	server := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 60 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			if pc, ok := c.(*ProxyConn); ok {
+				ctx = context.WithValue(ctx, proxyConnCtxKey{}, pc)
+			}
+			return ctx
+		},
+	}
+	return server.Serve(l)
+}
+
+type proxyConnCtxKey struct{}
+
+// ProxyConnFromContext returns the ProxyConn of the http request served by ProxyListener.Serve.
+func ProxyConnFromContext(ctx context.Context) (*ProxyConn, bool) {
+	pc, ok := ctx.Value(proxyConnCtxKey{}).(*ProxyConn)
+	return pc, ok
+}
+
+// ProxyConn is a decrypted and decompressed work connection. RemoteAddr and LocalAddr
+// return the addresses of the user connection if they are known.
+type ProxyConn struct {
+	net.Conn
+
+	rwc       io.ReadWriteCloser
+	extraInfo plugin.ExtraInfo
+}
+
+func (c *ProxyConn) Read(p []byte) (int, error) {
+	return c.rwc.Read(p)
+}
+
+func (c *ProxyConn) Write(p []byte) (int, error) {
+	return c.rwc.Write(p)
+}
+
+func (c *ProxyConn) Close() error {
+	return c.rwc.Close()
+}
+
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	if addr, ok := c.extraInfo.SrcAddr.(*net.TCPAddr); ok && addr != nil {
+		return addr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *ProxyConn) LocalAddr() net.Addr {
+	if addr, ok := c.extraInfo.DstAddr.(*net.TCPAddr); ok && addr != nil {
+		return addr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// ProxyProtocolHeader returns the proxy protocol header of the user connection, it's nil
+// if transport.proxyProtocolVersion of the proxy is not set or the source is unknown.
+func (c *ProxyConn) ProxyProtocolHeader() *pp.Header {
+	return c.extraInfo.ProxyProtocolHeader
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	libio "github.com/fatedier/golib/io"
+	"github.com/stretchr/testify/require"
+
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/msg"
+)
+
+func TestProxyListenerServe(t *testing.T) {
+	require := require.New(t)
+	svr, err := NewService(ServiceOptions{Common: &v1.ClientCommonConfig{}})
+	require.NoError(err)
+
+	l, err := svr.Listen("web")
+	require.NoError(err)
+	_, err = svr.Listen("web")
+	require.ErrorIs(err, ErrListenerExist)
+
+	go func() {
+		_ = l.Serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pc, ok := ProxyConnFromContext(r.Context())
+			if !ok {
+				http.Error(w, "no proxy conn", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintf(w, "%s %s", r.RemoteAddr, pc.LocalAddr())
+		}))
+	}()
+
+	cb := svr.inWorkConnCallback(&v1.ClientCommonConfig{Auth: v1.AuthClientConfig{Token: "token"}})
+	baseCfg := &v1.ProxyBaseConfig{Name: "web"}
+	baseCfg.Transport.UseEncryption = true
+	baseCfg.Transport.UseCompression = true
+
+	// frps side of the work connection
+	workConn, serverConn := net.Pipe()
+	require.False(cb(baseCfg, workConn, &msg.StartWorkConn{
+		ProxyName: "web",
+		SrcAddr:   "1.2.3.4",
+		SrcPort:   5678,
+		DstAddr:   "10.0.0.1",
+		DstPort:   80,
+	}))
+	var remote io.ReadWriteCloser
+	remote, err = libio.WithEncryption(serverConn, []byte("token"))
+	require.NoError(err)
+	remote = libio.WithCompression(remote)
+	defer remote.Close()
+
+	_, err = io.WriteString(remote, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	require.NoError(err)
+	resp, err := http.ReadResponse(bufio.NewReader(remote), nil)
+	require.NoError(err)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, resp.ContentLength))
+	require.NoError(err)
+	require.Equal("1.2.3.4:5678 10.0.0.1:80", string(body))
+
+	// work connections of proxies without a listener are handled as usual
+	require.True(cb(&v1.ProxyBaseConfig{Name: "other"}, nil, &msg.StartWorkConn{}))
+
+	require.NoError(l.Close())
+	require.True(cb(baseCfg, nil, &msg.StartWorkConn{}))
+	l, err = svr.Listen("web")
+	require.NoError(err)
+
+	// listeners are closed with the service
+	svr.stop()
+	_, err = l.Accept()
+	require.Error(err)
+	require.Nil(svr.getListener("web"))
+}
+
+func TestListenNotSupported(t *testing.T) {
+	require := require.New(t)
+	udpCfg := &v1.UDPProxyConfig{}
+	udpCfg.Name, udpCfg.Type = "dns", string(v1.ProxyTypeUDP)
+	tcpCfg := &v1.TCPProxyConfig{}
+	tcpCfg.Name, tcpCfg.Type = "ssh", string(v1.ProxyTypeTCP)
+	svr, err := NewService(ServiceOptions{
+		Common:    &v1.ClientCommonConfig{},
+		ProxyCfgs: []v1.ProxyConfigurer{udpCfg, tcpCfg},
+	})
+	require.NoError(err)
+
+	_, err = svr.Listen("dns")
+	require.ErrorIs(err, ErrListenerNotSupported)
+	l, err := svr.Listen("ssh")
+	require.NoError(err)
+	l.Close()
+}
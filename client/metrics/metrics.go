@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"time"
+)
+
+// outcomes of webhook deliveries
+const (
+	WebhookDelivered  = "delivered"
+	WebhookFailed     = "failed"
+	WebhookDeadLetter = "dead_letter"
+	WebhookDropped    = "dropped"
+)
+
+// Client is the metrics collector of the client agent, it does nothing until
+// a real implementation is registered.
+var Client ClientMetrics = noopClientMetrics{}
+
+func Register(m ClientMetrics) {
+	Client = m
+}
+
+type ClientMetrics interface {
+	// control connection
+	ControlConnected(connected bool)
+	Login(err error)
+	HeartbeatRTT(rtt time.Duration)
+	// WorkConnLatency observes the time of creating a work connection to the server.
+	WorkConnLatency(d time.Duration)
+
+	// proxy
+	ProxyPhase(name string, proxyType string, phase string)
+	OpenConnection(name string, proxyType string)
+	CloseConnection(name string, proxyType string)
+	// AddTraffic counts bytes transferred on work connections, it's called as they're read and written.
+	AddTraffic(name string, proxyType string, trafficIn int64, trafficOut int64)
+	DialLocalFailed(name string, proxyType string)
+	// RemoveProxy drops all metrics of the proxy after it's deleted.
+	RemoveProxy(name string)
+
+	// visitor
+	VisitorStarted(name string, visitorType string)
+	VisitorStartFailed(name string, visitorType string)
+	VisitorStopped(name string, visitorType string)
+	VisitorConnection(name string, visitorType string)
+
+	// webhook, failed is counted for each failed attempt
+	WebhookDelivery(subscriber string, outcome string)
+}
+
+type noopClientMetrics struct{}
+
+func (noopClientMetrics) ControlConnected(bool)                   {}
+func (noopClientMetrics) Login(error)                             {}
+func (noopClientMetrics) HeartbeatRTT(time.Duration)              {}
+func (noopClientMetrics) WorkConnLatency(time.Duration)           {}
+func (noopClientMetrics) ProxyPhase(string, string, string)       {}
+func (noopClientMetrics) OpenConnection(string, string)           {}
+func (noopClientMetrics) CloseConnection(string, string)          {}
+func (noopClientMetrics) AddTraffic(string, string, int64, int64) {}
+func (noopClientMetrics) DialLocalFailed(string, string)          {}
+func (noopClientMetrics) RemoveProxy(string)                      {}
+func (noopClientMetrics) VisitorStarted(string, string)           {}
+func (noopClientMetrics) VisitorStartFailed(string, string)       {}
+func (noopClientMetrics) VisitorStopped(string, string)           {}
+func (noopClientMetrics) VisitorConnection(string, string)        {}
+func (noopClientMetrics) WebhookDelivery(string, string)          {}
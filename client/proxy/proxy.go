@@ -16,26 +16,35 @@ package proxy
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	libio "github.com/fatedier/golib/io"
 	libnet "github.com/fatedier/golib/net"
 	pp "github.com/pires/go-proxyproto"
 	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 
+	"frpgo/client/inspect"
+	"frpgo/client/metrics"
+	"frpgo/client/tracing"
 	"frpgo/pkg/config/types"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	plugin "frpgo/pkg/plugin/client"
 	"frpgo/pkg/transport"
 	"frpgo/pkg/util/limit"
+	netpkg "frpgo/pkg/util/net"
 	"frpgo/pkg/util/xlog"
 )
 
@@ -51,6 +60,12 @@ type Proxy interface {
 	// InWorkConn accept work connections registered to server.
 	InWorkConn(net.Conn, *msg.StartWorkConn)
 	SetInWorkConnCallback(func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) /* continue */ bool)
+	// SetInspector enables capturing of http requests and responses.
+	SetInspector(*inspect.Recorder)
+	// SetStats sets the statistics collector of work connections.
+	SetStats(*Stats)
+	// DialLocal returns a connection to the local service, served by the plugin if it's set.
+	DialLocal() (net.Conn, error)
 	Close()
 }
 
@@ -91,6 +106,9 @@ type BaseProxy struct {
 	// It's only validate for TCP protocol now.
 	proxyPlugin        plugin.Plugin
 	inWorkConnCallback func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) /* continue */ bool
+	// inspector captures http requests and responses of work connections if it's not nil.
+	inspector *inspect.Recorder
+	stats     *Stats
 
 	mu  sync.RWMutex
 	xl  *xlog.Logger
@@ -120,48 +138,126 @@ func (pxy *BaseProxy) SetInWorkConnCallback(cb func(*v1.ProxyBaseConfig, net.Con
 	pxy.inWorkConnCallback = cb
 }
 
-func (pxy *BaseProxy) InWorkConn(conn net.Conn, m *msg.StartWorkConn) {
-	if pxy.inWorkConnCallback != nil {
-		if !pxy.inWorkConnCallback(pxy.baseCfg, conn, m) {
-			return
-		}
-	}
-	pxy.HandleTCPWorkConnection(conn, m, []byte(pxy.clientCfg.Auth.Token))
+func (pxy *BaseProxy) SetInspector(r *inspect.Recorder) {
+	pxy.inspector = r
 }
 
-// Common handler for tcp work connections.
-func (pxy *BaseProxy) HandleTCPWorkConnection(workConn net.Conn, m *msg.StartWorkConn, encKey []byte) {
-	logx.Debugf("HandleTCPWorkConnection")
+func (pxy *BaseProxy) SetStats(s *Stats) {
+	pxy.stats = s
+}
 
-	xl := pxy.xl
-	baseCfg := pxy.baseCfg
-	var (
-		remote io.ReadWriteCloser
-		err    error
+func (pxy *BaseProxy) DialLocal() (net.Conn, error) {
+	if pxy.proxyPlugin != nil {
+		conn, pluginConn := net.Pipe()
+		go pxy.proxyPlugin.Handle(pxy.ctx, pluginConn, pluginConn, &plugin.ExtraInfo{})
+		return conn, nil
+	}
+	if pxy.baseCfg.Plugin.Type != "" {
+		return nil, fmt.Errorf("plugin [%s] is not running", pxy.baseCfg.Plugin.Type)
+	}
+	return pxy.dialLocalService()
+}
+
+func (pxy *BaseProxy) dialLocalService() (net.Conn, error) {
+	return libnet.Dial(
+		net.JoinHostPort(pxy.baseCfg.LocalIP, strconv.Itoa(pxy.baseCfg.LocalPort)),
+		libnet.WithTimeout(10*time.Second),
 	)
-	remote = workConn
+}
+
+func (pxy *BaseProxy) InWorkConn(conn net.Conn, m *msg.StartWorkConn) {
+	if pxy.inWorkConnCallback == nil {
+		pxy.HandleTCPWorkConnection(conn, m, []byte(pxy.clientCfg.Auth.Token))
+		return
+	}
+	// work connections taken by the callback, e.g. dispatched to a listener,
+	// are counted and limited as well
+	conn = pxy.trackWorkConn(conn)
+	if !pxy.inWorkConnCallback(pxy.baseCfg, conn, m) {
+		return
+	}
+	pxy.handleTCPWorkConnection(conn, m, []byte(pxy.clientCfg.Auth.Token))
+}
+
+// trackWorkConn counts the work connection and its traffic in stats and metrics,
+// and limits its bandwidth if the limit mode of the proxy is client.
+func (pxy *BaseProxy) trackWorkConn(workConn net.Conn) net.Conn {
+	name, proxyType := pxy.baseCfg.Name, pxy.baseCfg.Type
+	metrics.Client.OpenConnection(name, proxyType)
+	c := &trackedConn{
+		Conn:      workConn,
+		reader:    workConn,
+		writer:    workConn,
+		name:      name,
+		proxyType: proxyType,
+		stats:     pxy.stats,
+	}
 	if pxy.limiter != nil {
-		remote = libio.WrapReadWriteCloser(limit.NewReader(workConn, pxy.limiter), limit.NewWriter(workConn, pxy.limiter), func() error {
-			return workConn.Close()
-		})
+		c.reader = limit.NewReader(workConn, pxy.limiter)
+		c.writer = limit.NewWriter(workConn, pxy.limiter)
+	}
+	if pxy.stats != nil {
+		c.closeStats = pxy.stats.OpenConn()
 	}
+	return c
+}
 
-	xl.Tracef("handle tcp work connection, useEncryption: %t, useCompression: %t",
-		baseCfg.Transport.UseEncryption, baseCfg.Transport.UseCompression)
-	if baseCfg.Transport.UseEncryption {
-		remote, err = libio.WithEncryption(remote, encKey)
-		if err != nil {
-			workConn.Close()
-			xl.Errorf("create encryption stream error: %v", err)
-			return
-		}
+// trackedConn counts traffic as it's read and written, so traffic of long-lived connections
+// like ssh or websocket shows up before they're closed.
+type trackedConn struct {
+	net.Conn
+	reader     io.Reader
+	writer     io.Writer
+	name       string
+	proxyType  string
+	stats      *Stats
+	closeStats func()
+	closed     atomic.Bool
+}
+
+// Context returns the context of the underlying connection, see netpkg.NewContextFromConn.
+func (c *trackedConn) Context() context.Context {
+	return netpkg.NewContextFromConn(c.Conn)
+}
+
+func (c *trackedConn) Read(p []byte) (n int, err error) {
+	n, err = c.reader.Read(p)
+	if n > 0 {
+		c.addTraffic(int64(n), 0)
 	}
-	var compressionResourceRecycleFn func()
-	if baseCfg.Transport.UseCompression {
-		remote, compressionResourceRecycleFn = libio.WithCompressionFromPool(remote)
+	return
+}
+
+func (c *trackedConn) Write(p []byte) (n int, err error) {
+	n, err = c.writer.Write(p)
+	if n > 0 {
+		c.addTraffic(0, int64(n))
 	}
+	return
+}
 
-	// check if we need to send proxy protocol info
+func (c *trackedConn) addTraffic(in int64, out int64) {
+	if c.stats != nil {
+		c.stats.AddTraffic(in, out)
+	}
+	metrics.Client.AddTraffic(c.name, c.proxyType, in, out)
+}
+
+func (c *trackedConn) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	err := c.Conn.Close()
+	if c.closeStats != nil {
+		c.closeStats()
+	}
+	metrics.Client.CloseConnection(c.name, c.proxyType)
+	return err
+}
+
+// NewExtraInfo returns the source and destination address of the user connection in
+// StartWorkConn, with the proxy protocol header if it's enabled for the proxy.
+func NewExtraInfo(baseCfg *v1.ProxyBaseConfig, m *msg.StartWorkConn) plugin.ExtraInfo {
 	var extraInfo plugin.ExtraInfo
 	if m.SrcAddr != "" && m.SrcPort != 0 {
 		if m.DstAddr == "" {
@@ -193,6 +289,56 @@ func (pxy *BaseProxy) HandleTCPWorkConnection(workConn net.Conn, m *msg.StartWor
 		}
 		extraInfo.ProxyProtocolHeader = h
 	}
+	return extraInfo
+}
+
+// Common handler for tcp work connections.
+func (pxy *BaseProxy) HandleTCPWorkConnection(workConn net.Conn, m *msg.StartWorkConn, encKey []byte) {
+	logx.Debugf("HandleTCPWorkConnection")
+	pxy.handleTCPWorkConnection(pxy.trackWorkConn(workConn), m, encKey)
+}
+
+// handleTCPWorkConnection handles a work connection returned by trackWorkConn.
+func (pxy *BaseProxy) handleTCPWorkConnection(workConn net.Conn, m *msg.StartWorkConn, encKey []byte) {
+	xl := pxy.xl
+	baseCfg := pxy.baseCfg
+	var (
+		remote io.ReadWriteCloser = workConn
+		err    error
+	)
+	// the work connection carries its span if it's sampled
+	ctx, span := tracing.StartChild(netpkg.NewContextFromConn(workConn), "proxy.handle_work_conn", trace.WithAttributes(
+		attribute.String("proxy.name", baseCfg.Name),
+		attribute.String("proxy.type", baseCfg.Type),
+	))
+	defer span.End()
+	xl = tracing.LogPrefix(xl, span)
+
+	xl.Tracef("handle tcp work connection, useEncryption: %t, useCompression: %t",
+		baseCfg.Transport.UseEncryption, baseCfg.Transport.UseCompression)
+	if baseCfg.Transport.UseEncryption {
+		remote, err = libio.WithEncryption(remote, encKey)
+		if err != nil {
+			workConn.Close()
+			xl.Errorf("create encryption stream error: %v", err)
+			return
+		}
+	}
+	var compressionResourceRecycleFn func()
+	if baseCfg.Transport.UseCompression {
+		remote, compressionResourceRecycleFn = libio.WithCompressionFromPool(remote)
+	}
+
+	if pxy.inspector != nil {
+		srcAddr := ""
+		if m.SrcAddr != "" {
+			srcAddr = net.JoinHostPort(m.SrcAddr, strconv.Itoa(int(m.SrcPort)))
+		}
+		remote = pxy.inspector.WrapConn(baseCfg.Name, srcAddr, remote)
+	}
+
+	// check if we need to send proxy protocol info
+	extraInfo := NewExtraInfo(baseCfg, m)
 
 	if pxy.proxyPlugin != nil {
 		// if plugin is set, let plugin handle connection first
@@ -202,11 +348,17 @@ func (pxy *BaseProxy) HandleTCPWorkConnection(workConn net.Conn, m *msg.StartWor
 		return
 	}
 
-	localConn, err := libnet.Dial(
-		net.JoinHostPort(baseCfg.LocalIP, strconv.Itoa(baseCfg.LocalPort)),
-		libnet.WithTimeout(10*time.Second),
-	)
+	_, dialSpan := tracing.StartChild(ctx, "proxy.dial_local", trace.WithAttributes(
+		attribute.String("local.addr", net.JoinHostPort(baseCfg.LocalIP, strconv.Itoa(baseCfg.LocalPort))),
+	))
+	localConn, err := pxy.dialLocalService()
+	tracing.End(dialSpan, err)
 	if err != nil {
+		span.SetStatus(codes.Error, "dial local service error")
+		if pxy.stats != nil {
+			pxy.stats.DialFailed()
+		}
+		metrics.Client.DialLocalFailed(baseCfg.Name, baseCfg.Type)
 		workConn.Close()
 		xl.Errorf("connect to local service [%s:%d] error: %v", baseCfg.LocalIP, baseCfg.LocalPort, err)
 		return
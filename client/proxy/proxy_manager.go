@@ -16,6 +16,7 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
@@ -25,6 +26,7 @@ import (
 	"github.com/zeromicro/go-zero/core/logx"
 
 	"frpgo/client/event"
+	"frpgo/client/inspect"
 	"frpgo/fmgr/webhook"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
@@ -33,21 +35,32 @@ import (
 	"frpgo/pkg2/utils2"
 )
 
+var (
+	ErrProxyExist    = errors.New("proxy is already exist")
+	ErrProxyNotFound = errors.New("proxy not found")
+)
+
 type Manager struct {
 	proxies            map[string]*Wrapper
 	msgTransporter     transport.MessageTransporter
 	inWorkConnCallback func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) bool
+	inspector          *inspect.Recorder
+	statsRegistry      *StatsRegistry
+	// reports if a proxy should be kept paused when it's created
+	isPaused func(name string) bool
 
 	closed bool
 	mu     sync.RWMutex
 
 	clientCfg *v1.ClientCommonConfig
+	runID     string
 
 	ctx context.Context
 }
 
 func NewManager(
 	ctx context.Context,
+	runID string,
 	clientCfg *v1.ClientCommonConfig,
 	msgTransporter transport.MessageTransporter,
 ) *Manager {
@@ -56,6 +69,7 @@ func NewManager(
 		msgTransporter: msgTransporter,
 		closed:         false,
 		clientCfg:      clientCfg,
+		runID:          runID,
 		ctx:            ctx,
 	}
 }
@@ -81,6 +95,20 @@ func (pm *Manager) SetInWorkConnCallback(cb func(*v1.ProxyBaseConfig, net.Conn,
 	pm.inWorkConnCallback = cb
 }
 
+func (pm *Manager) SetInspector(r *inspect.Recorder) {
+	pm.inspector = r
+}
+
+func (pm *Manager) SetStatsRegistry(r *StatsRegistry) {
+	pm.statsRegistry = r
+}
+
+// SetPausedFunc sets the function checked when proxies are created, proxies it reports
+// are created in the paused phase. It lets paused proxies stay paused after reconnecting.
+func (pm *Manager) SetPausedFunc(fn func(name string) bool) {
+	pm.isPaused = fn
+}
+
 func (pm *Manager) Close() {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
@@ -169,10 +197,7 @@ func (pm *Manager) UpdateAll(proxyCfgs []v1.ProxyConfigurer) {
 		logx.Debugf("Name: %v", name)
 
 		if _, ok := pm.proxies[name]; !ok {
-			pxy := NewWrapper(pm.ctx, cfg, pm.clientCfg, pm.HandleEvent, pm.msgTransporter)
-			if pm.inWorkConnCallback != nil {
-				pxy.SetInWorkConnCallback(pm.inWorkConnCallback)
-			}
+			pxy := pm.newWrapper(cfg)
 			pm.proxies[name] = pxy
 			addPxyNames = append(addPxyNames, name)
 
@@ -184,48 +209,156 @@ func (pm *Manager) UpdateAll(proxyCfgs []v1.ProxyConfigurer) {
 	}
 }
 
-// 创建新的代理（tcp或http）
-func (pm *Manager) CreateProxy(proxyType string, name string, localIP string, localPort int, remotePort int) error {
+// newWrapper creates the wrapper of cfg with the settings of the manager, it's not started yet.
+func (pm *Manager) newWrapper(cfg v1.ProxyConfigurer) *Wrapper {
+	name := cfg.GetBaseConfig().Name
+	pxy := NewWrapper(pm.ctx, pm.runID, cfg, pm.clientCfg, pm.HandleEvent, pm.msgTransporter)
+	if pm.inWorkConnCallback != nil {
+		pxy.SetInWorkConnCallback(pm.inWorkConnCallback)
+	}
+	if pm.inspector != nil {
+		pxy.SetInspector(pm.inspector)
+	}
+	if pm.statsRegistry != nil {
+		pxy.SetStats(pm.statsRegistry.Get(name))
+	}
+	if pm.isPaused != nil && pm.isPaused(name) {
+		pxy.Pause()
+	}
+	return pxy
+}
+
+// 根据参数生成代理配置，remotePort仅对tcp和udp有效
+func NewProxyConfigurer(proxyType string, name string, localIP string, localPort int, remotePort int) (v1.ProxyConfigurer, error) {
 	cfg := v1.NewProxyConfigurerByType(v1.ProxyType(proxyType))
 	if cfg == nil {
-		return fmt.Errorf("new proxy configurer error")
+		return nil, fmt.Errorf("unknown proxy type: %s", proxyType)
 	}
 
 	cfg.GetBaseConfig().Name = name
-	cfg.GetBaseConfig().Type = proxyType
-	// cfg.GetBaseConfig().LocalIP = localIP
+	cfg.GetBaseConfig().LocalIP = localIP
 	cfg.GetBaseConfig().LocalPort = localPort
 
-	serverIp := pm.clientCfg.ServerAddr
-
-	switch proxyType {
-	case string(v1.ProxyTypeTCP):
-		cfg.(*v1.TCPProxyConfig).LocalIP = localIP
-		cfg.(*v1.TCPProxyConfig).LocalPort = localPort
-		cfg.(*v1.TCPProxyConfig).RemotePort = remotePort
-
-	case string(v1.ProxyTypeHTTP):
-		cfg.(*v1.HTTPProxyConfig).LocalIP = localIP
-		cfg.(*v1.HTTPProxyConfig).LocalPort = localPort
+	switch c := cfg.(type) {
+	case *v1.TCPProxyConfig:
+		c.RemotePort = remotePort
+	case *v1.UDPProxyConfig:
+		c.RemotePort = remotePort
+	}
+	return cfg, nil
+}
 
-	default:
-		cfg.(*v1.HTTPProxyConfig).LocalIP = localIP
+// 创建新的代理
+func (pm *Manager) CreateProxy(proxyType string, name string, localIP string, localPort int, remotePort int) error {
+	cfg, err := NewProxyConfigurer(proxyType, name, localIP, localPort, remotePort)
+	if err != nil {
+		return err
 	}
+	return pm.AddProxy(cfg)
+}
 
-	logx.Debugf("CreateProxy serverIp: %v, proxyCfg: %v", serverIp, utils2.PrettyJson(cfg))
+// 启动一个新的代理，已存在同名代理时返回ErrProxyExist
+func (pm *Manager) AddProxy(cfg v1.ProxyConfigurer) error {
+	xl := xlog.FromContextSafe(pm.ctx)
+	name := cfg.GetBaseConfig().Name
 
-	pxy := NewWrapper(pm.ctx, cfg, pm.clientCfg, pm.HandleEvent, pm.msgTransporter)
-	if pm.inWorkConnCallback != nil {
-		pxy.SetInWorkConnCallback(pm.inWorkConnCallback)
+	logx.Debugf("AddProxy serverIp: %v, proxyCfg: %v", pm.clientCfg.ServerAddr, utils2.PrettyJson(cfg))
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if _, ok := pm.proxies[name]; ok {
+		return ErrProxyExist
 	}
 
+	pxy := pm.newWrapper(cfg)
 	pm.proxies[name] = pxy
 
 	pxy.Start()
+	xl.Infof("proxy added: %s", []string{name})
+
+	return nil
+}
+
+// 停止并移除代理，会向服务端发送CloseProxy
+func (pm *Manager) RemoveProxy(name string) error {
+	xl := xlog.FromContextSafe(pm.ctx)
+
+	pm.mu.Lock()
+	pxy, ok := pm.proxies[name]
+	if ok {
+		delete(pm.proxies, name)
+	}
+	pm.mu.Unlock()
+
+	if !ok {
+		return ErrProxyNotFound
+	}
+
+	pxy.Stop()
+	xl.Infof("proxy removed: %s", []string{name})
+	return nil
+}
+
+// UpdateProxy replaces the config of a running proxy with the same name, see Wrapper.Update.
+func (pm *Manager) UpdateProxy(cfg v1.ProxyConfigurer) (UpdateMode, error) {
+	name := cfg.GetBaseConfig().Name
+
+	pm.mu.RLock()
+	pxy, ok := pm.proxies[name]
+	pm.mu.RUnlock()
+	if !ok {
+		return "", ErrProxyNotFound
+	}
+
+	mode, err := pxy.Update(cfg)
+	if err != nil {
+		return "", err
+	}
+	if mode != UpdateModeNone {
+		xlog.FromContextSafe(pm.ctx).Infof("proxy updated by %s: %s", mode, []string{name})
+	}
+	return mode, nil
+}
+
+// PauseProxy closes the proxy on the server but keeps it in the manager, see Wrapper.Pause.
+func (pm *Manager) PauseProxy(name string) error {
+	pm.mu.RLock()
+	pxy, ok := pm.proxies[name]
+	pm.mu.RUnlock()
+	if !ok {
+		return ErrProxyNotFound
+	}
 
+	pxy.Pause()
+	xlog.FromContextSafe(pm.ctx).Infof("proxy paused: %s", []string{name})
 	return nil
 }
 
+// ResumeProxy registers a paused proxy on the server again.
+func (pm *Manager) ResumeProxy(name string) error {
+	pm.mu.RLock()
+	pxy, ok := pm.proxies[name]
+	pm.mu.RUnlock()
+	if !ok {
+		return ErrProxyNotFound
+	}
+
+	pxy.Resume()
+	xlog.FromContextSafe(pm.ctx).Infof("proxy resumed: %s", []string{name})
+	return nil
+}
+
+// DialLocal dials the local service of the proxy, see Proxy.DialLocal.
+func (pm *Manager) DialLocal(name string) (net.Conn, error) {
+	pm.mu.RLock()
+	pw, ok := pm.proxies[name]
+	pm.mu.RUnlock()
+	if !ok {
+		return nil, ErrProxyNotFound
+	}
+	return pw.DialLocal()
+}
+
 func (pm *Manager) GetProxyDetail(name string) (*WorkingDetial, bool) {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
@@ -237,18 +370,15 @@ func (pm *Manager) GetProxyDetail(name string) (*WorkingDetial, bool) {
 
 // 代理是否已创建
 func (pm *Manager) IsProxyExist(name string) bool {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-	if _, ok := pm.proxies[name]; ok {
-		// webhook
-		proxyDetial, isSuccess := pm.GetProxyDetail(name)
-		if isSuccess {
-			webhook.PushProxyDetail(proxyDetial)
-
-			return true
-		}
-		return false
+	proxyDetial, isExist := pm.GetProxyDetail(name)
+	if isExist {
+		e := webhook.NewEvent(webhook.EventProxyExists, pm.runID)
+		e.ProxyName = name
+		e.ProxyType = proxyDetial.Type
+		e.Phase = proxyDetial.Status
+		e.Detail = proxyDetial
+		webhook.Push(e)
 	}
 
-	return false
+	return isExist
 }
@@ -1 +1,186 @@
 package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"frpgo/client/tracing/tracingtest"
+	"frpgo/pkg/config/types"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/msg"
+	"frpgo/pkg/transport"
+)
+
+func newTestManager(t *testing.T) (*Manager, chan msg.Message) {
+	clientCfg := &v1.ClientCommonConfig{}
+	clientCfg.Complete()
+
+	sendCh := make(chan msg.Message, 16)
+	pm := NewManager(context.Background(), "", clientCfg, transport.NewMessageTransporter(sendCh))
+	t.Cleanup(pm.Close)
+	return pm, sendCh
+}
+
+func recvMsg(t *testing.T, ch chan msg.Message) msg.Message {
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait message timeout")
+	}
+	return nil
+}
+
+func TestManagerCreateAndRemoveProxy(t *testing.T) {
+	require := require.New(t)
+	pm, sendCh := newTestManager(t)
+
+	require.NoError(pm.CreateProxy("tcp", "ssh", "127.0.0.1", 22, 6000))
+	require.ErrorIs(pm.CreateProxy("tcp", "ssh", "127.0.0.1", 22, 6000), ErrProxyExist)
+
+	newProxyMsg, ok := recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+	require.Equal("ssh", newProxyMsg.ProxyName)
+	require.Equal(6000, newProxyMsg.RemotePort)
+
+	detail, ok := pm.GetProxyDetail("ssh")
+	require.True(ok)
+	require.Equal("127.0.0.1", detail.Config.LocalIP)
+	require.Equal(22, detail.Config.LocalPort)
+
+	require.NoError(pm.RemoveProxy("ssh"))
+	closeProxyMsg, ok := recvMsg(t, sendCh).(*msg.CloseProxy)
+	require.True(ok)
+	require.Equal("ssh", closeProxyMsg.ProxyName)
+
+	_, ok = pm.GetProxyDetail("ssh")
+	require.False(ok)
+	require.ErrorIs(pm.RemoveProxy("ssh"), ErrProxyNotFound)
+}
+
+func TestManagerTraceProxyStart(t *testing.T) {
+	require := require.New(t)
+	c := tracingtest.NewCollector(t)
+	pm, sendCh := newTestManager(t)
+
+	require.NoError(pm.CreateProxy("tcp", "ssh", "127.0.0.1", 22, 6000))
+	_, ok := recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+	require.Nil(c.Span("proxy.start"))
+
+	require.NoError(pm.StartProxy("ssh", ":6000", ""))
+
+	start := c.Span("proxy.start")
+	require.NotNil(start)
+	require.Equal("ssh", tracingtest.Attr(start, "proxy.name"))
+	require.Equal(tracepb.Status_STATUS_CODE_UNSET, start.Status.Code)
+	run := c.Span("proxy.run")
+	require.NotNil(run)
+	require.Equal(start.SpanId, run.ParentSpanId)
+}
+
+func TestManagerPauseAndResumeProxy(t *testing.T) {
+	require := require.New(t)
+	pm, sendCh := newTestManager(t)
+
+	require.NoError(pm.CreateProxy("tcp", "ssh", "127.0.0.1", 22, 6000))
+	_, ok := recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+	require.NoError(pm.StartProxy("ssh", ":6000", ""))
+
+	require.NoError(pm.PauseProxy("ssh"))
+	closeProxyMsg, ok := recvMsg(t, sendCh).(*msg.CloseProxy)
+	require.True(ok)
+	require.Equal("ssh", closeProxyMsg.ProxyName)
+	status, ok := pm.GetProxyStatus("ssh")
+	require.True(ok)
+	require.Equal(ProxyPhasePaused, status.Phase)
+	require.Error(pm.StartProxy("ssh", ":6000", ""))
+
+	require.NoError(pm.ResumeProxy("ssh"))
+	newProxyMsg, ok := recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+	require.Equal("ssh", newProxyMsg.ProxyName)
+	require.NoError(pm.StartProxy("ssh", ":6000", ""))
+
+	require.ErrorIs(pm.PauseProxy("web"), ErrProxyNotFound)
+	require.ErrorIs(pm.ResumeProxy("web"), ErrProxyNotFound)
+}
+
+func TestManagerCreatePausedProxy(t *testing.T) {
+	require := require.New(t)
+	pm, sendCh := newTestManager(t)
+	pm.SetPausedFunc(func(name string) bool { return name == "ssh" })
+
+	require.NoError(pm.CreateProxy("tcp", "ssh", "127.0.0.1", 22, 6000))
+	status, ok := pm.GetProxyStatus("ssh")
+	require.True(ok)
+	require.Equal(ProxyPhasePaused, status.Phase)
+	select {
+	case m := <-sendCh:
+		t.Fatalf("unexpected message %T for a paused proxy", m)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(pm.ResumeProxy("ssh"))
+	_, ok = recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+}
+
+func TestManagerUpdateProxy(t *testing.T) {
+	require := require.New(t)
+	pm, sendCh := newTestManager(t)
+
+	cfg, err := NewProxyConfigurer("tcp", "ssh", "127.0.0.1", 22, 6000)
+	require.NoError(err)
+	cfg.Complete("")
+	require.NoError(pm.AddProxy(cfg))
+	_, ok := recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+	require.NoError(pm.StartProxy("ssh", ":6000", ""))
+
+	// local address and bandwidth limit in client mode are applied in place
+	hotCfg, err := NewProxyConfigurer("tcp", "ssh", "127.0.0.1", 2222, 6000)
+	require.NoError(err)
+	hotCfg.Complete("")
+	hotCfg.GetBaseConfig().Transport.BandwidthLimit, err = types.NewBandwidthQuantity("1MB")
+	require.NoError(err)
+	mode, err := pm.UpdateProxy(hotCfg)
+	require.NoError(err)
+	require.Equal(UpdateModeHotSwap, mode)
+	status, ok := pm.GetProxyStatus("ssh")
+	require.True(ok)
+	require.Equal(ProxyPhaseRunning, status.Phase)
+	require.Equal(2222, status.Cfg.GetBaseConfig().LocalPort)
+	select {
+	case m := <-sendCh:
+		t.Fatalf("unexpected message %T for hot swap", m)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	mode, err = pm.UpdateProxy(hotCfg)
+	require.NoError(err)
+	require.Equal(UpdateModeNone, mode)
+
+	// remote port is registered on the server
+	reopenCfg, err := NewProxyConfigurer("tcp", "ssh", "127.0.0.1", 2222, 6001)
+	require.NoError(err)
+	reopenCfg.Complete("")
+	mode, err = pm.UpdateProxy(reopenCfg)
+	require.NoError(err)
+	require.Equal(UpdateModeReopen, mode)
+	_, ok = recvMsg(t, sendCh).(*msg.CloseProxy)
+	require.True(ok)
+	newProxyMsg, ok := recvMsg(t, sendCh).(*msg.NewProxy)
+	require.True(ok)
+	require.Equal(6001, newProxyMsg.RemotePort)
+
+	unknown, err := NewProxyConfigurer("tcp", "web", "127.0.0.1", 80, 0)
+	require.NoError(err)
+	_, err = pm.UpdateProxy(unknown)
+	require.ErrorIs(err, ErrProxyNotFound)
+}
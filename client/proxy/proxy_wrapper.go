@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -25,9 +26,16 @@ import (
 
 	"github.com/fatedier/golib/errors"
 	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"frpgo/client/event"
 	"frpgo/client/health"
+	"frpgo/client/inspect"
+	"frpgo/client/metrics"
+	"frpgo/client/tracing"
+	"frpgo/fmgr/webhook"
+	"frpgo/pkg/config/types"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	"frpgo/pkg/transport"
@@ -41,6 +49,30 @@ const (
 	ProxyPhaseRunning     = "running"
 	ProxyPhaseCheckFailed = "check failed"
 	ProxyPhaseClosed      = "closed"
+	ProxyPhasePaused      = "paused"
+)
+
+// Wrappers are created again on every login, entering the new phase doesn't push
+// proxy.created, it's pushed by client.Service when a proxy is really created.
+var phaseEvents = map[string]webhook.EventType{
+	ProxyPhaseWaitStart:   webhook.EventProxyWaitStart,
+	ProxyPhaseStartErr:    webhook.EventProxyStartError,
+	ProxyPhaseRunning:     webhook.EventProxyStarted,
+	ProxyPhaseCheckFailed: webhook.EventProxyCheckFailed,
+	ProxyPhaseClosed:      webhook.EventProxyClosed,
+	ProxyPhasePaused:      webhook.EventProxyPaused,
+}
+
+// UpdateMode tells how Wrapper.Update applies a new config.
+type UpdateMode string
+
+const (
+	// the config is not changed
+	UpdateModeNone UpdateMode = "none"
+	// only client side settings are changed, they're applied without registering the proxy again
+	UpdateModeHotSwap UpdateMode = "hot-swap"
+	// the proxy is closed on the server and registered again with the new config
+	UpdateModeReopen UpdateMode = "reopen"
 )
 
 var (
@@ -59,6 +91,33 @@ type WorkingStatus struct {
 
 	// Got from server.
 	RemoteAddr string `json:"remote_addr"`
+
+	Stats StatsSnapshot `json:"stats"`
+	// kept across reconnects like Stats
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Detail converts the status to WorkingDetial.
+func (s *WorkingStatus) Detail() *WorkingDetial {
+	cfg := ConfigInfo{
+		LocalIP:   s.Cfg.GetBaseConfig().LocalIP,
+		LocalPort: s.Cfg.GetBaseConfig().LocalPort,
+	}
+	if httpCfg, ok := s.Cfg.(*v1.HTTPProxyConfig); ok {
+		cfg.Inspect = httpCfg.Inspect
+	}
+
+	return &WorkingDetial{
+		Name:       s.Name,
+		Type:       s.Type,
+		Status:     s.Phase,
+		Config:     cfg,
+		PublicUrl:  s.RemoteIP + s.RemoteAddr,
+		RemoteAddr: s.RemoteAddr,
+		Err:        s.Err,
+		CreatedAt:  s.CreatedAt,
+		Stats:      s.Stats,
+	}
 }
 
 type Wrapper struct {
@@ -75,6 +134,20 @@ type Wrapper struct {
 	handler event.Handler
 
 	msgTransporter transport.MessageTransporter
+	clientCfg      *v1.ClientCommonConfig
+
+	// kept to set up the underlying proxy again when the config is updated
+	inWorkConnCallback func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) bool
+	inspector          *inspect.Recorder
+
+	// run id of the controller, attached to webhook events
+	runID string
+
+	stats *Stats
+
+	// span from sending NewProxy to leaving the wait start phase
+	startSpan trace.Span
+	startCtx  context.Context
 
 	health           uint32
 	lastSendStartMsg time.Time
@@ -89,6 +162,7 @@ type Wrapper struct {
 
 func NewWrapper(
 	ctx context.Context,
+	runID string,
 	cfg v1.ProxyConfigurer,
 	clientCfg *v1.ClientCommonConfig,
 	eventHandler event.Handler,
@@ -100,7 +174,6 @@ func NewWrapper(
 		WorkingStatus: WorkingStatus{
 			Name:     baseInfo.Name,
 			Type:     baseInfo.Type,
-			Phase:    ProxyPhaseNew,
 			RemoteIP: clientCfg.ServerAddr,
 			Cfg:      cfg,
 		},
@@ -108,26 +181,74 @@ func NewWrapper(
 		healthNotifyCh: make(chan struct{}),
 		handler:        eventHandler,
 		msgTransporter: msgTransporter,
+		clientCfg:      clientCfg,
+		runID:          runID,
+		stats:          NewStats(),
 		xl:             xl,
 		ctx:            xlog.NewContext(ctx, xl),
 	}
 
-	if baseInfo.HealthCheck.Type != "" && baseInfo.LocalPort > 0 {
+	pw.monitor = pw.newMonitor(cfg)
+	if pw.monitor != nil {
 		pw.health = 1 // means failed
-		addr := net.JoinHostPort(baseInfo.LocalIP, strconv.Itoa(baseInfo.LocalPort))
-		pw.monitor = health.NewMonitor(pw.ctx, baseInfo.HealthCheck, addr,
-			pw.statusNormalCallback, pw.statusFailedCallback)
 		xl.Tracef("enable health check monitor")
 	}
 
-	pw.pxy = NewProxy(pw.ctx, pw.Cfg, clientCfg, pw.msgTransporter)
+	pw.pxy = pw.newProxy(cfg)
+	pw.setPhase(ProxyPhaseNew)
 	return pw
 }
 
+// newMonitor creates the health check monitor of cfg, it returns nil if health check is disabled.
+func (pw *Wrapper) newMonitor(cfg v1.ProxyConfigurer) *health.Monitor {
+	baseInfo := cfg.GetBaseConfig()
+	if baseInfo.HealthCheck.Type == "" || baseInfo.LocalPort <= 0 {
+		return nil
+	}
+	addr := net.JoinHostPort(baseInfo.LocalIP, strconv.Itoa(baseInfo.LocalPort))
+	return health.NewMonitor(pw.ctx, baseInfo.HealthCheck, addr,
+		pw.statusNormalCallback, pw.statusFailedCallback)
+}
+
+// newProxy creates the underlying proxy of cfg with the settings of the wrapper.
+func (pw *Wrapper) newProxy(cfg v1.ProxyConfigurer) Proxy {
+	pxy := NewProxy(pw.ctx, cfg, pw.clientCfg, pw.msgTransporter)
+	pxy.SetStats(pw.stats)
+	if pw.inWorkConnCallback != nil {
+		pxy.SetInWorkConnCallback(pw.inWorkConnCallback)
+	}
+	if httpCfg, ok := cfg.(*v1.HTTPProxyConfig); ok && httpCfg.Inspect && pw.inspector != nil {
+		pxy.SetInspector(pw.inspector)
+	}
+	return pxy
+}
+
 func (pw *Wrapper) SetInWorkConnCallback(cb func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) bool) {
+	pw.inWorkConnCallback = cb
 	pw.pxy.SetInWorkConnCallback(cb)
 }
 
+// SetInspector enables capturing of requests and responses if it's a http proxy with inspect enabled.
+func (pw *Wrapper) SetInspector(r *inspect.Recorder) {
+	pw.inspector = r
+	if cfg, ok := pw.Cfg.(*v1.HTTPProxyConfig); ok && cfg.Inspect {
+		pw.pxy.SetInspector(r)
+	}
+}
+
+// SetStats replaces the statistics collector, it should be called before Start.
+func (pw *Wrapper) SetStats(s *Stats) {
+	pw.stats = s
+	pw.pxy.SetStats(s)
+}
+
+func (pw *Wrapper) DialLocal() (net.Conn, error) {
+	pw.mu.RLock()
+	pxy := pw.pxy
+	pw.mu.RUnlock()
+	return pxy.DialLocal()
+}
+
 func (pw *Wrapper) SetRunningStatus(remoteAddr string, respErr string) error {
 	logx.Debugf("SetRunningStatus remoteAddr: %v", remoteAddr)
 
@@ -137,24 +258,30 @@ func (pw *Wrapper) SetRunningStatus(remoteAddr string, respErr string) error {
 		return fmt.Errorf("status not wait start, ignore start message")
 	}
 
+	pw.startSpan.AddEvent("receive NewProxyResp", trace.WithAttributes(
+		attribute.String("remote_addr", remoteAddr),
+	))
 	pw.RemoteAddr = remoteAddr
 	if respErr != "" {
-		pw.Phase = ProxyPhaseStartErr
 		pw.Err = respErr
 		pw.lastStartErr = time.Now()
+		pw.setPhase(ProxyPhaseStartErr)
 		return fmt.Errorf(pw.Err)
 	}
 
-	if err := pw.pxy.Run(); err != nil {
+	_, runSpan := tracing.Start(pw.startCtx, "proxy.run")
+	err := pw.pxy.Run()
+	tracing.End(runSpan, err)
+	if err != nil {
 		pw.close()
-		pw.Phase = ProxyPhaseStartErr
 		pw.Err = err.Error()
 		pw.lastStartErr = time.Now()
+		pw.setPhase(ProxyPhaseStartErr)
 		return err
 	}
 
-	pw.Phase = ProxyPhaseRunning
 	pw.Err = ""
+	pw.setPhase(ProxyPhaseRunning)
 	return nil
 }
 
@@ -174,10 +301,113 @@ func (pw *Wrapper) Stop() {
 	if pw.monitor != nil {
 		pw.monitor.Stop()
 	}
-	pw.Phase = ProxyPhaseClosed
+	pw.setPhase(ProxyPhaseClosed)
 	pw.close()
 }
 
+// Pause closes the proxy on the server and stops registering it again until Resume is called.
+// Config, stats and captured requests are kept, work connections are rejected while paused.
+func (pw *Wrapper) Pause() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if pw.Phase == ProxyPhasePaused || pw.Phase == ProxyPhaseClosed {
+		return
+	}
+	// NewProxy has not been sent yet in the new phase
+	if pw.Phase != ProxyPhaseNew {
+		pw.close()
+	}
+	pw.Err = ""
+	pw.setPhase(ProxyPhasePaused)
+}
+
+// Resume registers a paused proxy on the server again.
+func (pw *Wrapper) Resume() {
+	pw.mu.Lock()
+	if pw.Phase != ProxyPhasePaused {
+		pw.mu.Unlock()
+		return
+	}
+	pw.setPhase(ProxyPhaseNew)
+	pw.mu.Unlock()
+	pw.notifyCheckWorker()
+}
+
+// Update replaces the config of the proxy, name and type of cfg must not be changed.
+// If the NewProxy message of cfg is the same, the underlying proxy and health check monitor
+// are replaced in place and the proxy keeps working on the server. Otherwise the proxy is closed
+// on the server and registered again. Stats and captured requests are kept in both cases.
+func (pw *Wrapper) Update(cfg v1.ProxyConfigurer) (UpdateMode, error) {
+	pw.mu.Lock()
+	if reflect.DeepEqual(pw.Cfg, cfg) {
+		pw.mu.Unlock()
+		return UpdateModeNone, nil
+	}
+
+	mode := UpdateModeHotSwap
+	if needReopen(pw.Cfg, cfg) {
+		mode = UpdateModeReopen
+	}
+
+	pxy := pw.newProxy(cfg)
+	// a running proxy serves work connections with the new one at once,
+	// otherwise it's run after the server accepts the proxy
+	if mode == UpdateModeHotSwap && pw.Phase == ProxyPhaseRunning {
+		if err := pxy.Run(); err != nil {
+			pxy.Close()
+			pw.mu.Unlock()
+			return "", err
+		}
+	}
+
+	oldPxy := pw.pxy
+	pw.pxy = pxy
+	pw.Cfg = cfg
+	oldPxy.Close()
+
+	oldMonitor := pw.monitor
+	pw.monitor = pw.newMonitor(cfg)
+	if oldMonitor != nil {
+		oldMonitor.Stop()
+	}
+	if pw.monitor == nil {
+		atomic.StoreUint32(&pw.health, 0)
+	}
+
+	if mode == UpdateModeReopen && pw.Phase != ProxyPhasePaused {
+		if pw.Phase != ProxyPhaseNew {
+			pw.close()
+		}
+		pw.Err = ""
+		pw.setPhase(ProxyPhaseNew)
+	}
+	pw.pushEvent(webhook.EventProxyUpdated)
+	monitor := pw.monitor
+	pw.mu.Unlock()
+
+	if monitor != nil {
+		go monitor.Start()
+	}
+	pw.notifyCheckWorker()
+	return mode, nil
+}
+
+// needReopen reports if the proxy has to be registered on the server again to apply newCfg,
+// which is true if the NewProxy message is changed.
+func needReopen(oldCfg v1.ProxyConfigurer, newCfg v1.ProxyConfigurer) bool {
+	var oldMsg, newMsg msg.NewProxy
+	oldCfg.MarshalToMsg(&oldMsg)
+	newCfg.MarshalToMsg(&newMsg)
+
+	// bandwidth limit is done by the client in client mode
+	if oldCfg.GetBaseConfig().Transport.BandwidthLimitMode == types.BandwidthLimitModeClient &&
+		newCfg.GetBaseConfig().Transport.BandwidthLimitMode == types.BandwidthLimitModeClient {
+		oldMsg.BandwidthLimit = ""
+		newMsg.BandwidthLimit = ""
+	}
+	return !reflect.DeepEqual(oldMsg, newMsg)
+}
+
 func (pw *Wrapper) close() {
 	_ = pw.handler(&event.CloseProxyPayload{
 		CloseProxyMsg: &msg.CloseProxy{
@@ -203,14 +433,18 @@ func (pw *Wrapper) checkWorker() {
 				(pw.Phase == ProxyPhaseStartErr && now.After(pw.lastStartErr.Add(startErrTimeout))) {
 
 				xl.Tracef("change status from [%s] to [%s]", pw.Phase, ProxyPhaseWaitStart)
-				pw.Phase = ProxyPhaseWaitStart
+				pw.setPhase(ProxyPhaseWaitStart)
 
 				var newProxyMsg msg.NewProxy
 				pw.Cfg.MarshalToMsg(&newProxyMsg)
 				pw.lastSendStartMsg = now
-				_ = pw.handler(&event.StartProxyPayload{
+				err := pw.handler(&event.StartProxyPayload{
 					NewProxyMsg: &newProxyMsg,
 				})
+				if err != nil {
+					pw.startSpan.RecordError(err)
+				}
+				xlog.FromContextSafe(pw.startCtx).Debugf("send NewProxy message")
 			}
 			pw.mu.Unlock()
 		} else {
@@ -218,7 +452,7 @@ func (pw *Wrapper) checkWorker() {
 			if pw.Phase == ProxyPhaseRunning || pw.Phase == ProxyPhaseWaitStart {
 				pw.close()
 				xl.Tracef("change status from [%s] to [%s]", pw.Phase, ProxyPhaseCheckFailed)
-				pw.Phase = ProxyPhaseCheckFailed
+				pw.setPhase(ProxyPhaseCheckFailed)
 			}
 			pw.mu.Unlock()
 		}
@@ -235,33 +469,53 @@ func (pw *Wrapper) checkWorker() {
 func (pw *Wrapper) statusNormalCallback() {
 	xl := pw.xl
 	atomic.StoreUint32(&pw.health, 0)
-	_ = errors.PanicToError(func() {
-		select {
-		case pw.healthNotifyCh <- struct{}{}:
-		default:
-		}
-	})
+	pw.notifyCheckWorker()
 	xl.Infof("health check success")
+	pw.pushHealthEvent(webhook.EventProxyHealthy)
 }
 
 func (pw *Wrapper) statusFailedCallback() {
 	xl := pw.xl
 	atomic.StoreUint32(&pw.health, 1)
+	pw.notifyCheckWorker()
+	xl.Infof("health check failed")
+	pw.pushHealthEvent(webhook.EventProxyUnhealthy)
+}
+
+// notifyCheckWorker wakes up checkWorker to check the status without waiting for the next interval.
+func (pw *Wrapper) notifyCheckWorker() {
 	_ = errors.PanicToError(func() {
 		select {
 		case pw.healthNotifyCh <- struct{}{}:
 		default:
 		}
 	})
-	xl.Infof("health check failed")
+}
+
+func (pw *Wrapper) pushHealthEvent(typ webhook.EventType) {
+	pw.mu.RLock()
+	defer pw.mu.RUnlock()
+	pw.pushEvent(typ)
+}
+
+// pushEvent pushes an event with the current phase and detail of the proxy.
+// Hold lock before calling this function.
+func (pw *Wrapper) pushEvent(typ webhook.EventType) {
+	e := webhook.NewEvent(typ, pw.runID)
+	e.ProxyName = pw.Name
+	e.ProxyType = pw.Type
+	e.Phase = pw.Phase
+	e.Detail = pw.detail()
+	webhook.Push(e)
 }
 
 func (pw *Wrapper) InWorkConn(workConn net.Conn, m *msg.StartWorkConn) {
 	xl := pw.xl
 	pw.mu.RLock()
 	pxy := pw.pxy
+	running := pw.Phase == ProxyPhaseRunning
 	pw.mu.RUnlock()
-	if pxy != nil && pw.Phase == ProxyPhaseRunning {
+	if pxy != nil && running {
 		xl.Debugf("start a new work connection, localAddr: %s remoteAddr: %s", workConn.LocalAddr().String(), workConn.RemoteAddr().String())
 		go pxy.InWorkConn(workConn, m)
 	} else {
@@ -272,36 +526,83 @@ func (pw *Wrapper) InWorkConn(workConn net.Conn, m *msg.StartWorkConn) {
 func (pw *Wrapper) GetStatus() *WorkingStatus {
 	pw.mu.RLock()
 	defer pw.mu.RUnlock()
-	ps := &WorkingStatus{
+	return pw.status()
+}
+
+// Hold lock before calling this function.
+func (pw *Wrapper) status() *WorkingStatus {
+	return &WorkingStatus{
 		Name:       pw.Name,
 		Type:       pw.Type,
 		Phase:      pw.Phase,
 		Err:        pw.Err,
+		RemoteIP:   pw.RemoteIP,
 		Cfg:        pw.Cfg,
 		RemoteAddr: pw.RemoteAddr,
+		Stats:      pw.stats.Snapshot(),
+		CreatedAt:  pw.stats.CreatedAt(),
 	}
-	return ps
 }
 
-func (pw *Wrapper) GetDetial() *WorkingDetial {
-	pw.mu.RLock()
-	defer pw.mu.RUnlock()
-
-	cfg := ConfigInfo{
-		LocalIP:   pw.Cfg.GetBaseConfig().LocalIP,
-		LocalPort: pw.Cfg.GetBaseConfig().LocalPort,
-		Inspect:   false,
+// setPhase changes the phase and pushes the event of the transition.
+// Hold lock before calling this function.
+func (pw *Wrapper) setPhase(phase string) {
+	prev := pw.Phase
+	pw.Phase = phase
+	metrics.Client.ProxyPhase(pw.Name, pw.Type, phase)
+	pw.traceStart(prev, phase)
+
+	typ, ok := phaseEvents[phase]
+	if prev == ProxyPhasePaused {
+		typ, ok = webhook.EventProxyResumed, true
 	}
+	if !ok {
+		return
+	}
+	e := webhook.NewEvent(typ, pw.runID)
+	e.ProxyName = pw.Name
+	e.ProxyType = pw.Type
+	e.PrevPhase = prev
+	e.Phase = phase
+	e.Error = pw.Err
+	e.Detail = pw.detail()
+	webhook.Push(e)
+}
 
-	publicUrl := pw.RemoteIP + pw.RemoteAddr
+// traceStart traces starting of the proxy, a span is started when entering the wait start phase
+// and ended when leaving it.
+// Hold lock before calling this function.
+func (pw *Wrapper) traceStart(prev string, phase string) {
+	if prev == ProxyPhaseWaitStart && pw.startSpan != nil {
+		var err error
+		switch phase {
+		case ProxyPhaseRunning:
+		case ProxyPhaseStartErr:
+			err = fmt.Errorf("%s", pw.Err)
+		case ProxyPhaseWaitStart:
+			err = fmt.Errorf("wait NewProxyResp timeout")
+		default:
+			err = fmt.Errorf("proxy phase changed to [%s]", phase)
+		}
+		tracing.End(pw.startSpan, err)
+		pw.startSpan = nil
+	}
 
-	ps := &WorkingDetial{
-		Name:      pw.Name,
-		Type:      pw.Type,
-		Status:    pw.Phase,
-		Config:    cfg,
-		PublicUrl: publicUrl,
+	if phase == ProxyPhaseWaitStart {
+		pw.startCtx, pw.startSpan = tracing.Start(pw.ctx, "proxy.start", trace.WithAttributes(
+			attribute.String("proxy.name", pw.Name),
+			attribute.String("proxy.type", pw.Type),
+			attribute.String("run_id", pw.runID),
+		))
 	}
+}
+
+func (pw *Wrapper) GetDetial() *WorkingDetial {
+	pw.mu.RLock()
+	defer pw.mu.RUnlock()
+	return pw.detail()
+}
 
-	return ps
+func (pw *Wrapper) detail() *WorkingDetial {
+	return pw.status().Detail()
 }
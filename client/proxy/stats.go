@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"frpgo/client/metrics"
+	"frpgo/pkg/util/metric"
+)
+
+// statsReserveDays is the days of traffic history kept for each proxy.
+const statsReserveDays = 7
+
+// Stats collects traffic and connection statistics of work connections of a proxy.
+// Traffic is counted as it's transferred, so long-lived connections are counted before they're closed.
+type Stats struct {
+	// bytes read from and written to work connections
+	trafficIn  metric.DateCounter
+	trafficOut metric.DateCounter
+	curConns   metric.Counter
+
+	totalTrafficIn  atomic.Int64
+	totalTrafficOut atomic.Int64
+	totalConns      atomic.Int64
+	dialFailures    atomic.Int64
+	// duration of closed connections, in nanoseconds
+	totalDuration atomic.Int64
+	closedConns   atomic.Int64
+	lastConnAt    atomic.Int64
+
+	// Stats is kept across reconnects, so it's also the time the proxy is created
+	createdAt time.Time
+}
+
+type StatsSnapshot struct {
+	TrafficIn  int64 `json:"traffic_in"`
+	TrafficOut int64 `json:"traffic_out"`
+	// daily traffic of last days, today first
+	TrafficInHistory  []int64 `json:"traffic_in_history"`
+	TrafficOutHistory []int64 `json:"traffic_out_history"`
+	CurConns          int64   `json:"cur_conns"`
+	TotalConns        int64   `json:"total_conns"`
+	// failures of dialing to the local service
+	DialFailures      int64 `json:"dial_failures"`
+	AvgConnDurationMs int64 `json:"avg_conn_duration_ms"`
+	// zero if there is no connection yet
+	LastConnAt time.Time `json:"last_conn_at"`
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		trafficIn:  metric.NewDateCounter(statsReserveDays),
+		trafficOut: metric.NewDateCounter(statsReserveDays),
+		curConns:   metric.NewCounter(),
+		createdAt:  time.Now(),
+	}
+}
+
+func (s *Stats) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// OpenConn counts a new work connection, the returned function must be called when it's closed.
+func (s *Stats) OpenConn() func() {
+	start := time.Now()
+	s.curConns.Inc(1)
+	s.totalConns.Add(1)
+	s.lastConnAt.Store(start.UnixNano())
+
+	return func() {
+		s.curConns.Dec(1)
+		s.totalDuration.Add(int64(time.Since(start)))
+		s.closedConns.Add(1)
+	}
+}
+
+// AddTraffic counts bytes read from and written to a work connection.
+func (s *Stats) AddTraffic(in int64, out int64) {
+	if in > 0 {
+		s.trafficIn.Inc(in)
+		s.totalTrafficIn.Add(in)
+	}
+	if out > 0 {
+		s.trafficOut.Inc(out)
+		s.totalTrafficOut.Add(out)
+	}
+}
+
+func (s *Stats) DialFailed() {
+	s.dialFailures.Add(1)
+}
+
+func (s *Stats) Snapshot() StatsSnapshot {
+	snap := StatsSnapshot{
+		TrafficIn:         s.totalTrafficIn.Load(),
+		TrafficOut:        s.totalTrafficOut.Load(),
+		TrafficInHistory:  s.trafficIn.GetLastDaysCount(statsReserveDays),
+		TrafficOutHistory: s.trafficOut.GetLastDaysCount(statsReserveDays),
+		CurConns:          int64(s.curConns.Count()),
+		TotalConns:        s.totalConns.Load(),
+		DialFailures:      s.dialFailures.Load(),
+	}
+	if closed := s.closedConns.Load(); closed > 0 {
+		snap.AvgConnDurationMs = time.Duration(s.totalDuration.Load() / closed).Milliseconds()
+	}
+	if last := s.lastConnAt.Load(); last > 0 {
+		snap.LastConnAt = time.Unix(0, last)
+	}
+	return snap
+}
+
+// StatsRegistry keeps Stats of proxies by name, so statistics survive reconnecting to the server.
+// Metrics of proxies are also dropped when they're removed from the registry.
+type StatsRegistry struct {
+	stats map[string]*Stats
+	mu    sync.Mutex
+}
+
+func NewStatsRegistry() *StatsRegistry {
+	return &StatsRegistry{
+		stats: make(map[string]*Stats),
+	}
+}
+
+// Get returns Stats of the proxy, creates it if not exist.
+func (r *StatsRegistry) Get(name string) *Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.stats[name]
+	if !ok {
+		s = NewStats()
+		r.stats[name] = s
+	}
+	return s
+}
+
+func (r *StatsRegistry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.stats, name)
+	metrics.Client.RemoveProxy(name)
+}
+
+// Retain removes Stats of proxies not in names.
+func (r *StatsRegistry) Retain(names []string) {
+	keep := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		keep[name] = struct{}{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.stats {
+		if _, ok := keep[name]; !ok {
+			delete(r.stats, name)
+			metrics.Client.RemoveProxy(name)
+		}
+	}
+}
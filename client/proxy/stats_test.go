@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/msg"
+)
+
+func TestStats(t *testing.T) {
+	require := require.New(t)
+
+	s := NewStats()
+	snap := s.Snapshot()
+	require.Zero(snap.TotalConns)
+	require.True(snap.LastConnAt.IsZero())
+
+	closeFn := s.OpenConn()
+	s.OpenConn()
+	s.DialFailed()
+	s.AddTraffic(100, 0)
+	s.AddTraffic(0, 200)
+	closeFn()
+
+	snap = s.Snapshot()
+	require.EqualValues(100, snap.TrafficIn)
+	require.EqualValues(200, snap.TrafficOut)
+	require.EqualValues(100, snap.TrafficInHistory[0])
+	require.EqualValues(200, snap.TrafficOutHistory[0])
+	require.EqualValues(1, snap.CurConns)
+	require.EqualValues(2, snap.TotalConns)
+	require.EqualValues(1, snap.DialFailures)
+	require.False(snap.LastConnAt.IsZero())
+}
+
+func TestStatsRegistry(t *testing.T) {
+	require := require.New(t)
+
+	r := NewStatsRegistry()
+	a := r.Get("a")
+	require.Same(a, r.Get("a"))
+	r.Get("b")
+
+	r.Retain([]string{"b"})
+	require.NotSame(a, r.Get("a"))
+
+	b := r.Get("b")
+	r.Remove("b")
+	require.NotSame(b, r.Get("b"))
+}
+
+func TestTrackWorkConn(t *testing.T) {
+	require := require.New(t)
+
+	pxy := &BaseProxy{baseCfg: &v1.ProxyBaseConfig{Name: "ssh", Type: "tcp"}, stats: NewStats()}
+	local, remote := net.Pipe()
+	defer remote.Close()
+	conn := pxy.trackWorkConn(local)
+	go func() {
+		_, _ = remote.Write([]byte("hello"))
+		_, _ = io.ReadFull(remote, make([]byte, 3))
+	}()
+
+	// counted before the connection is closed
+	_, err := io.ReadFull(conn, make([]byte, 5))
+	require.NoError(err)
+	_, err = conn.Write([]byte("bye"))
+	require.NoError(err)
+	snap := pxy.stats.Snapshot()
+	require.EqualValues(5, snap.TrafficIn)
+	require.EqualValues(3, snap.TrafficOut)
+	require.EqualValues(1, snap.CurConns)
+
+	require.NoError(conn.Close())
+	require.NoError(conn.Close())
+	snap = pxy.stats.Snapshot()
+	require.EqualValues(5, snap.TrafficIn)
+	require.EqualValues(0, snap.CurConns)
+}
+
+func TestInWorkConnCallbackTracked(t *testing.T) {
+	require := require.New(t)
+
+	pxy := &BaseProxy{baseCfg: &v1.ProxyBaseConfig{Name: "web", Type: "http"}, stats: NewStats()}
+	var got net.Conn
+	pxy.SetInWorkConnCallback(func(_ *v1.ProxyBaseConfig, conn net.Conn, _ *msg.StartWorkConn) bool {
+		got = conn
+		return false
+	})
+	local, remote := net.Pipe()
+	defer remote.Close()
+	pxy.InWorkConn(local, &msg.StartWorkConn{})
+
+	// connections taken by the callback are counted like handled ones
+	require.IsType(&trackedConn{}, got)
+	require.EqualValues(1, pxy.stats.Snapshot().CurConns)
+	require.NoError(got.Close())
+	require.EqualValues(0, pxy.stats.Snapshot().CurConns)
+}
@@ -30,7 +30,6 @@ import (
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	"frpgo/pkg/proto/udp"
-	"frpgo/pkg/util/limit"
 	netpkg "frpgo/pkg/util/net"
 )
 
@@ -83,13 +82,9 @@ func (pxy *SUDPProxy) InWorkConn(conn net.Conn, _ *msg.StartWorkConn) {
 	xl := pxy.xl
 	xl.Infof("incoming a new work connection for sudp proxy, %s", conn.RemoteAddr().String())
 
+	conn = pxy.trackWorkConn(conn)
 	var rwc io.ReadWriteCloser = conn
 	var err error
-	if pxy.limiter != nil {
-		rwc = libio.WrapReadWriteCloser(limit.NewReader(conn, pxy.limiter), limit.NewWriter(conn, pxy.limiter), func() error {
-			return conn.Close()
-		})
-	}
 	if pxy.cfg.Transport.UseEncryption {
 		rwc, err = libio.WithEncryption(rwc, []byte(pxy.clientCfg.Auth.Token))
 		if err != nil {
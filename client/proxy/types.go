@@ -1,5 +1,7 @@
 package proxy
 
+import "time"
+
 type ConfigInfo struct {
 	LocalIP   string `json:"local_ip"`
 	LocalPort int    `json:"local_port"`
@@ -7,11 +9,17 @@ type ConfigInfo struct {
 }
 
 type WorkingDetial struct {
-	Name      string `json:"name"`
-	Uri       string `json:"uri"`
-	PublicUrl string `json:"public_url"`
-	Type      string `json:"type"`
-	Status    string `json:"status"`
+	Name       string    `json:"name"`
+	Uri        string    `json:"uri"`
+	PublicUrl  string    `json:"public_url"`
+	Type       string    `json:"type"`
+	Status     string    `json:"status"`
+	RemoteAddr string    `json:"remote_addr"`
+	Err        string    `json:"err"`
+	CreatedAt  time.Time `json:"created_at"`
+	// file or api, set by client.Service
+	Origin string `json:"origin,omitempty"`
 
-	Config ConfigInfo `json:"config"`
+	Config ConfigInfo    `json:"config"`
+	Stats  StatsSnapshot `json:"stats"`
 }
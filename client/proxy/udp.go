@@ -23,13 +23,11 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/fatedier/golib/errors"
 	libio "github.com/fatedier/golib/io"
 
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	"frpgo/pkg/proto/udp"
-	"frpgo/pkg/util/limit"
 	netpkg "frpgo/pkg/util/net"
 )
 
@@ -43,12 +41,14 @@ type UDPProxy struct {
 	cfg *v1.UDPProxyConfig
 
 	localAddr *net.UDPAddr
-	readCh    chan *msg.UDPPacket
 
-	// include msg.UDPPacket and msg.Ping
-	sendCh   chan msg.Message
+	// the raw work connection, the compression writer on it is not safe to close
+	// while the writer goroutine is using it
 	workConn net.Conn
-	closed   bool
+	// closed to stop goroutines of workConn, channels to them are never closed by Close
+	// as they may be sending to them
+	closeCh chan struct{}
+	closed  bool
 }
 
 func NewUDPProxy(baseProxy *BaseProxy, cfg v1.ProxyConfigurer) Proxy {
@@ -79,11 +79,8 @@ func (pxy *UDPProxy) Close() {
 		if pxy.workConn != nil {
 			pxy.workConn.Close()
 		}
-		if pxy.readCh != nil {
-			close(pxy.readCh)
-		}
-		if pxy.sendCh != nil {
-			close(pxy.sendCh)
+		if pxy.closeCh != nil {
+			close(pxy.closeCh)
 		}
 	}
 }
@@ -94,13 +91,9 @@ func (pxy *UDPProxy) InWorkConn(conn net.Conn, _ *msg.StartWorkConn) {
 	// close resources related with old workConn
 	pxy.Close()
 
+	conn = pxy.trackWorkConn(conn)
 	var rwc io.ReadWriteCloser = conn
 	var err error
-	if pxy.limiter != nil {
-		rwc = libio.WrapReadWriteCloser(limit.NewReader(conn, pxy.limiter), limit.NewWriter(conn, pxy.limiter), func() error {
-			return conn.Close()
-		})
-	}
 	if pxy.cfg.Transport.UseEncryption {
 		rwc, err = libio.WithEncryption(rwc, []byte(pxy.clientCfg.Auth.Token))
 		if err != nil {
@@ -112,27 +105,33 @@ func (pxy *UDPProxy) InWorkConn(conn net.Conn, _ *msg.StartWorkConn) {
 	if pxy.cfg.Transport.UseCompression {
 		rwc = libio.WithCompression(rwc)
 	}
+	rawConn := conn
 	conn = netpkg.WrapReadWriteCloserToConn(rwc, conn)
 
+	readCh := make(chan *msg.UDPPacket, 1024)
+	// include msg.UDPPacket and msg.Ping
+	sendCh := make(chan msg.Message, 1024)
+	closeCh := make(chan struct{})
 	pxy.mu.Lock()
-	pxy.workConn = conn
-	pxy.readCh = make(chan *msg.UDPPacket, 1024)
-	pxy.sendCh = make(chan msg.Message, 1024)
+	pxy.workConn = rawConn
+	pxy.closeCh = closeCh
 	pxy.closed = false
 	pxy.mu.Unlock()
 
+	// the only sender of readCh closes it, which stops the forwarder
 	workConnReaderFn := func(conn net.Conn, readCh chan *msg.UDPPacket) {
+		defer close(readCh)
 		for {
 			var udpMsg msg.UDPPacket
 			if errRet := msg.ReadMsgInto(conn, &udpMsg); errRet != nil {
 				xl.Warnf("read from workConn for udp error: %v", errRet)
 				return
 			}
-			if errRet := errors.PanicToError(func() {
-				xl.Tracef("get udp package from workConn: %s", udpMsg.Content)
-				readCh <- &udpMsg
-			}); errRet != nil {
-				xl.Infof("reader goroutine for udp work connection closed: %v", errRet)
+			xl.Tracef("get udp package from workConn: %s", udpMsg.Content)
+			select {
+			case readCh <- &udpMsg:
+			case <-closeCh:
+				xl.Infof("reader goroutine for udp work connection closed")
 				return
 			}
 		}
@@ -142,7 +141,13 @@ func (pxy *UDPProxy) InWorkConn(conn net.Conn, _ *msg.StartWorkConn) {
 			xl.Infof("writer goroutine for udp work connection closed")
 		}()
 		var errRet error
-		for rawMsg := range sendCh {
+		for {
+			var rawMsg msg.Message
+			select {
+			case rawMsg = <-sendCh:
+			case <-closeCh:
+				return
+			}
 			switch m := rawMsg.(type) {
 			case *msg.UDPPacket:
 				xl.Tracef("send udp package to workConn: %s", m.Content)
@@ -156,20 +161,24 @@ func (pxy *UDPProxy) InWorkConn(conn net.Conn, _ *msg.StartWorkConn) {
 		}
 	}
 	heartbeatFn := func(sendCh chan msg.Message) {
-		var errRet error
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
 		for {
-			time.Sleep(time.Duration(30) * time.Second)
-			if errRet = errors.PanicToError(func() {
-				sendCh <- &msg.Ping{}
-			}); errRet != nil {
+			select {
+			case <-ticker.C:
+			case <-closeCh:
 				xl.Tracef("heartbeat goroutine for udp work connection closed")
-				break
+				return
+			}
+			select {
+			case sendCh <- &msg.Ping{}:
+			case <-closeCh:
 			}
 		}
 	}
 
-	go workConnSenderFn(pxy.workConn, pxy.sendCh)
-	go workConnReaderFn(pxy.workConn, pxy.readCh)
-	go heartbeatFn(pxy.sendCh)
-	udp.Forwarder(pxy.localAddr, pxy.readCh, pxy.sendCh, int(pxy.clientCfg.UDPPacketSize))
+	go workConnSenderFn(conn, sendCh)
+	go workConnReaderFn(conn, readCh)
+	go heartbeatFn(sendCh)
+	udp.Forwarder(pxy.localAddr, readCh, sendCh, int(pxy.clientCfg.UDPPacketSize))
 }
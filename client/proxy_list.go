@@ -0,0 +1,190 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"frpgo/client/proxy"
+)
+
+// Sort keys of ListProxies.
+const (
+	ProxySortByName    = "name"
+	ProxySortByCreated = "created"
+	// sum of inbound and outbound traffic. Traffic changes between pages, so the list
+	// sorted by it is not paginated, Limit returns the top proxies without NextCursor.
+	ProxySortByTraffic = "traffic"
+)
+
+// ErrInvalidListOptions is returned by ListProxies for unknown sort keys or broken cursors.
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// ProxyListOptions filters, sorts and paginates proxies returned by ListProxies.
+// An empty filter matches all proxies.
+type ProxyListOptions struct {
+	ProxyFilter
+
+	// one of ProxySortBy*, ProxySortByName by default.
+	// Proxies with the same sort key are ordered by name.
+	SortBy string
+	Desc   bool
+
+	// NextCursor of the previous page, empty for the first page.
+	// It's not supported by ProxySortByTraffic.
+	Cursor string
+	// no limit if it's not positive
+	Limit int
+}
+
+// ProxyList is a page of proxies returned by ListProxies.
+type ProxyList struct {
+	Proxies []*proxy.WorkingDetial
+	// number of proxies matching the filters in all pages
+	Total int
+	// empty if it's the last page
+	NextCursor string
+}
+
+// proxyCursor is the position of a proxy in the sorted list, it's encoded as the page cursor.
+type proxyCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Key    int64  `json:"k,omitempty"`
+	Name   string `json:"n"`
+}
+
+func (c *proxyCursor) before(o *proxyCursor) bool {
+	if c.Key != o.Key {
+		return (c.Key < o.Key) != c.Desc
+	}
+	return (c.Name < o.Name) != c.Desc
+}
+
+func (c *proxyCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeProxyCursor(s string) (*proxyCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	c := &proxyCursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (o *ProxyListOptions) complete() error {
+	if o.SortBy == "" {
+		o.SortBy = ProxySortByName
+	}
+	if !slices.Contains([]string{ProxySortByName, ProxySortByCreated, ProxySortByTraffic}, o.SortBy) {
+		return fmt.Errorf("%w: unknown sort key [%s]", ErrInvalidListOptions, o.SortBy)
+	}
+	if o.SortBy == ProxySortByTraffic && o.Cursor != "" {
+		return fmt.Errorf("%w: cursor is not supported by sort key [%s]", ErrInvalidListOptions, o.SortBy)
+	}
+	return o.ProxyFilter.complete()
+}
+
+func (o *ProxyListOptions) cursor(s *proxy.WorkingStatus) *proxyCursor {
+	c := &proxyCursor{SortBy: o.SortBy, Desc: o.Desc, Name: s.Name}
+	switch o.SortBy {
+	case ProxySortByCreated:
+		c.Key = s.CreatedAt.UnixNano()
+	case ProxySortByTraffic:
+		c.Key = s.Stats.TrafficIn + s.Stats.TrafficOut
+	}
+	return c
+}
+
+// ListProxies returns proxies of the current control matching opts.
+func (svr *Service) ListProxies(opts ProxyListOptions) (*ProxyList, error) {
+	if err := opts.complete(); err != nil {
+		return nil, err
+	}
+	var after *proxyCursor
+	if opts.Cursor != "" {
+		c, err := decodeProxyCursor(opts.Cursor)
+		if err != nil || c.SortBy != opts.SortBy || c.Desc != opts.Desc {
+			return nil, fmt.Errorf("%w: cursor doesn't match the sort order", ErrInvalidListOptions)
+		}
+		after = c
+	}
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil {
+		return nil, ErrControlNotReady
+	}
+
+	type item struct {
+		status *proxy.WorkingStatus
+		origin string
+		cursor *proxyCursor
+	}
+	origins := svr.proxyOrigins()
+	items := make([]item, 0)
+	for _, s := range ctl.pm.GetAllProxyStatus() {
+		origin := origins[s.Name]
+		if opts.match(s.Cfg, s.Phase, origin) {
+			items = append(items, item{status: s, origin: origin, cursor: opts.cursor(s)})
+		}
+	}
+	slices.SortFunc(items, func(a, b item) int {
+		if a.cursor.before(b.cursor) {
+			return -1
+		}
+		if b.cursor.before(a.cursor) {
+			return 1
+		}
+		return 0
+	})
+
+	list := &ProxyList{
+		Proxies: make([]*proxy.WorkingDetial, 0),
+		Total:   len(items),
+	}
+	if after != nil {
+		i, _ := slices.BinarySearchFunc(items, after, func(it item, c *proxyCursor) int {
+			if c.before(it.cursor) {
+				return 1
+			}
+			return -1
+		})
+		items = items[i:]
+	}
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		items = items[:opts.Limit]
+		if opts.SortBy != ProxySortByTraffic {
+			list.NextCursor = items[len(items)-1].cursor.encode()
+		}
+	}
+	for _, it := range items {
+		detail := it.status.Detail()
+		detail.Origin = it.origin
+		list.Proxies = append(list.Proxies, detail)
+	}
+	return list, nil
+}
+
+// proxyOrigins returns where the proxies come from by name.
+func (svr *Service) proxyOrigins() map[string]string {
+	svr.cfgMu.RLock()
+	defer svr.cfgMu.RUnlock()
+	origins := make(map[string]string, len(svr.proxyCfgs)+len(svr.runtimeProxyCfgs))
+	for _, c := range svr.proxyCfgs {
+		origins[c.GetBaseConfig().Name] = ProxyOriginFile
+	}
+	for _, c := range svr.runtimeProxyCfgs {
+		origins[c.GetBaseConfig().Name] = ProxyOriginAPI
+	}
+	return origins
+}
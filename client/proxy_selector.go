@@ -0,0 +1,173 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "frpgo/pkg/config/v1"
+)
+
+// Origins of proxies.
+const (
+	// loaded from the config file
+	ProxyOriginFile = "file"
+	// created at runtime by AddProxy
+	ProxyOriginAPI = "api"
+)
+
+// ErrInvalidProxyFilter is returned for unknown origins, bad name patterns or selectors,
+// and for batch operations without any filter.
+var ErrInvalidProxyFilter = errors.New("invalid proxy filter")
+
+// ProxyFilter selects proxies for ListProxies and batch operations like DeleteProxies.
+// A proxy is selected if it matches all of the non-empty fields.
+type ProxyFilter struct {
+	Types  []string
+	Phases []string
+	// glob patterns of proxy names, a proxy matching any of them is selected
+	Names []string
+	// label selectors on metadatas and annotations of the proxy, see ParseProxySelector
+	Metadatas   labels.Selector
+	Annotations labels.Selector
+	// ProxyOriginFile or ProxyOriginAPI
+	Origin string
+}
+
+// ParseProxySelector parses a Kubernetes style label selector, e.g. `env=dev,team in (a,b),!temp`.
+// An empty string selects everything.
+func ParseProxySelector(s string) (labels.Selector, error) {
+	selector, err := labels.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxyFilter, err)
+	}
+	return selector, nil
+}
+
+func (f *ProxyFilter) complete() error {
+	if f.Origin != "" && f.Origin != ProxyOriginFile && f.Origin != ProxyOriginAPI {
+		return fmt.Errorf("%w: unknown origin [%s]", ErrInvalidProxyFilter, f.Origin)
+	}
+	for _, p := range f.Names {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%w: bad name pattern [%s]", ErrInvalidProxyFilter, p)
+		}
+	}
+	return nil
+}
+
+// empty reports if the filter selects all proxies.
+func (f *ProxyFilter) empty() bool {
+	return len(f.Types) == 0 && len(f.Phases) == 0 && len(f.Names) == 0 && f.Origin == "" &&
+		(f.Metadatas == nil || f.Metadatas.Empty()) && (f.Annotations == nil || f.Annotations.Empty())
+}
+
+func (f *ProxyFilter) match(cfg v1.ProxyConfigurer, phase string, origin string) bool {
+	base := cfg.GetBaseConfig()
+	if len(f.Types) > 0 && !slices.Contains(f.Types, strings.ToLower(base.Type)) {
+		return false
+	}
+	if len(f.Phases) > 0 && !slices.Contains(f.Phases, phase) {
+		return false
+	}
+	if len(f.Names) > 0 && !slices.ContainsFunc(f.Names, func(p string) bool {
+		ok, _ := path.Match(p, base.Name)
+		return ok
+	}) {
+		return false
+	}
+	if f.Origin != "" && f.Origin != origin {
+		return false
+	}
+	if f.Metadatas != nil && !f.Metadatas.Matches(labels.Set(base.Metadatas)) {
+		return false
+	}
+	return f.Annotations == nil || f.Annotations.Matches(labels.Set(base.Annotations))
+}
+
+// ProxyBatchResult is the result of a batch operation on proxies.
+type ProxyBatchResult struct {
+	// names of proxies the operation is applied to
+	Names []string
+	// errors of the proxies failed by name
+	Errors map[string]error
+}
+
+// DeleteProxies deletes all proxies selected by f like DeleteProxy, including those from the config file.
+// The filter must not be empty, use ResetAllConfigurer to remove all proxies.
+func (svr *Service) DeleteProxies(f ProxyFilter) (*ProxyBatchResult, error) {
+	return svr.batchProxies("delete", f, svr.DeleteProxy)
+}
+
+// PauseProxies pauses all proxies selected by f, see PauseProxy.
+func (svr *Service) PauseProxies(f ProxyFilter) (*ProxyBatchResult, error) {
+	return svr.batchProxies("pause", f, svr.PauseProxy)
+}
+
+// ResumeProxies resumes all paused proxies selected by f, see ResumeProxy.
+func (svr *Service) ResumeProxies(f ProxyFilter) (*ProxyBatchResult, error) {
+	return svr.batchProxies("resume", f, svr.ResumeProxy)
+}
+
+func (svr *Service) batchProxies(op string, f ProxyFilter, fn func(name string) error) (*ProxyBatchResult, error) {
+	names, err := svr.selectProxies(f)
+	if err != nil {
+		return nil, err
+	}
+	logx.Debugf("batch %s proxies: %v", op, names)
+
+	result := &ProxyBatchResult{
+		Names:  make([]string, 0, len(names)),
+		Errors: make(map[string]error),
+	}
+	for _, name := range names {
+		if err := fn(name); err != nil {
+			result.Errors[name] = err
+			continue
+		}
+		result.Names = append(result.Names, name)
+	}
+	return result, nil
+}
+
+// selectProxies returns names of the configured proxies selected by f, sorted by name.
+// Proxies are selected from the desired state, so it works before logging in,
+// but no proxy has a phase then.
+func (svr *Service) selectProxies(f ProxyFilter) ([]string, error) {
+	if err := f.complete(); err != nil {
+		return nil, err
+	}
+	if f.empty() {
+		return nil, fmt.Errorf("%w: no proxy filter", ErrInvalidProxyFilter)
+	}
+
+	svr.cfgMu.RLock()
+	cfgs := svr.allProxyCfgs()
+	svr.cfgMu.RUnlock()
+	origins := svr.proxyOrigins()
+
+	phases := make(map[string]string)
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl != nil {
+		for _, s := range ctl.pm.GetAllProxyStatus() {
+			phases[s.Name] = s.Phase
+		}
+	}
+
+	names := make([]string, 0)
+	for _, cfg := range cfgs {
+		name := cfg.GetBaseConfig().Name
+		if f.match(cfg, phases[name], origins[name]) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
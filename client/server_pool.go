@@ -0,0 +1,412 @@
+package client
+
+import (
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	"frpgo/pkg/auth"
+	v1 "frpgo/pkg/config/v1"
+)
+
+const (
+	// A server that failed to login with all of its protocols is skipped for a backoff
+	// period, which doubles on every consecutive failure, unless all servers are backing off.
+	serverBackoffBase = 5 * time.Second
+	serverBackoffMax  = time.Minute
+
+	// fall back to the next protocol after the control is closed due to heartbeat
+	// timeout this many times in a row
+	maxHeartbeatTimeouts = 2
+
+	maxFailoverHistory = 20
+)
+
+// serverEndpoint is a server to login, with its own copy of the common config.
+type serverEndpoint struct {
+	name   string
+	weight int
+	// the transport protocols to fall back through, the preferred one first
+	protocols []string
+	// common configs of the protocols
+	commons    []*v1.ClientCommonConfig
+	authSetter auth.Setter
+
+	// index of the protocol to login, it is kept after the protocol works
+	protocolIdx int
+	// protocols failed in a row since the last working one
+	protocolFailures  int
+	heartbeatTimeouts int
+
+	consecutiveFailures int
+	totalFailures       int64
+	totalSuccesses      int64
+	lastErr             string
+	lastFailureAt       time.Time
+	lastSuccessAt       time.Time
+	retryAfter          time.Time
+}
+
+func newServerEndpoint(common *v1.ClientCommonConfig, cfg v1.ServerEndpointConfig) *serverEndpoint {
+	c := *common
+	c.ServerAddr = cfg.ServerAddr
+	c.ServerPort = cfg.ServerPort
+	c.Servers = nil
+	if cfg.Transport != nil {
+		c.Transport = *cfg.Transport
+	}
+	if cfg.Auth != nil {
+		c.Auth = *cfg.Auth
+	}
+
+	ep := &serverEndpoint{
+		name:       cfg.Name,
+		weight:     cfg.Weight,
+		protocols:  lo.Uniq(c.Transport.Protocols),
+		authSetter: auth.NewAuthSetter(c.Auth),
+	}
+	if len(ep.protocols) == 0 {
+		ep.protocols = []string{c.Transport.Protocol}
+	}
+	for _, protocol := range ep.protocols {
+		pc := c
+		pc.Transport.Protocol = protocol
+		ep.commons = append(ep.commons, &pc)
+	}
+	return ep
+}
+
+func (ep *serverEndpoint) addr() string {
+	return net.JoinHostPort(ep.commons[0].ServerAddr, strconv.Itoa(ep.commons[0].ServerPort))
+}
+
+// score is the weight discounted by consecutive failures.
+func (ep *serverEndpoint) score() float64 {
+	return float64(ep.weight) / float64(1+ep.consecutiveFailures)
+}
+
+func (ep *serverEndpoint) target(protocolIdx int) *loginTarget {
+	return &loginTarget{
+		ep:          ep,
+		protocolIdx: protocolIdx,
+		common:      ep.commons[protocolIdx],
+	}
+}
+
+// loginTarget is a server with the transport protocol to login with.
+type loginTarget struct {
+	ep          *serverEndpoint
+	protocolIdx int
+	common      *v1.ClientCommonConfig
+}
+
+func (t *loginTarget) protocol() string {
+	return t.common.Transport.Protocol
+}
+
+type ServerStatus struct {
+	Name                string    `json:"name"`
+	Addr                string    `json:"addr"`
+	Protocol            string    `json:"protocol"`
+	Protocols           []string  `json:"protocols"`
+	Weight              int       `json:"weight"`
+	Active              bool      `json:"active"`
+	Healthy             bool      `json:"healthy"`
+	Score               float64   `json:"score"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	TotalFailures       int64     `json:"total_failures"`
+	TotalSuccesses      int64     `json:"total_successes"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailureAt       time.Time `json:"last_failure_at"`
+	LastSuccessAt       time.Time `json:"last_success_at"`
+	RetryAfter          time.Time `json:"retry_after"`
+}
+
+// FailoverRecord is recorded when the control connection moves to another server.
+type FailoverRecord struct {
+	Time time.Time `json:"time"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	// Reason is the last login error of the previous server.
+	Reason string `json:"reason,omitempty"`
+}
+
+type ServersStatus struct {
+	Strategy  string           `json:"strategy"`
+	Active    string           `json:"active,omitempty"`
+	Servers   []ServerStatus   `json:"servers"`
+	Failovers []FailoverRecord `json:"failovers"`
+}
+
+// serverPool chooses a server to login from the configured servers with health scoring.
+type serverPool struct {
+	mu        sync.Mutex
+	strategy  string
+	endpoints []*serverEndpoint
+	// the server of the current control, it is kept after the control is closed
+	// until another server is logged in.
+	active *serverEndpoint
+	// index of the protocol of the current control
+	activeIdx int
+	// the server to retry first with another protocol
+	pending *serverEndpoint
+	history []FailoverRecord
+}
+
+func newServerPool(common *v1.ClientCommonConfig) *serverPool {
+	p := &serverPool{strategy: common.ServerStrategy}
+	servers := common.Servers
+	if len(servers) == 0 {
+		servers = []v1.ServerEndpointConfig{{
+			ServerAddr: common.ServerAddr,
+			ServerPort: common.ServerPort,
+		}}
+		servers[0].Complete()
+	}
+	for _, cfg := range servers {
+		p.endpoints = append(p.endpoints, newServerEndpoint(common, cfg))
+	}
+	return p
+}
+
+// next returns the server and protocol for the next login attempt.
+func (p *serverPool) next() *loginTarget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ep := p.pending; ep != nil {
+		p.pending = nil
+		return ep.target(ep.protocolIdx)
+	}
+
+	now := time.Now()
+	candidates := make([]*serverEndpoint, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		if !now.Before(ep.retryAfter) {
+			candidates = append(candidates, ep)
+		}
+	}
+	if len(candidates) == 0 {
+		// all servers are backing off, try the one that recovers first
+		first := p.endpoints[0]
+		for _, ep := range p.endpoints[1:] {
+			if ep.retryAfter.Before(first.retryAfter) {
+				first = ep
+			}
+		}
+		return first.target(first.protocolIdx)
+	}
+
+	ep := candidates[0]
+	if p.strategy == "weighted" {
+		ep = pickWeighted(candidates)
+	}
+	return ep.target(ep.protocolIdx)
+}
+
+func pickWeighted(candidates []*serverEndpoint) *serverEndpoint {
+	total := 0.0
+	for _, ep := range candidates {
+		total += ep.score()
+	}
+	if total <= 0 {
+		return candidates[0]
+	}
+	r := rand.Float64() * total
+	for _, ep := range candidates {
+		if r -= ep.score(); r < 0 {
+			return ep
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// fallback moves the server of t to the next protocol and returns true if the protocol
+// is not tried yet since the last working one.
+func (p *serverPool) fallback(t *loginTarget) bool {
+	ep := t.ep
+	if len(ep.protocols) <= 1 || t.protocolIdx != ep.protocolIdx {
+		return false
+	}
+	ep.protocolIdx = (ep.protocolIdx + 1) % len(ep.protocols)
+	ep.protocolFailures++
+	if ep.protocolFailures < len(ep.protocols) {
+		p.pending = ep
+		return true
+	}
+	// all protocols failed, retry from the last working one after backoff
+	ep.protocolFailures = 0
+	return false
+}
+
+// reportFailure records a failed login. If the connection to the server can't be
+// established with the protocol, the next protocol is tried before the server backs off.
+func (p *serverPool) reportFailure(t *loginTarget, err error, transportErr bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	ep := t.ep
+	ep.totalFailures++
+	ep.lastErr = err.Error()
+	ep.lastFailureAt = now
+	if transportErr && p.fallback(t) {
+		return
+	}
+
+	if p.pending == ep {
+		p.pending = nil
+	}
+	ep.consecutiveFailures++
+	backoff := serverBackoffBase << min(ep.consecutiveFailures-1, 6)
+	ep.retryAfter = now.Add(min(backoff, serverBackoffMax))
+}
+
+// reportControlClosed records that the current control is closed. After repeated
+// heartbeat timeouts, the next protocol of the server is tried.
+func (p *serverPool) reportControlClosed(heartbeatTimedOut bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep := p.active
+	if ep == nil {
+		return
+	}
+	if !heartbeatTimedOut {
+		ep.heartbeatTimeouts = 0
+		return
+	}
+	ep.heartbeatTimeouts++
+	if ep.heartbeatTimeouts >= maxHeartbeatTimeouts {
+		ep.heartbeatTimeouts = 0
+		p.fallback(ep.target(p.activeIdx))
+	}
+}
+
+// activate marks t as the server and protocol of the new control. It returns the
+// failover record if the control is moved from another server.
+func (p *serverPool) activate(t *loginTarget) (FailoverRecord, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep := t.ep
+	ep.protocolIdx = t.protocolIdx
+	ep.protocolFailures = 0
+	ep.consecutiveFailures = 0
+	ep.totalSuccesses++
+	ep.lastSuccessAt = time.Now()
+	ep.retryAfter = time.Time{}
+
+	prev := p.active
+	p.active, p.activeIdx = ep, t.protocolIdx
+	if prev == nil || prev == ep {
+		return FailoverRecord{}, false
+	}
+	record := FailoverRecord{
+		Time:   ep.lastSuccessAt,
+		From:   prev.name,
+		To:     ep.name,
+		Reason: prev.lastErr,
+	}
+	p.history = append(p.history, record)
+	if len(p.history) > maxFailoverHistory {
+		p.history = p.history[len(p.history)-maxFailoverHistory:]
+	}
+	return record, true
+}
+
+// probeTargets returns the protocols of the active server which are preferred to the
+// current one, the most preferred first.
+func (p *serverPool) probeTargets() []*loginTarget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep := p.active
+	if ep == nil {
+		return nil
+	}
+	targets := make([]*loginTarget, 0, ep.protocolIdx)
+	for i := 0; i < ep.protocolIdx; i++ {
+		targets = append(targets, ep.target(i))
+	}
+	return targets
+}
+
+// probeInterval returns the protocol probe interval of the active server.
+func (p *serverPool) probeInterval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active == nil {
+		return 0
+	}
+	return time.Duration(p.active.commons[0].Transport.ProtocolProbeInterval) * time.Second
+}
+
+// switchProtocol makes the active server login with the protocol of t next time. It
+// returns false if t is not preferred to the current protocol.
+func (p *serverPool) switchProtocol(t *loginTarget) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep := t.ep
+	if p.active != ep || t.protocolIdx >= ep.protocolIdx {
+		return false
+	}
+	ep.protocolIdx = t.protocolIdx
+	ep.protocolFailures = 0
+	p.pending = ep
+	return true
+}
+
+// allFailed returns true if every server failed on its last login attempt.
+func (p *serverPool) allFailed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ep := range p.endpoints {
+		if ep.consecutiveFailures == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *serverPool) status() ServersStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	s := ServersStatus{
+		Strategy:  p.strategy,
+		Servers:   make([]ServerStatus, 0, len(p.endpoints)),
+		Failovers: append([]FailoverRecord{}, p.history...),
+	}
+	if p.active != nil {
+		s.Active = p.active.name
+	}
+	for _, ep := range p.endpoints {
+		s.Servers = append(s.Servers, ServerStatus{
+			Name:                ep.name,
+			Addr:                ep.addr(),
+			Protocol:            ep.protocols[ep.protocolIdx],
+			Protocols:           slices.Clone(ep.protocols),
+			Weight:              ep.weight,
+			Active:              ep == p.active,
+			Healthy:             !now.Before(ep.retryAfter),
+			Score:               ep.score(),
+			ConsecutiveFailures: ep.consecutiveFailures,
+			TotalFailures:       ep.totalFailures,
+			TotalSuccesses:      ep.totalSuccesses,
+			LastError:           ep.lastErr,
+			LastFailureAt:       ep.lastFailureAt,
+			LastSuccessAt:       ep.lastSuccessAt,
+			RetryAfter:          ep.retryAfter,
+		})
+	}
+	return s
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/msg"
+)
+
+func newTestServersConfig(strategy string, servers ...v1.ServerEndpointConfig) *v1.ClientCommonConfig {
+	cfg := &v1.ClientCommonConfig{
+		Auth:           v1.AuthClientConfig{Token: "common"},
+		Servers:        servers,
+		ServerStrategy: strategy,
+	}
+	cfg.Complete()
+	return cfg
+}
+
+func TestServerPoolOrdered(t *testing.T) {
+	require := require.New(t)
+	p := newServerPool(newTestServersConfig("ordered",
+		v1.ServerEndpointConfig{Name: "primary", ServerAddr: "10.0.0.1"},
+		v1.ServerEndpointConfig{ServerAddr: "10.0.0.2", Transport: &v1.ClientTransportConfig{Protocol: "quic"}},
+	))
+
+	primary, secondary := p.endpoints[0], p.endpoints[1]
+	require.Equal("10.0.0.2:7000", secondary.name)
+	require.Equal("tcp", primary.commons[0].Transport.Protocol)
+	require.Equal("quic", secondary.commons[0].Transport.Protocol)
+	require.Equal("common", secondary.commons[0].Auth.Token)
+
+	require.Same(primary, p.next().ep)
+	p.activate(primary.target(0))
+
+	// primary is skipped while backing off
+	p.reportFailure(primary.target(0), errors.New("connection refused"), true)
+	require.Same(secondary, p.next().ep)
+	require.False(p.allFailed())
+
+	p.reportFailure(secondary.target(0), errors.New("timeout"), true)
+	require.True(p.allFailed())
+	// all servers are backing off, the one recovers first is chosen
+	require.Same(primary, p.next().ep)
+
+	record, ok := p.activate(secondary.target(0))
+	require.True(ok)
+	require.Equal("primary", record.From)
+	require.Equal("10.0.0.2:7000", record.To)
+	require.Equal("connection refused", record.Reason)
+
+	st := p.status()
+	require.Equal("10.0.0.2:7000", st.Active)
+	require.Len(st.Failovers, 1)
+	require.False(st.Servers[0].Healthy)
+	require.Equal(1, st.Servers[0].ConsecutiveFailures)
+	require.True(st.Servers[1].Active)
+	require.Equal(0, st.Servers[1].ConsecutiveFailures)
+	require.EqualValues(1, st.Servers[1].TotalFailures)
+
+	_, ok = p.activate(secondary.target(0))
+	require.False(ok)
+}
+
+func TestServerPoolProtocolFallback(t *testing.T) {
+	require := require.New(t)
+	p := newServerPool(newTestServersConfig("ordered",
+		v1.ServerEndpointConfig{Name: "primary", ServerAddr: "10.0.0.1", Transport: &v1.ClientTransportConfig{
+			Protocols: []string{"quic", "tcp", "wss"},
+		}},
+		v1.ServerEndpointConfig{Name: "backup", ServerAddr: "10.0.0.2"},
+	))
+	primary := p.endpoints[0]
+
+	// transport errors fall back through the protocols of the same server
+	target := p.next()
+	require.Equal("quic", target.protocol())
+	p.reportFailure(target, errors.New("quic blocked"), true)
+	target = p.next()
+	require.Same(primary, target.ep)
+	require.Equal("tcp", target.protocol())
+	require.Equal(0, primary.consecutiveFailures)
+
+	// rejected by the server, no fallback
+	p.reportFailure(target, errors.New("token mismatch"), false)
+	require.Equal(1, primary.consecutiveFailures)
+	require.Equal("backup", p.next().ep.name)
+
+	// the last working protocol is remembered
+	primary.retryAfter = time.Time{}
+	target = p.next()
+	require.Equal("tcp", target.protocol())
+	p.activate(target)
+	require.Equal("tcp", p.status().Servers[0].Protocol)
+
+	// repeated heartbeat timeouts fall back to the next protocol
+	p.reportControlClosed(true)
+	require.Equal("tcp", p.next().protocol())
+	p.reportControlClosed(true)
+	target = p.next()
+	require.Equal("wss", target.protocol())
+	p.activate(target)
+
+	// quic and tcp are probed in order
+	probes := p.probeTargets()
+	require.Len(probes, 2)
+	require.Equal("quic", probes[0].protocol())
+	require.Equal("tcp", probes[1].protocol())
+	require.True(p.switchProtocol(probes[1]))
+	require.False(p.switchProtocol(probes[1]))
+	require.Equal("tcp", p.next().protocol())
+
+	// all protocols failed, the server backs off
+	target = primary.target(primary.protocolIdx)
+	for i := 0; i < 3; i++ {
+		p.reportFailure(target, errors.New("timeout"), true)
+		target = primary.target(primary.protocolIdx)
+	}
+	require.Equal(1, primary.consecutiveFailures)
+	require.Equal("tcp", target.protocol())
+	require.Equal("backup", p.next().ep.name)
+}
+
+func TestServerPoolWeighted(t *testing.T) {
+	p := newServerPool(newTestServersConfig("weighted",
+		v1.ServerEndpointConfig{Name: "a", ServerAddr: "10.0.0.1", Weight: 3},
+		v1.ServerEndpointConfig{Name: "b", ServerAddr: "10.0.0.2"},
+	))
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[p.next().ep.name]++
+	}
+	require.InDelta(t, 3000, counts["a"], 200)
+	require.InDelta(t, 1000, counts["b"], 200)
+}
+
+// fakeConnector returns connections to a fake frps which accepts the login.
+type fakeConnector struct {
+	openErr error
+}
+
+func (c *fakeConnector) Open() error { return c.openErr }
+
+func (c *fakeConnector) Connect() (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if _, err := msg.ReadMsg(server); err != nil {
+			return
+		}
+		if err := msg.WriteMsg(server, &msg.LoginResp{RunID: "fake"}); err != nil {
+			return
+		}
+		_, _ = io.Copy(io.Discard, server)
+	}()
+	return client, nil
+}
+
+func (c *fakeConnector) Close() error { return nil }
+
+func TestServiceLoginFailover(t *testing.T) {
+	require := require.New(t)
+	svr, err := NewService(ServiceOptions{
+		Common: &v1.ClientCommonConfig{
+			Servers: []v1.ServerEndpointConfig{
+				{Name: "primary", ServerAddr: "10.0.0.1"},
+				{Name: "backup", ServerAddr: "10.0.0.2", Auth: &v1.AuthClientConfig{Token: "backup"}},
+			},
+			LoginFailExit: lo.ToPtr(true),
+		},
+		ConnectorCreator: func(_ context.Context, cfg *v1.ClientCommonConfig) Connector {
+			if cfg.ServerAddr == "10.0.0.1" {
+				return &fakeConnector{openErr: errors.New("connection refused")}
+			}
+			return &fakeConnector{}
+		},
+	})
+	require.NoError(err)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	svr.ctx, svr.cancel = ctx, cancel
+	defer svr.Close()
+
+	// loginFailExit doesn't exit until all servers failed
+	done := make(chan struct{})
+	go func() {
+		svr.loopLoginUntilSuccess(time.Second, true)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("login timeout")
+	}
+	require.NoError(context.Cause(ctx))
+
+	require.NotNil(svr.ctl)
+	require.Equal("10.0.0.2", svr.ctl.sessionCtx.Common.ServerAddr)
+	require.Equal("backup", svr.ctl.sessionCtx.Common.Auth.Token)
+
+	st := svr.ServersStatus()
+	require.Equal("backup", st.Active)
+	require.Equal("connection refused", st.Servers[0].LastError)
+}
+
+func TestServiceProtocolProbe(t *testing.T) {
+	require := require.New(t)
+	var quicOK atomic.Bool
+	svr, err := NewService(ServiceOptions{
+		Common: &v1.ClientCommonConfig{
+			ServerAddr: "10.0.0.1",
+			Transport: v1.ClientTransportConfig{
+				Protocols:             []string{"quic", "tcp"},
+				ProtocolProbeInterval: 1,
+			},
+		},
+		ConnectorCreator: func(_ context.Context, cfg *v1.ClientCommonConfig) Connector {
+			if cfg.Transport.Protocol == "quic" && !quicOK.Load() {
+				return &fakeConnector{openErr: errors.New("quic blocked")}
+			}
+			return &fakeConnector{}
+		},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	currentProtocol := func() string {
+		svr.ctlMu.RLock()
+		defer svr.ctlMu.RUnlock()
+		if svr.ctl == nil {
+			return ""
+		}
+		return svr.ctl.sessionCtx.Common.Transport.Protocol
+	}
+	require.Eventually(func() bool { return currentProtocol() == "tcp" }, 5*time.Second, 50*time.Millisecond)
+	require.Equal("tcp", svr.ServersStatus().Servers[0].Protocol)
+
+	// quic works again, the control reconnects with it after probing
+	quicOK.Store(true)
+	require.Eventually(func() bool { return currentProtocol() == "quic" }, 5*time.Second, 50*time.Millisecond)
+	require.Equal("quic", svr.ServersStatus().Servers[0].Protocol)
+}
@@ -16,21 +16,28 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"os"
 	"runtime"
+	"slices"
 	"time"
 
 	"github.com/fatedier/golib/crypto"
 	"github.com/samber/lo"
 	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"frpgo/client/inspect"
+	"frpgo/client/metrics"
 	"frpgo/client/proxy"
+	"frpgo/client/tracing"
 	"frpgo/fmgr/webhook"
-	"frpgo/pkg/auth"
 	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/config/v1/validation"
 	"frpgo/pkg/msg"
 	httppkg "frpgo/pkg/util/http"
 	"frpgo/pkg/util/log"
@@ -40,6 +47,17 @@ import (
 	"frpgo/pkg/util/xlog"
 )
 
+var (
+	// ErrControlNotReady is returned when no control connection to frps has been established yet.
+	ErrControlNotReady = errors.New("control is not ready, not logged in to server")
+
+	// ErrInvalidConfig is returned when a proxy or visitor created at runtime has an invalid config.
+	ErrInvalidConfig = errors.New("invalid config")
+
+	ErrVisitorExist    = errors.New("visitor is already exist")
+	ErrVisitorNotFound = errors.New("visitor not found")
+)
+
 func init() {
 	crypto.DefaultSalt = "frp"
 	// Disable quic-go's receive buffer warning.
@@ -67,8 +85,8 @@ func NewService(options ServiceOptions) (*Service, error) {
 	}
 
 	s := &Service{
-		ctx:        context.Background(),
-		authSetter: auth.NewAuthSetter(options.Common.Auth),
+		ctx:     context.Background(),
+		servers: newServerPool(options.Common),
 
 		webServer:      webServer,
 		common:         options.Common,
@@ -77,9 +95,14 @@ func NewService(options ServiceOptions) (*Service, error) {
 		visitorCfgs:    options.VisitorCfgs,
 		clientSpec:     options.ClientSpec,
 
-		connectorCreator: options.ConnectorCreator,
-		handleWorkConnCb: options.HandleWorkConnCb,
+		connectorCreator:    options.ConnectorCreator,
+		handleWorkConnCb:    options.HandleWorkConnCb,
+		onRuntimeCfgsChange: options.OnRuntimeCfgsChange,
+		inspector:           options.Inspector,
+		proxyStats:          proxy.NewStatsRegistry(),
+		pausedProxies:       make(map[string]struct{}),
 	}
+	s.runtimeProxyCfgs, s.runtimeVisitorCfgs = s.filterRuntimeCfgs(options.RuntimeProxyCfgs, options.RuntimeVisitorCfgs)
 
 	if webServer != nil {
 		webServer.RouteRegister(s.registerRouteHandlers)
@@ -109,15 +132,23 @@ func (svr *Service) Run(ctx context.Context) error {
 		}()
 	}
 
+	svr.cfgMu.RLock()
+	proxyCfgs := svr.proxyCfgs
+	svr.cfgMu.RUnlock()
+	svr.pushProxyCreated(proxyCfgs)
+
 	// first login to frps
+	svr.connStatus.setState(StateConnecting)
 	svr.loopLoginUntilSuccess(10*time.Second, lo.FromPtr(svr.common.LoginFailExit))
 	if svr.ctl == nil {
+		svr.connStatus.setState(StateStopped)
 		cancelCause := cancelErr{}
 		_ = errors.As(context.Cause(svr.ctx), &cancelCause)
 		return fmt.Errorf("login to the server failed: %v. With loginFailExit enabled, no additional retries will be attempted", cancelCause.Err)
 	}
 
 	go svr.keepControllerWorking()
+	go svr.keepProtocolProbing()
 
 	<-svr.ctx.Done()
 	svr.stop()
@@ -125,7 +156,11 @@ func (svr *Service) Run(ctx context.Context) error {
 }
 
 func (svr *Service) keepControllerWorking() {
-	<-svr.ctl.Done()
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	<-ctl.Done()
+	svr.controlClosed(ctl)
 
 	// There is a situation where the login is successful but due to certain reasons,
 	// the control immediately exits. It is necessary to limit the frequency of reconnection in this case.
@@ -135,8 +170,13 @@ func (svr *Service) keepControllerWorking() {
 		// loopLoginUntilSuccess is another layer of loop that will continuously attempt to
 		// login to the server until successful.
 		svr.loopLoginUntilSuccess(20*time.Second, false)
-		if svr.ctl != nil {
-			<-svr.ctl.Done()
+		svr.ctlMu.RLock()
+		ctl := svr.ctl
+		svr.ctlMu.RUnlock()
+		if ctl != nil {
+			svr.pushControlEvent(webhook.EventControlReconnected, nil)
+			<-ctl.Done()
+			svr.controlClosed(ctl)
 			return false, errors.New("control is closed and try another loop")
 		}
 		// If the control is nil, it means that the login failed and the service is also closed.
@@ -155,14 +195,102 @@ func (svr *Service) keepControllerWorking() {
 	), true, svr.ctx.Done())
 }
 
+// controlClosed is called after ctl is closed, a new control will be logged in later.
+func (svr *Service) controlClosed(ctl *Control) {
+	reason := "control connection closed"
+	if ctl.HeartbeatTimedOut() {
+		reason = "heartbeat timeout"
+	}
+	svr.connStatus.controlClosed(reason)
+	svr.servers.reportControlClosed(ctl.HeartbeatTimedOut())
+	metrics.Client.ControlConnected(false)
+	svr.pushControlEvent(webhook.EventControlClosed, nil)
+}
+
+// keepProtocolProbing probes the protocols preferred to the current one periodically after
+// falling back. If one of them works again, the control reconnects with it.
+func (svr *Service) keepProtocolProbing() {
+	xl := xlog.FromContextSafe(svr.ctx)
+	for {
+		interval := svr.servers.probeInterval()
+		if interval <= 0 {
+			// disabled or not logged in yet, check again later
+			interval = time.Minute
+		}
+		select {
+		case <-svr.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if svr.servers.probeInterval() <= 0 {
+			continue
+		}
+
+		for _, t := range svr.servers.probeTargets() {
+			if err := svr.probe(t); err != nil {
+				xl.Debugf("probe server [%s] with protocol [%s] error: %v", t.ep.name, t.protocol(), err)
+				continue
+			}
+			if !svr.servers.switchProtocol(t) {
+				break
+			}
+			xl.Infof("protocol [%s] of server [%s] works again, reconnect with it", t.protocol(), t.ep.name)
+			svr.ctlMu.RLock()
+			ctl := svr.ctl
+			svr.ctlMu.RUnlock()
+			if ctl != nil {
+				ctl.Close()
+			}
+			break
+		}
+	}
+}
+
+// probe checks if a connection to the server can be established with the protocol of t.
+func (svr *Service) probe(t *loginTarget) error {
+	connector := svr.connectorCreator(svr.ctx, t.common)
+	defer connector.Close()
+	if err := connector.Open(); err != nil {
+		return err
+	}
+	conn, err := connector.Connect()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
 // login creates a connection to frps and registers it self as a client
 // conn: control connection
 // session: if it's not nil, using tcp mux
-func (svr *Service) login() (conn net.Conn, connector Connector, err error) {
-	xl := xlog.FromContextSafe(svr.ctx)
-	connector = svr.connectorCreator(svr.ctx, svr.common)
-	if err = connector.Open(); err != nil {
-		return nil, nil, err
+// t: the server and transport protocol logged in with, chosen from the configured servers
+func (svr *Service) login() (conn net.Conn, connector Connector, t *loginTarget, err error) {
+	t = svr.servers.next()
+	ctx, span := tracing.Start(svr.ctx, "control.login", trace.WithAttributes(
+		attribute.String("server.name", t.ep.name),
+		attribute.String("server.addr", t.ep.addr()),
+		attribute.String("transport.protocol", t.protocol()),
+	))
+	xl := xlog.FromContextSafe(ctx)
+	// the connection can't be established or is broken, rather than rejected by the server
+	transportErr := true
+	defer func() {
+		tracing.End(span, err)
+		metrics.Client.Login(err)
+		if err != nil {
+			svr.servers.reportFailure(t, err, transportErr)
+			svr.pushControlEvent(webhook.EventControlLoginFailed, err)
+		} else {
+			svr.pushControlEvent(webhook.EventControlLogin, nil)
+		}
+	}()
+
+	connector = svr.connectorCreator(svr.ctx, t.common)
+	_, openSpan := tracing.Start(ctx, "connector.open")
+	err = connector.Open()
+	tracing.End(openSpan, err)
+	if err != nil {
+		return nil, nil, t, err
 	}
 
 	defer func() {
@@ -171,7 +299,9 @@ func (svr *Service) login() (conn net.Conn, connector Connector, err error) {
 		}
 	}()
 
+	_, connectSpan := tracing.Start(ctx, "connector.connect")
 	conn, err = connector.Connect()
+	tracing.End(connectSpan, err)
 	if err != nil {
 		return
 	}
@@ -179,7 +309,7 @@ func (svr *Service) login() (conn net.Conn, connector Connector, err error) {
 	loginMsg := &msg.Login{
 		Arch:      runtime.GOARCH,
 		Os:        runtime.GOOS,
-		PoolCount: svr.common.Transport.PoolCount,
+		PoolCount: t.common.Transport.PoolCount,
 		User:      svr.common.User,
 		Version:   version.Full(),
 		Timestamp: time.Now().Unix(),
@@ -191,7 +321,8 @@ func (svr *Service) login() (conn net.Conn, connector Connector, err error) {
 	}
 
 	// Add auth
-	if err = svr.authSetter.SetLogin(loginMsg); err != nil {
+	if err = t.ep.authSetter.SetLogin(loginMsg); err != nil {
+		transportErr = false
 		return
 	}
 
@@ -207,55 +338,71 @@ func (svr *Service) login() (conn net.Conn, connector Connector, err error) {
 	_ = conn.SetReadDeadline(time.Time{})
 
 	if loginRespMsg.Error != "" {
+		transportErr = false
 		err = fmt.Errorf("%s", loginRespMsg.Error)
 		xl.Errorf("%s", loginRespMsg.Error)
 		return
 	}
 
 	svr.runID = loginRespMsg.RunID
-	xl.AddPrefix(xlog.LogPrefix{Name: "runID", Value: svr.runID})
+	span.SetAttributes(attribute.String("run_id", svr.runID))
+	xlog.FromContextSafe(svr.ctx).AddPrefix(xlog.LogPrefix{Name: "runID", Value: svr.runID})
 
-	xl.Infof("login to server success, get run id [%s]", loginRespMsg.RunID)
+	xl.Infof("login to server [%s] with protocol [%s] success, get run id [%s]", t.ep.name, t.protocol(), loginRespMsg.RunID)
 	return
 }
 
+func (svr *Service) pushControlEvent(typ webhook.EventType, err error) {
+	e := webhook.NewEvent(typ, svr.runID)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	webhook.Push(e)
+}
+
 func (svr *Service) loopLoginUntilSuccess(maxInterval time.Duration, firstLoginExit bool) {
 	xl := xlog.FromContextSafe(svr.ctx)
 
 	loginFunc := func() (bool, error) {
 		xl.Infof("try to connect to server...")
-		conn, connector, err := svr.login()
+		conn, connector, t, err := svr.login()
 		if err != nil {
-			xl.Warnf("connect to server error: %v", err)
-			if firstLoginExit {
+			xl.Warnf("connect to server [%s] with protocol [%s] error: %v", t.ep.name, t.protocol(), err)
+			svr.connStatus.recordError(err)
+			// with multiple servers, exit only after all of them failed
+			if firstLoginExit && svr.servers.allFailed() {
 				svr.cancel(cancelErr{Err: err})
 			}
 			return false, err
 		}
 
 		svr.cfgMu.RLock()
-		proxyCfgs := svr.proxyCfgs
-		visitorCfgs := svr.visitorCfgs
+		proxyCfgs := svr.allProxyCfgs()
+		visitorCfgs := svr.allVisitorCfgs()
 		svr.cfgMu.RUnlock()
 		connEncrypted := true
 		if svr.clientSpec != nil && svr.clientSpec.Type == "ssh-tunnel" {
 			connEncrypted = false
 		}
 		sessionCtx := &SessionContext{
-			Common:        svr.common,
+			Common:        t.common,
 			RunID:         svr.runID,
 			Conn:          conn,
 			ConnEncrypted: connEncrypted,
-			AuthSetter:    svr.authSetter,
+			AuthSetter:    t.ep.authSetter,
 			Connector:     connector,
 		}
 		ctl, err := NewControl(svr.ctx, sessionCtx)
 		if err != nil {
 			conn.Close()
 			xl.Errorf("NewControl error: %v", err)
+			svr.connStatus.recordError(err)
 			return false, err
 		}
-		ctl.SetInWorkConnCallback(svr.handleWorkConnCb)
+		ctl.SetInWorkConnCallback(svr.inWorkConnCallback(t.common))
+		ctl.SetInspector(svr.inspector)
+		ctl.SetStatsRegistry(svr.proxyStats)
+		ctl.SetPausedFunc(svr.isProxyPaused)
 
 		ctl.Run(proxyCfgs, visitorCfgs)
 		// close and replace previous control
@@ -265,6 +412,17 @@ func (svr *Service) loopLoginUntilSuccess(maxInterval time.Duration, firstLoginE
 		}
 		svr.ctl = ctl
 		svr.ctlMu.Unlock()
+		metrics.Client.ControlConnected(true)
+		svr.connStatus.loggedIn(t, sessionCtx.RunID)
+
+		// all proxies and visitors are registered on the new control by ctl.Run
+		if record, ok := svr.servers.activate(t); ok {
+			xl.Infof("failover from server [%s] to [%s]", record.From, record.To)
+			e := webhook.NewEvent(webhook.EventControlFailover, svr.runID)
+			e.Error = record.Reason
+			e.Detail = record
+			webhook.Push(e)
+		}
 
 		return true, nil
 	}
@@ -279,21 +437,134 @@ func (svr *Service) loopLoginUntilSuccess(maxInterval time.Duration, firstLoginE
 		}), true, svr.ctx.Done())
 }
 
+// UpdateAllConfigurer replaces the proxies and visitors loaded from the config file.
+// Proxies created at runtime by CreateProxy are kept, unless one of the new proxies
+// uses the same name.
 func (svr *Service) UpdateAllConfigurer(proxyCfgs []v1.ProxyConfigurer, visitorCfgs []v1.VisitorConfigurer) error {
+	return svr.updateAllConfigurer(proxyCfgs, visitorCfgs, false)
+}
+
+// ResetAllConfigurer is the same as UpdateAllConfigurer, but also removes all proxies
+// created at runtime.
+func (svr *Service) ResetAllConfigurer(proxyCfgs []v1.ProxyConfigurer, visitorCfgs []v1.VisitorConfigurer) error {
+	return svr.updateAllConfigurer(proxyCfgs, visitorCfgs, true)
+}
+
+func (svr *Service) updateAllConfigurer(proxyCfgs []v1.ProxyConfigurer, visitorCfgs []v1.VisitorConfigurer, clearRuntime bool) error {
 	svr.cfgMu.Lock()
+	oldNames := lo.SliceToMap(svr.allProxyCfgs(), func(c v1.ProxyConfigurer) (string, struct{}) {
+		return c.GetBaseConfig().Name, struct{}{}
+	})
 	svr.proxyCfgs = proxyCfgs
 	svr.visitorCfgs = visitorCfgs
+	runtimeChanged := len(svr.runtimeProxyCfgs) + len(svr.runtimeVisitorCfgs)
+	if clearRuntime {
+		svr.runtimeProxyCfgs = nil
+		svr.runtimeVisitorCfgs = nil
+	} else {
+		svr.runtimeProxyCfgs, svr.runtimeVisitorCfgs = svr.filterRuntimeCfgs(svr.runtimeProxyCfgs, svr.runtimeVisitorCfgs)
+	}
+	runtimeChanged -= len(svr.runtimeProxyCfgs) + len(svr.runtimeVisitorCfgs)
+	allProxyCfgs := svr.allProxyCfgs()
+	allVisitorCfgs := svr.allVisitorCfgs()
+	// forget paused proxies which are removed
+	for name := range svr.pausedProxies {
+		if !lo.ContainsBy(allProxyCfgs, func(c v1.ProxyConfigurer) bool { return c.GetBaseConfig().Name == name }) {
+			delete(svr.pausedProxies, name)
+		}
+	}
 	svr.cfgMu.Unlock()
 
+	// drop stats after removed proxies are closed
+	defer svr.proxyStats.Retain(lo.Map(allProxyCfgs, func(c v1.ProxyConfigurer, _ int) string {
+		return c.GetBaseConfig().Name
+	}))
+	if runtimeChanged != 0 {
+		svr.notifyRuntimeCfgsChange()
+	}
+
 	svr.ctlMu.RLock()
 	ctl := svr.ctl
 	svr.ctlMu.RUnlock()
 
+	var err error
 	if ctl != nil {
-		return svr.ctl.UpdateAllConfigurer(proxyCfgs, visitorCfgs)
+		err = ctl.UpdateAllConfigurer(allProxyCfgs, allVisitorCfgs)
 	}
+	svr.pushProxyCreated(lo.Filter(allProxyCfgs, func(c v1.ProxyConfigurer, _ int) bool {
+		_, ok := oldNames[c.GetBaseConfig().Name]
+		return !ok
+	}))
+	return err
+}
 
-	return nil
+// filterRuntimeCfgs drops runtime configurers which have the same name with the ones
+// in config file, config file always wins.
+// cfgMu must be held by the caller.
+func (svr *Service) filterRuntimeCfgs(
+	proxyCfgs []v1.ProxyConfigurer,
+	visitorCfgs []v1.VisitorConfigurer,
+) ([]v1.ProxyConfigurer, []v1.VisitorConfigurer) {
+	xl := xlog.FromContextSafe(svr.ctx)
+
+	proxyNames := lo.SliceToMap(svr.proxyCfgs, func(c v1.ProxyConfigurer) (string, struct{}) {
+		return c.GetBaseConfig().Name, struct{}{}
+	})
+	proxyCfgs = lo.Filter(proxyCfgs, func(c v1.ProxyConfigurer, _ int) bool {
+		if _, ok := proxyNames[c.GetBaseConfig().Name]; ok {
+			xl.Warnf("runtime proxy [%s] is replaced by the one in config file", c.GetBaseConfig().Name)
+			return false
+		}
+		return true
+	})
+
+	visitorNames := lo.SliceToMap(svr.visitorCfgs, func(c v1.VisitorConfigurer) (string, struct{}) {
+		return c.GetBaseConfig().Name, struct{}{}
+	})
+	visitorCfgs = lo.Filter(visitorCfgs, func(c v1.VisitorConfigurer, _ int) bool {
+		if _, ok := visitorNames[c.GetBaseConfig().Name]; ok {
+			xl.Warnf("runtime visitor [%s] is replaced by the one in config file", c.GetBaseConfig().Name)
+			return false
+		}
+		return true
+	})
+	return proxyCfgs, visitorCfgs
+}
+
+// allProxyCfgs returns proxies from config file followed by proxies created at runtime.
+// cfgMu must be held by the caller.
+func (svr *Service) allProxyCfgs() []v1.ProxyConfigurer {
+	proxyCfgs := make([]v1.ProxyConfigurer, 0, len(svr.proxyCfgs)+len(svr.runtimeProxyCfgs))
+	proxyCfgs = append(proxyCfgs, svr.proxyCfgs...)
+	proxyCfgs = append(proxyCfgs, svr.runtimeProxyCfgs...)
+	return proxyCfgs
+}
+
+// allVisitorCfgs returns visitors from config file followed by visitors created at runtime.
+// cfgMu must be held by the caller.
+func (svr *Service) allVisitorCfgs() []v1.VisitorConfigurer {
+	visitorCfgs := make([]v1.VisitorConfigurer, 0, len(svr.visitorCfgs)+len(svr.runtimeVisitorCfgs))
+	visitorCfgs = append(visitorCfgs, svr.visitorCfgs...)
+	visitorCfgs = append(visitorCfgs, svr.runtimeVisitorCfgs...)
+	return visitorCfgs
+}
+
+// notifyRuntimeCfgsChange passes a snapshot of runtime configurers to onRuntimeCfgsChange.
+// Calls are serialized so the last call always sees the latest snapshot.
+func (svr *Service) notifyRuntimeCfgsChange() {
+	if svr.onRuntimeCfgsChange == nil {
+		return
+	}
+
+	svr.runtimeNotifyMu.Lock()
+	defer svr.runtimeNotifyMu.Unlock()
+
+	svr.cfgMu.RLock()
+	proxyCfgs := slices.Clone(svr.runtimeProxyCfgs)
+	visitorCfgs := slices.Clone(svr.runtimeVisitorCfgs)
+	svr.cfgMu.RUnlock()
+
+	svr.onRuntimeCfgsChange(proxyCfgs, visitorCfgs)
 }
 
 func (svr *Service) Close() {
@@ -306,12 +577,14 @@ func (svr *Service) GracefulClose(d time.Duration) {
 }
 
 func (svr *Service) stop() {
+	svr.connStatus.setState(StateStopped)
 	svr.ctlMu.Lock()
 	defer svr.ctlMu.Unlock()
 	if svr.ctl != nil {
 		svr.ctl.GracefulClose(svr.gracefulShutdownDuration)
 		svr.ctl = nil
 	}
+	svr.closeListeners()
 }
 
 func (svr *Service) getProxyStatus(name string) (*proxy.WorkingStatus, bool) {
@@ -325,6 +598,16 @@ func (svr *Service) getProxyStatus(name string) (*proxy.WorkingStatus, bool) {
 	return ctl.pm.GetProxyStatus(name)
 }
 
+// ServersStatus returns the health of configured servers and the failover history.
+func (svr *Service) ServersStatus() ServersStatus {
+	return svr.servers.status()
+}
+
+// Inspector returns the recorder of captured http requests.
+func (svr *Service) Inspector() *inspect.Recorder {
+	return svr.inspector
+}
+
 func (svr *Service) StatusExporter() StatusExporter {
 	return &statusExporterImpl{
 		getProxyStatusFunc: svr.getProxyStatus,
@@ -335,19 +618,365 @@ func (svr *Service) CreateProxy(proxyType string, name string, localIP string, l
 	logx.Debugf("CreateProxy proxyType: %v, name: %v, localIP: %v, localPort: %v, remotePort: %v",
 		proxyType, name, localIP, localPort, remotePort)
 
+	cfg, err := proxy.NewProxyConfigurer(proxyType, name, localIP, localPort, remotePort)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return svr.AddProxy(cfg)
+}
+
+// AddProxy validates the proxy config and starts it. The name is used as it is,
+// without the user prefix added to proxies in config file.
+func (svr *Service) AddProxy(cfg v1.ProxyConfigurer) error {
+	name := cfg.GetBaseConfig().Name
+	logx.Debugf("AddProxy name: %v, type: %v", name, cfg.GetBaseConfig().Type)
+
+	cfg.Complete("")
+	if err := validation.ValidateProxyConfigurerForClient(cfg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil {
+		return ErrControlNotReady
+	}
+
 	// 若proxyName已存在，则不创建，通过webhook返回已有的代理详情
-	isExist := svr.ctl.pm.IsProxyExist(name)
-	if isExist {
-		return errors.New("proxy is already exist")
-	} else {
-		return svr.ctl.pm.CreateProxy(proxyType, name, localIP, localPort, remotePort)
+	if ctl.pm.IsProxyExist(name) {
+		return proxy.ErrProxyExist
+	}
+
+	// 记录到期望状态中，重连后会重新注册
+	svr.cfgMu.Lock()
+	exist := lo.ContainsBy(svr.allProxyCfgs(), func(c v1.ProxyConfigurer) bool {
+		return c.GetBaseConfig().Name == name
+	})
+	if !exist {
+		svr.runtimeProxyCfgs = append(svr.runtimeProxyCfgs, cfg)
+	}
+	svr.cfgMu.Unlock()
+	if exist {
+		return proxy.ErrProxyExist
+	}
+
+	if err := ctl.pm.AddProxy(cfg); err != nil {
+		svr.removeProxyCfg(name)
+		return err
+	}
+	svr.notifyRuntimeCfgsChange()
+	svr.pushProxyCreated([]v1.ProxyConfigurer{cfg})
+	return nil
+}
+
+// pushProxyCreated pushes proxy.created for proxies added by AddProxy or loaded from the config file.
+// Proxies registered again after reconnecting don't push it.
+func (svr *Service) pushProxyCreated(cfgs []v1.ProxyConfigurer) {
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+
+	runID := ""
+	if ctl != nil {
+		runID = ctl.sessionCtx.RunID
+	}
+	for _, cfg := range cfgs {
+		name := cfg.GetBaseConfig().Name
+		e := webhook.NewEvent(webhook.EventProxyCreated, runID)
+		e.ProxyName = name
+		e.ProxyType = cfg.GetBaseConfig().Type
+		if ctl != nil {
+			if detail, ok := ctl.pm.GetProxyDetail(name); ok {
+				e.Phase = detail.Status
+				e.Detail = detail
+			}
+		}
+		webhook.Push(e)
 	}
 }
 
+// DeleteProxy stops the proxy and sends CloseProxy to frps.
+// The proxy is also removed from the desired state, so it won't come back after reconnecting.
+func (svr *Service) DeleteProxy(name string) error {
+	logx.Debugf("DeleteProxy name: %v", name)
+
+	removed := svr.removeProxyCfg(name)
+	if removed {
+		svr.notifyRuntimeCfgsChange()
+	}
+	// drop stats and captured requests after the proxy is closed,
+	// a proxy created later with the same name starts from scratch
+	defer func() {
+		svr.proxyStats.Remove(name)
+		svr.inspector.Clear(name)
+	}()
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	// without a control, proxies not in the desired state don't exist at all
+	if ctl == nil {
+		if removed {
+			return nil
+		}
+		return proxy.ErrProxyNotFound
+	}
+
+	err := ctl.pm.RemoveProxy(name)
+	if removed && errors.Is(err, proxy.ErrProxyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// removeProxyCfg removes the proxy from both config file proxies and runtime proxies.
+func (svr *Service) removeProxyCfg(name string) bool {
+	isTarget := func(c v1.ProxyConfigurer, _ int) bool {
+		return c.GetBaseConfig().Name == name
+	}
+
+	svr.cfgMu.Lock()
+	defer svr.cfgMu.Unlock()
+	n := len(svr.proxyCfgs) + len(svr.runtimeProxyCfgs)
+	svr.proxyCfgs = lo.Reject(svr.proxyCfgs, isTarget)
+	svr.runtimeProxyCfgs = lo.Reject(svr.runtimeProxyCfgs, isTarget)
+	delete(svr.pausedProxies, name)
+	return n != len(svr.proxyCfgs)+len(svr.runtimeProxyCfgs)
+}
+
+// UpdateProxy applies update to a copy of the proxy config and replaces the running proxy with it.
+// Changes of client side settings are applied in place, others close the proxy on frps and
+// register it again, the returned mode tells which one is taken. Name and type can't be changed.
+func (svr *Service) UpdateProxy(name string, update func(v1.ProxyConfigurer) error) (proxy.UpdateMode, error) {
+	logx.Debugf("UpdateProxy name: %v", name)
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil {
+		return "", ErrControlNotReady
+	}
+
+	svr.cfgMu.RLock()
+	oldCfg, ok := lo.Find(svr.allProxyCfgs(), func(c v1.ProxyConfigurer) bool {
+		return c.GetBaseConfig().Name == name
+	})
+	svr.cfgMu.RUnlock()
+	if !ok {
+		return "", proxy.ErrProxyNotFound
+	}
+
+	cfg, err := cloneProxyConfigurer(oldCfg)
+	if err != nil {
+		return "", err
+	}
+	if err := update(cfg); err != nil {
+		return "", err
+	}
+	if cfg.GetBaseConfig().Name != name || cfg.GetBaseConfig().Type != oldCfg.GetBaseConfig().Type {
+		return "", fmt.Errorf("%w: name and type of proxy can't be changed", ErrInvalidConfig)
+	}
+	cfg.Complete("")
+	if err := validation.ValidateProxyConfigurerForClient(cfg); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	if !svr.replaceProxyCfg(oldCfg, cfg) {
+		// changed by others meanwhile
+		return "", fmt.Errorf("proxy [%s] is changed during updating", name)
+	}
+	mode, err := ctl.pm.UpdateProxy(cfg)
+	if err != nil {
+		svr.replaceProxyCfg(cfg, oldCfg)
+		return "", err
+	}
+	svr.notifyRuntimeCfgsChange()
+	return mode, nil
+}
+
+// replaceProxyCfg replaces oldCfg with newCfg in config file proxies or runtime proxies.
+// It returns false if oldCfg is not found.
+func (svr *Service) replaceProxyCfg(oldCfg v1.ProxyConfigurer, newCfg v1.ProxyConfigurer) bool {
+	// copy on write, the slices may be shared with the caller of NewService and snapshots
+	replace := func(cfgs []v1.ProxyConfigurer) ([]v1.ProxyConfigurer, bool) {
+		i := slices.Index(cfgs, oldCfg)
+		if i < 0 {
+			return cfgs, false
+		}
+		cfgs = slices.Clone(cfgs)
+		cfgs[i] = newCfg
+		return cfgs, true
+	}
+
+	svr.cfgMu.Lock()
+	defer svr.cfgMu.Unlock()
+	var ok bool
+	if svr.proxyCfgs, ok = replace(svr.proxyCfgs); ok {
+		return true
+	}
+	svr.runtimeProxyCfgs, ok = replace(svr.runtimeProxyCfgs)
+	return ok
+}
+
+// cloneProxyConfigurer deep copies cfg through its json form.
+func cloneProxyConfigurer(cfg v1.ProxyConfigurer) (v1.ProxyConfigurer, error) {
+	b, err := json.Marshal(&v1.TypedProxyConfig{Type: cfg.GetBaseConfig().Type, ProxyConfigurer: cfg})
+	if err != nil {
+		return nil, err
+	}
+	var out v1.TypedProxyConfig
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out.ProxyConfigurer, nil
+}
+
+// PauseProxy closes the proxy on frps without removing it. It keeps its config, stats
+// and captured requests, and stays paused after reconnecting until ResumeProxy is called.
+func (svr *Service) PauseProxy(name string) error {
+	logx.Debugf("PauseProxy name: %v", name)
+	return svr.setProxyPaused(name, true)
+}
+
+// ResumeProxy registers a proxy paused by PauseProxy on frps again.
+func (svr *Service) ResumeProxy(name string) error {
+	logx.Debugf("ResumeProxy name: %v", name)
+	return svr.setProxyPaused(name, false)
+}
+
+func (svr *Service) setProxyPaused(name string, paused bool) error {
+	svr.cfgMu.Lock()
+	exist := lo.ContainsBy(svr.allProxyCfgs(), func(c v1.ProxyConfigurer) bool {
+		return c.GetBaseConfig().Name == name
+	})
+	if exist {
+		if paused {
+			svr.pausedProxies[name] = struct{}{}
+		} else {
+			delete(svr.pausedProxies, name)
+		}
+	}
+	svr.cfgMu.Unlock()
+	if !exist {
+		return proxy.ErrProxyNotFound
+	}
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil {
+		// applied when the next control is logged in
+		return nil
+	}
+	if paused {
+		return ctl.pm.PauseProxy(name)
+	}
+	return ctl.pm.ResumeProxy(name)
+}
+
+// isProxyPaused reports if the proxy is paused by PauseProxy.
+func (svr *Service) isProxyPaused(name string) bool {
+	svr.cfgMu.RLock()
+	defer svr.cfgMu.RUnlock()
+	_, ok := svr.pausedProxies[name]
+	return ok
+}
+
+// CreateVisitor adds a visitor at runtime. Like CreateProxy, it is kept across
+// reconnects and config reloads.
+func (svr *Service) CreateVisitor(cfg v1.VisitorConfigurer) error {
+	name := cfg.GetBaseConfig().Name
+	logx.Debugf("CreateVisitor name: %v, type: %v", name, cfg.GetBaseConfig().Type)
+
+	cfg.Complete(svr.common)
+	if err := validation.ValidateVisitorConfigurer(cfg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	svr.cfgMu.Lock()
+	exist := lo.ContainsBy(svr.allVisitorCfgs(), func(c v1.VisitorConfigurer) bool {
+		return c.GetBaseConfig().Name == name
+	})
+	if !exist {
+		svr.runtimeVisitorCfgs = append(svr.runtimeVisitorCfgs, cfg)
+	}
+	visitorCfgs := svr.allVisitorCfgs()
+	svr.cfgMu.Unlock()
+	if exist {
+		return ErrVisitorExist
+	}
+	svr.notifyRuntimeCfgsChange()
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl != nil {
+		ctl.vm.UpdateAll(visitorCfgs)
+	}
+	return nil
+}
+
+// DeleteVisitor removes a visitor from both config file visitors and runtime visitors.
+func (svr *Service) DeleteVisitor(name string) error {
+	logx.Debugf("DeleteVisitor name: %v", name)
+
+	isTarget := func(c v1.VisitorConfigurer, _ int) bool {
+		return c.GetBaseConfig().Name == name
+	}
+
+	svr.cfgMu.Lock()
+	n := len(svr.visitorCfgs) + len(svr.runtimeVisitorCfgs)
+	svr.visitorCfgs = lo.Reject(svr.visitorCfgs, isTarget)
+	svr.runtimeVisitorCfgs = lo.Reject(svr.runtimeVisitorCfgs, isTarget)
+	removed := n != len(svr.visitorCfgs)+len(svr.runtimeVisitorCfgs)
+	visitorCfgs := svr.allVisitorCfgs()
+	svr.cfgMu.Unlock()
+	if !removed {
+		return ErrVisitorNotFound
+	}
+	svr.notifyRuntimeCfgsChange()
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl != nil {
+		ctl.vm.UpdateAll(visitorCfgs)
+	}
+	return nil
+}
+
+// ReplayCapturedRequest 将捕获的请求按opts修改后重新发送到代理的本地服务，结果作为新的捕获记录
+func (svr *Service) ReplayCapturedRequest(ctx context.Context, name string, id string, opts inspect.ReplayOptions) (*inspect.Capture, error) {
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil {
+		return nil, ErrControlNotReady
+	}
+
+	return svr.inspector.Replay(ctx, name, id, opts, func() (net.Conn, error) {
+		return ctl.pm.DialLocal(name)
+	})
+}
+
 func (svr *Service) GetProxyDetail(name string) (*proxy.WorkingDetial, bool) {
-	proxyDetial, isSuccess := svr.ctl.pm.GetProxyDetail(name)
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil {
+		return nil, false
+	}
+
+	proxyDetial, isSuccess := ctl.pm.GetProxyDetail(name)
 	if isSuccess {
-		webhook.PushProxyDetail(proxyDetial)
+		proxyDetial.Origin = svr.proxyOrigins()[name]
+		e := webhook.NewEvent(webhook.EventProxyQueried, ctl.sessionCtx.RunID)
+		e.ProxyName = name
+		e.ProxyType = proxyDetial.Type
+		e.Phase = proxyDetial.Status
+		e.Detail = proxyDetial
+		webhook.Push(e)
 	}
 	return proxyDetial, isSuccess
 }
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"frpgo/client/inspect"
+	"frpgo/client/proxy"
+	v1 "frpgo/pkg/config/v1"
+)
+
+func proxyNames(cfgs []v1.ProxyConfigurer) []string {
+	return lo.Map(cfgs, func(c v1.ProxyConfigurer, _ int) string {
+		return c.GetBaseConfig().Name
+	})
+}
+
+func newTestProxyCfg(t *testing.T, name string) v1.ProxyConfigurer {
+	cfg, err := proxy.NewProxyConfigurer("tcp", name, "127.0.0.1", 22, 0)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestServiceKeepRuntimeProxies(t *testing.T) {
+	require := require.New(t)
+	svr, err := NewService(ServiceOptions{
+		Common:    &v1.ClientCommonConfig{},
+		ProxyCfgs: []v1.ProxyConfigurer{newTestProxyCfg(t, "file")},
+	})
+	require.NoError(err)
+	svr.runtimeProxyCfgs = []v1.ProxyConfigurer{newTestProxyCfg(t, "api-1"), newTestProxyCfg(t, "api-2")}
+	require.Equal([]string{"file", "api-1", "api-2"}, proxyNames(svr.allProxyCfgs()))
+
+	// reload keeps runtime proxies, but the one in config file wins on conflict
+	require.NoError(svr.UpdateAllConfigurer([]v1.ProxyConfigurer{newTestProxyCfg(t, "api-2")}, nil))
+	require.Equal([]string{"api-2", "api-1"}, proxyNames(svr.allProxyCfgs()))
+
+	require.NoError(svr.DeleteProxy("api-1"))
+	require.Equal([]string{"api-2"}, proxyNames(svr.allProxyCfgs()))
+	require.ErrorIs(svr.DeleteProxy("api-1"), proxy.ErrProxyNotFound)
+
+	svr.runtimeProxyCfgs = []v1.ProxyConfigurer{newTestProxyCfg(t, "api-3")}
+	require.NoError(svr.ResetAllConfigurer([]v1.ProxyConfigurer{newTestProxyCfg(t, "file")}, nil))
+	require.Equal([]string{"file"}, proxyNames(svr.allProxyCfgs()))
+}
+
+func TestServiceDeleteProxyClearsCaptures(t *testing.T) {
+	require := require.New(t)
+	svr, err := NewService(ServiceOptions{
+		Common:    &v1.ClientCommonConfig{},
+		ProxyCfgs: []v1.ProxyConfigurer{newTestProxyCfg(t, "web"), newTestProxyCfg(t, "api")},
+	})
+	require.NoError(err)
+	svr.Inspector().Record(&inspect.Capture{ID: "1", TunnelName: "web"})
+	svr.Inspector().Record(&inspect.Capture{ID: "2", TunnelName: "api"})
+
+	// captures are kept when the proxy is paused
+	require.NoError(svr.PauseProxy("web"))
+	require.Len(svr.Inspector().List("web", inspect.ListOptions{}), 1)
+
+	require.NoError(svr.DeleteProxy("web"))
+	require.Empty(svr.Inspector().List("web", inspect.ListOptions{}))
+	require.Len(svr.Inspector().List("api", inspect.ListOptions{}), 1)
+}
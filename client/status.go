@@ -0,0 +1,192 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Connection states of the service.
+const (
+	StateConnecting   = "connecting"
+	StateLoggedIn     = "logged-in"
+	StateReconnecting = "reconnecting"
+	StateStopped      = "stopped"
+)
+
+// number of recent heartbeats the RTT statistics are calculated from
+const rttWindowSize = 10
+
+// StreamCounter is implemented by connectors that multiplex connections to the server
+// over one underlying connection.
+type StreamCounter interface {
+	// NumStreams returns the number of open streams, or -1 if the connections are not multiplexed.
+	NumStreams() int
+}
+
+// HeartbeatRTT is the round trip time of the recent heartbeats in milliseconds.
+// It is only measured when heartbeat is enabled, see transport.heartbeatInterval.
+type HeartbeatRTT struct {
+	Last    float64 `json:"last_ms"`
+	Avg     float64 `json:"avg_ms"`
+	Min     float64 `json:"min_ms"`
+	Max     float64 `json:"max_ms"`
+	Samples int     `json:"samples"`
+}
+
+type ServiceStatus struct {
+	State      string `json:"state"`
+	RunID      string `json:"run_id"`
+	Server     string `json:"server"`
+	ServerAddr string `json:"server_addr"`
+	Protocol   string `json:"protocol"`
+	TCPMux     bool   `json:"tcp_mux"`
+	// open streams on the multiplexed connection, -1 if not multiplexed or unknown
+	Streams      int          `json:"streams"`
+	LoginAt      time.Time    `json:"login_at"`
+	Reconnects   int64        `json:"reconnects"`
+	LastError    string       `json:"last_error,omitempty"`
+	LastErrorAt  time.Time    `json:"last_error_at"`
+	HeartbeatRTT HeartbeatRTT `json:"heartbeat_rtt"`
+}
+
+// connStatus tracks the connection state of the service across reconnects.
+type connStatus struct {
+	mu           sync.Mutex
+	state        string
+	runID        string
+	server       string
+	serverAddr   string
+	protocol     string
+	tcpMux       bool
+	loginAt      time.Time
+	reconnects   int64
+	lastErr      string
+	lastErrAt    time.Time
+	everLoggedIn bool
+}
+
+// setState changes the state, the service never leaves the stopped state.
+func (s *connStatus) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != StateStopped {
+		s.state = state
+	}
+}
+
+func (s *connStatus) recordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err.Error()
+	s.lastErrAt = time.Now()
+}
+
+func (s *connStatus) loggedIn(t *loginTarget, runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == StateStopped {
+		return
+	}
+	if s.everLoggedIn {
+		s.reconnects++
+	}
+	s.everLoggedIn = true
+	s.state = StateLoggedIn
+	s.runID = runID
+	s.server = t.ep.name
+	s.serverAddr = t.ep.addr()
+	s.protocol = t.protocol()
+	s.tcpMux = lo.FromPtr(t.common.Transport.TCPMux)
+	s.loginAt = time.Now()
+}
+
+// controlClosed moves a logged in service to reconnecting.
+func (s *connStatus) controlClosed(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == StateStopped {
+		return
+	}
+	s.state = StateReconnecting
+	s.lastErr = reason
+	s.lastErrAt = time.Now()
+}
+
+func (s *connStatus) snapshot() ServiceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ServiceStatus{
+		State:       s.state,
+		RunID:       s.runID,
+		Server:      s.server,
+		ServerAddr:  s.serverAddr,
+		Protocol:    s.protocol,
+		TCPMux:      s.tcpMux,
+		Streams:     -1,
+		LoginAt:     s.loginAt,
+		Reconnects:  s.reconnects,
+		LastError:   s.lastErr,
+		LastErrorAt: s.lastErrAt,
+	}
+}
+
+// rttWindow keeps the RTT of the recent heartbeats.
+type rttWindow struct {
+	mu      sync.Mutex
+	samples [rttWindowSize]time.Duration
+	// total number of samples added
+	n int
+}
+
+func (w *rttWindow) add(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.n%rttWindowSize] = d
+	w.n++
+}
+
+func (w *rttWindow) snapshot() HeartbeatRTT {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var rtt HeartbeatRTT
+	if w.n == 0 {
+		return rtt
+	}
+	toMs := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	samples := w.samples[:min(w.n, rttWindowSize)]
+	var sum time.Duration
+	minRTT, maxRTT := samples[0], samples[0]
+	for _, d := range samples {
+		sum += d
+		minRTT = min(minRTT, d)
+		maxRTT = max(maxRTT, d)
+	}
+	rtt.Last = toMs(w.samples[(w.n-1)%rttWindowSize])
+	rtt.Avg = toMs(sum / time.Duration(len(samples)))
+	rtt.Min = toMs(minRTT)
+	rtt.Max = toMs(maxRTT)
+	rtt.Samples = len(samples)
+	return rtt
+}
+
+// Status returns the state of the connection to the server.
+func (svr *Service) Status() ServiceStatus {
+	s := svr.connStatus.snapshot()
+
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	if ctl == nil || s.State != StateLoggedIn {
+		return s
+	}
+	if sc, ok := ctl.sessionCtx.Connector.(StreamCounter); ok {
+		s.Streams = sc.NumStreams()
+	}
+	s.HeartbeatRTT = ctl.rtt.snapshot()
+	return s
+}
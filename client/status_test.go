@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	v1 "frpgo/pkg/config/v1"
+)
+
+func TestRTTWindow(t *testing.T) {
+	require := require.New(t)
+	var w rttWindow
+	require.Equal(HeartbeatRTT{}, w.snapshot())
+
+	for i := 1; i <= rttWindowSize+2; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	// the first two samples are rolled out
+	rtt := w.snapshot()
+	require.Equal(rttWindowSize, rtt.Samples)
+	require.Equal(float64(rttWindowSize+2), rtt.Last)
+	require.Equal(3.0, rtt.Min)
+	require.Equal(float64(rttWindowSize+2), rtt.Max)
+	require.InDelta(7.5, rtt.Avg, 0.001)
+}
+
+func TestServiceStatus(t *testing.T) {
+	require := require.New(t)
+	var attempts atomic.Int32
+	svr, err := NewService(ServiceOptions{
+		Common: &v1.ClientCommonConfig{
+			ServerAddr:    "10.0.0.1",
+			LoginFailExit: lo.ToPtr(false),
+		},
+		ConnectorCreator: func(context.Context, *v1.ClientCommonConfig) Connector {
+			if attempts.Add(1) == 1 {
+				return &fakeConnector{openErr: errors.New("connection refused")}
+			}
+			return &fakeConnector{}
+		},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+
+	require.Eventually(func() bool { return svr.Status().State == StateLoggedIn }, 5*time.Second, 50*time.Millisecond)
+	st := svr.Status()
+	require.Equal("fake", st.RunID)
+	require.Equal("10.0.0.1:7000", st.ServerAddr)
+	require.Equal("tcp", st.Protocol)
+	require.Equal("connection refused", st.LastError)
+	require.Zero(st.Reconnects)
+	require.False(st.LoginAt.IsZero())
+
+	// the control is closed by the server, a new one is logged in
+	svr.ctlMu.RLock()
+	ctl := svr.ctl
+	svr.ctlMu.RUnlock()
+	ctl.closeSession()
+	require.Eventually(func() bool { return svr.Status().Reconnects == 1 }, 5*time.Second, 50*time.Millisecond)
+	require.Equal(StateLoggedIn, svr.Status().State)
+	require.Equal("control connection closed", svr.Status().LastError)
+
+	svr.Close()
+	require.Eventually(func() bool { return svr.Status().State == StateStopped }, 5*time.Second, 50*time.Millisecond)
+}
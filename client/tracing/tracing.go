@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"frpgo/pkg/util/xlog"
+)
+
+const TracerName = "frpgo/client"
+
+// ratio of work connections to trace, stored as bits of float64
+var workConnSampler atomic.Uint64
+
+// SetWorkConnSampler sets the ratio of traced work connections, control plane
+// operations are always traced unless they're dropped by the tracer provider.
+func SetWorkConnSampler(ratio float64) {
+	workConnSampler.Store(math.Float64bits(ratio))
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start starts a span and adds its trace id to the xlog prefixes of the returned context.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, name, opts...)
+	return withLogPrefix(ctx, span), span
+}
+
+// StartWorkConn starts a root span of a work connection if it's sampled.
+func StartWorkConn(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ratio := math.Float64frombits(workConnSampler.Load())
+	if ratio <= 0 || rand.Float64() >= ratio {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	opts = append(opts, trace.WithNewRoot())
+	return Start(ctx, name, opts...)
+}
+
+// StartChild starts a span only if there is a span in ctx, it's used on the data plane
+// to follow sampling decisions of work connections.
+func StartChild(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records the error if it's not nil and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// LogPrefix returns the logger with the trace id of the span in its prefixes.
+func LogPrefix(xl *xlog.Logger, span trace.Span) *xlog.Logger {
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return xl
+	}
+	return xl.Spawn().AddPrefix(xlog.LogPrefix{Name: "trace", Value: sc.TraceID().String(), Priority: 20})
+}
+
+func withLogPrefix(ctx context.Context, span trace.Span) context.Context {
+	if !span.SpanContext().IsValid() {
+		return ctx
+	}
+	return xlog.NewContext(ctx, LogPrefix(xlog.FromContextSafe(ctx), span))
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"frpgo/client/tracing/tracingtest"
+	"frpgo/pkg/util/xlog"
+)
+
+func TestStart(t *testing.T) {
+	require := require.New(t)
+	c := tracingtest.NewCollector(t)
+
+	parent := xlog.New().AppendPrefix("svc")
+	ctx, span := Start(xlog.NewContext(context.Background(), parent), "control.login")
+	traceID := span.SpanContext().TraceID().String()
+
+	// trace id is added to the log prefixes without touching the parent logger
+	prefixes := xlog.FromContextSafe(ctx).ResetPrefixes()
+	require.Len(prefixes, 2)
+	require.Equal("svc", prefixes[0].Value)
+	require.Equal(traceID, prefixes[1].Value)
+	require.Len(parent.ResetPrefixes(), 1)
+
+	_, child := Start(ctx, "connector.connect")
+	End(child, errors.New("refused"))
+	End(span, nil)
+
+	login := c.Span("control.login")
+	require.NotNil(login)
+	connect := c.Span("connector.connect")
+	require.NotNil(connect)
+	require.Equal(login.SpanId, connect.ParentSpanId)
+	require.Equal(tracepb.Status_STATUS_CODE_ERROR, connect.Status.Code)
+	require.Equal("refused", connect.Status.Message)
+}
+
+func TestStartWorkConn(t *testing.T) {
+	require := require.New(t)
+	c := tracingtest.NewCollector(t)
+	t.Cleanup(func() { SetWorkConnSampler(0) })
+
+	// not sampled, children are dropped too
+	SetWorkConnSampler(0)
+	ctx, span := StartWorkConn(context.Background(), "control.req_work_conn")
+	require.False(span.SpanContext().IsValid())
+	_, child := StartChild(ctx, "proxy.dial_local")
+	require.False(child.SpanContext().IsValid())
+	child.End()
+	span.End()
+	require.Empty(c.Spans())
+
+	// work connections are roots even if there is a span in ctx
+	SetWorkConnSampler(1)
+	parentCtx, parent := Start(context.Background(), "control.login")
+	ctx, span = StartWorkConn(parentCtx, "control.req_work_conn")
+	require.True(span.SpanContext().IsValid())
+	require.NotEqual(parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	_, child = StartChild(ctx, "proxy.dial_local")
+	child.End()
+	span.End()
+	parent.End()
+
+	reqWorkConn := c.Span("control.req_work_conn")
+	require.NotNil(reqWorkConn)
+	require.Empty(reqWorkConn.ParentSpanId)
+	require.Equal(reqWorkConn.SpanId, c.Span("proxy.dial_local").ParentSpanId)
+}
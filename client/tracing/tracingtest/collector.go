@@ -0,0 +1,118 @@
+// Package tracingtest provides an in-process OTLP collector stand-in for tests.
+package tracingtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Collector receives spans exported by OTLP over HTTP.
+type Collector struct {
+	srv *httptest.Server
+	tp  *sdktrace.TracerProvider
+
+	spans []*tracepb.Span
+	mu    sync.Mutex
+}
+
+// NewCollector starts a collector and sets a tracer provider exporting all spans to it
+// as the global one until the test ends.
+func NewCollector(t *testing.T) *Collector {
+	c := &Collector{}
+	c.srv = httptest.NewServer(http.HandlerFunc(c.handleTraces))
+
+	exp, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpoint(strings.TrimPrefix(c.srv.URL, "http://")),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("create otlp exporter error: %v", err)
+	}
+	c.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSyncer(exp),
+	)
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(c.tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		_ = c.tp.Shutdown(context.Background())
+		c.srv.Close()
+	})
+	return c
+}
+
+func (c *Collector) handleTraces(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	_, _ = w.Write(out)
+}
+
+// Spans returns spans received by now, ended spans are exported synchronously.
+func (c *Collector) Spans() []*tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*tracepb.Span(nil), c.spans...)
+}
+
+// Span returns the first received span with the name, or nil.
+func (c *Collector) Span(name string) *tracepb.Span {
+	for _, s := range c.Spans() {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Attr returns the string form of the attribute of the span.
+func Attr(s *tracepb.Span, key string) string {
+	for _, kv := range s.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		switch v := kv.Value.Value.(type) {
+		case *commonpb.AnyValue_StringValue:
+			return v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			return strconv.FormatInt(v.IntValue, 10)
+		case *commonpb.AnyValue_BoolValue:
+			return strconv.FormatBool(v.BoolValue)
+		}
+	}
+	return ""
+}
@@ -6,8 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"frpgo/client/inspect"
 	"frpgo/client/proxy"
-	"frpgo/pkg/auth"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	httppkg "frpgo/pkg/util/http"
@@ -21,8 +21,10 @@ type Service struct {
 	// Uniq id got from frps, it will be attached to loginMsg.
 	runID string
 
-	// Sets authentication based on selected method
-	authSetter auth.Setter
+	// servers to login, each with its own transport and auth settings
+	servers *serverPool
+	// state of the connection to the server
+	connStatus connStatus
 
 	// web server for admin UI and apis
 	webServer *httppkg.Server
@@ -32,6 +34,17 @@ type Service struct {
 	proxyCfgs   []v1.ProxyConfigurer
 	visitorCfgs []v1.VisitorConfigurer
 	clientSpec  *msg.ClientSpec
+	// Proxies and visitors created at runtime by CreateProxy and CreateVisitor.
+	// They are registered on every new Control together with proxyCfgs and
+	// visitorCfgs and are kept across config reloads.
+	runtimeProxyCfgs   []v1.ProxyConfigurer
+	runtimeVisitorCfgs []v1.VisitorConfigurer
+	// Names of proxies paused by PauseProxy, they stay paused on every new Control.
+	pausedProxies map[string]struct{}
+
+	// serialize calls of onRuntimeCfgsChange
+	runtimeNotifyMu     sync.Mutex
+	onRuntimeCfgsChange func([]v1.ProxyConfigurer, []v1.VisitorConfigurer)
 
 	// The configuration file used to initialize this client, or an empty
 	// string if no configuration file was used.
@@ -45,6 +58,14 @@ type Service struct {
 
 	connectorCreator func(context.Context, *v1.ClientCommonConfig) Connector
 	handleWorkConnCb func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) bool
+	// listeners of proxies created by Listen, keyed by proxy name
+	listenerMu sync.Mutex
+	listeners  map[string]*ProxyListener
+
+	// captured requests of http proxies with inspect enabled, kept across reconnects
+	inspector *inspect.Recorder
+	// traffic statistics of proxies, kept across reconnects
+	proxyStats *proxy.StatsRegistry
 }
 
 // ServiceOptions contains options for creating a new client service.
@@ -53,6 +74,16 @@ type ServiceOptions struct {
 	ProxyCfgs   []v1.ProxyConfigurer
 	VisitorCfgs []v1.VisitorConfigurer
 
+	// RuntimeProxyCfgs and RuntimeVisitorCfgs are configurers created at runtime in a
+	// previous run, usually restored from a state store. Unlike ProxyCfgs and VisitorCfgs,
+	// they are kept when the config file is reloaded.
+	RuntimeProxyCfgs   []v1.ProxyConfigurer
+	RuntimeVisitorCfgs []v1.VisitorConfigurer
+
+	// OnRuntimeCfgsChange is called with a snapshot of all runtime configurers
+	// every time they are changed. It can be used to persist them.
+	OnRuntimeCfgsChange func([]v1.ProxyConfigurer, []v1.VisitorConfigurer)
+
 	// ConfigFilePath is the path to the configuration file used to initialize.
 	// If it is empty, it means that the configuration file is not used for initialization.
 	// It may be initialized using command line parameters or called directly.
@@ -74,6 +105,11 @@ type ServiceOptions struct {
 	//
 	// If it is not set, the default frpc implementation will be used.
 	HandleWorkConnCb func(*v1.ProxyBaseConfig, net.Conn, *msg.StartWorkConn) bool
+
+	// Inspector records requests and responses of http proxies with inspect enabled.
+	//
+	// If it is not set, a recorder with default limits will be used.
+	Inspector *inspect.Recorder
 }
 
 type StatusExporter interface {
@@ -1,5 +1,7 @@
 package client
 
+import "frpgo/client/inspect"
+
 // setServiceOptionsDefault sets the default values for ServiceOptions.
 func setServiceOptionsDefault(options *ServiceOptions) {
 	if options.Common != nil {
@@ -8,6 +10,9 @@ func setServiceOptionsDefault(options *ServiceOptions) {
 	if options.ConnectorCreator == nil {
 		options.ConnectorCreator = NewConnector
 	}
+	if options.Inspector == nil {
+		options.Inspector = inspect.NewRecorder(0, 0)
+	}
 }
 
 type cancelErr struct {
@@ -22,6 +22,7 @@ import (
 
 	libio "github.com/fatedier/golib/io"
 
+	"frpgo/client/metrics"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	"frpgo/pkg/util/util"
@@ -80,6 +81,7 @@ func (sv *STCPVisitor) handleConn(userConn net.Conn) {
 	defer userConn.Close()
 
 	xl.Debugf("get a new stcp user connection")
+	metrics.Client.VisitorConnection(sv.cfg.Name, sv.cfg.Type)
 	visitorConn, err := sv.helper.ConnectServer()
 	if err != nil {
 		return
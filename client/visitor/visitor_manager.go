@@ -24,6 +24,8 @@ import (
 
 	"github.com/samber/lo"
 
+	"frpgo/client/metrics"
+	"frpgo/fmgr/webhook"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/transport"
 	"frpgo/pkg/util/xlog"
@@ -98,8 +100,10 @@ func (vm *Manager) keepVisitorsRunning() {
 func (vm *Manager) Close() {
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
-	for _, v := range vm.visitors {
+	for name, v := range vm.visitors {
 		v.Close()
+		metrics.Client.VisitorStopped(name, vm.cfgs[name].GetBaseConfig().Type)
+		vm.pushEvent(webhook.EventVisitorStopped, vm.cfgs[name], nil)
 	}
 	select {
 	case <-vm.stopCh:
@@ -116,13 +120,27 @@ func (vm *Manager) startVisitor(cfg v1.VisitorConfigurer) (err error) {
 	err = visitor.Run()
 	if err != nil {
 		xl.Warnf("start error: %v", err)
+		metrics.Client.VisitorStartFailed(name, cfg.GetBaseConfig().Type)
+		vm.pushEvent(webhook.EventVisitorStartError, cfg, err)
 	} else {
 		vm.visitors[name] = visitor
 		xl.Infof("start visitor success")
+		metrics.Client.VisitorStarted(name, cfg.GetBaseConfig().Type)
+		vm.pushEvent(webhook.EventVisitorStarted, cfg, nil)
 	}
 	return
 }
 
+func (vm *Manager) pushEvent(typ webhook.EventType, cfg v1.VisitorConfigurer, err error) {
+	e := webhook.NewEvent(typ, vm.helper.RunID())
+	e.VisitorName = cfg.GetBaseConfig().Name
+	e.VisitorType = cfg.GetBaseConfig().Type
+	if err != nil {
+		e.Error = err.Error()
+	}
+	webhook.Push(e)
+}
+
 func (vm *Manager) UpdateAll(cfgs []v1.VisitorConfigurer) {
 	if len(cfgs) > 0 {
 		// Only start keepVisitorsRunning goroutine once and only when there is at least one visitor.
@@ -151,6 +169,8 @@ func (vm *Manager) UpdateAll(cfgs []v1.VisitorConfigurer) {
 			delete(vm.cfgs, name)
 			if visitor, ok := vm.visitors[name]; ok {
 				visitor.Close()
+				metrics.Client.VisitorStopped(name, oldCfg.GetBaseConfig().Type)
+				vm.pushEvent(webhook.EventVisitorStopped, oldCfg, nil)
 			}
 			delete(vm.visitors, name)
 		}
@@ -29,6 +29,7 @@ import (
 	quic "github.com/quic-go/quic-go"
 	"golang.org/x/time/rate"
 
+	"frpgo/client/metrics"
 	v1 "frpgo/pkg/config/v1"
 	"frpgo/pkg/msg"
 	"frpgo/pkg/nathole"
@@ -158,6 +159,7 @@ func (sv *XTCPVisitor) keepTunnelOpenWorker() {
 func (sv *XTCPVisitor) handleConn(userConn net.Conn) {
 	xl := xlog.FromContextSafe(sv.ctx)
 	isConnTrasfered := false
+	metrics.Client.VisitorConnection(sv.cfg.Name, sv.cfg.Type)
 	defer func() {
 		if !isConnTrasfered {
 			userConn.Close()
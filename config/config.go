@@ -1,6 +1,10 @@
 package config
 
-import "github.com/zeromicro/go-zero/rest"
+import (
+	"time"
+
+	"github.com/zeromicro/go-zero/rest"
+)
 
 type Config struct {
 	rest.RestConf
@@ -11,7 +15,86 @@ type Config struct {
 	}
 
 	// webhook
-	Webhook struct {
-		Url string
+	Webhook WebhookConf
+
+	// 实时事件流 /api/events
+	Events struct {
+		// 内存中保留的最近事件数，用于Last-Event-ID续传
+		BufferSize int `json:",default=1000"`
+		// 允许WebSocket连接的页面来源，如https://console.example.com，
+		// 未配置时仅允许与请求Host相同的来源
+		AllowedOrigins []string `json:",optional"`
+	}
+
+	// 运行时创建的隧道的持久化存储，重启后恢复
+	Store StoreConf
+
+	// Prometheus指标，启用后go-zero服务及frpc管理端口均提供/metrics
+	Metrics struct {
+		Enable bool `json:",default=true"`
+	}
+
+	// 链路追踪，导出配置见Telemetry（go-zero），控制面操作全部追踪，
+	// 工作连接按比例采样
+	Tracing struct {
+		WorkConnSampler float64 `json:",default=0.01"`
 	}
+
+	// http隧道请求抓取
+	Inspect struct {
+		// 每个隧道保留的最大请求数
+		MaxRequests int `json:",default=100"`
+		// 请求/响应body保留的最大字节数
+		MaxBodySize int `json:",default=65536"`
+	}
+}
+
+type WebhookConf struct {
+	// 配置后作为名为default的订阅者，接收全部事件
+	Url string `json:",optional"`
+	// 签名密钥，请求携带时间戳及HMAC-SHA256签名，接收方使用fmgr/webhook/signature校验。
+	// 订阅者未配置密钥时使用
+	Secret string `json:",optional"`
+	// 轮换密钥时同时配置新旧密钥，每个密钥各生成一个签名
+	Secrets []string `json:",optional"`
+	// 订阅者，也可通过接口在运行时增删
+	Subscribers []WebhookSubscriberConf `json:",optional"`
+	// 单次投递超时，订阅者未配置超时时使用
+	Timeout time.Duration `json:",default=10s"`
+	// 投递失败的最大尝试次数，超过后进入死信列表
+	MaxAttempts int `json:",default=10"`
+	// 重试间隔上限
+	MaxBackoff time.Duration `json:",default=5m"`
+	// 待投递及死信事件的保存目录，为空时不持久化
+	OutboxDir string `json:",default=./data/webhook"`
+	// 死信列表保留的最大数量
+	MaxDeadLetters int `json:",default=1000"`
+	// 每个订阅者待投递队列的长度，订阅者不可达导致队列满时丢弃新事件
+	QueueSize int `json:",default=1000"`
+}
+
+type WebhookSubscriberConf struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	// 事件类型过滤，支持glob如proxy.*，为空时接收全部事件
+	Events []string `json:"events,optional"`
+	// 代理及访问者名过滤，支持glob，为空时不过滤。不带名称的事件（如control.*）不受影响
+	Names   []string          `json:"names,optional"`
+	Headers map[string]string `json:"headers,optional"`
+	Timeout time.Duration     `json:"timeout,optional"`
+	// 请求body格式，raw: 事件json; cloudevents: CloudEvents 1.0 json; template: 使用Template渲染
+	Format string `json:"format,optional"`
+	// text/template模板，以事件为数据，Format为template时使用
+	Template string `json:"template,optional"`
+	// Format为template时的Content-Type，默认application/json
+	ContentType string `json:"contentType,optional"`
+	// 配置后覆盖全局密钥
+	Secret  string   `json:"secret,optional"`
+	Secrets []string `json:"secrets,optional"`
+}
+
+type StoreConf struct {
+	// file: 本地json文件; none: 不持久化
+	Type string `json:",default=file"`
+	Path string `json:",default=./data/state.json"`
 }
@@ -2,26 +2,43 @@ package fmgr
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"frpgo/client"
+	"frpgo/client/inspect"
+	"frpgo/client/tracing"
 	gconfig "frpgo/config"
+	"frpgo/fmgr/store"
 	"frpgo/fmgr/webhook"
 	"frpgo/pkg/config"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/metrics"
 	"frpgo/pkg/util/log"
 	"frpgo/pkg2/utils2"
 
+	"github.com/samber/lo"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
 func CreateService(c gconfig.Config) (*client.Service, error) {
 	logx.Debugf("CreateService Frp Conf: %v", utils2.PrettyJson(c.Frp))
 
+	if c.Metrics.Enable {
+		if err := metrics.EnablePrometheus(); err != nil {
+			return nil, fmt.Errorf("enable prometheus metrics error: %v", err)
+		}
+	}
+
+	tracing.SetWorkConnSampler(c.Tracing.WorkConnSampler)
+
 	// setup webhook
-	webhook.Setup(c)
+	if err := webhook.Setup(c); err != nil {
+		return nil, err
+	}
 
 	cfg, _, _, _, err := config.LoadClientConfig(c.Frp.Conf, true)
 	if err != nil {
@@ -30,17 +47,37 @@ func CreateService(c gconfig.Config) (*client.Service, error) {
 
 	log.InitLogger(cfg.Log.To, cfg.Log.Level, int(cfg.Log.MaxDays), cfg.Log.DisablePrintColor)
 
+	// restore tunnels created at runtime before last restart
+	st, err := store.New(c.Store)
+	if err != nil {
+		return nil, err
+	}
+	state := store.NewState(nil, nil)
+	if st != nil {
+		if state, err = st.Load(); err != nil {
+			return nil, err
+		}
+		logx.Infof("restore %d proxies and %d visitors from state store", len(state.Proxies), len(state.Visitors))
+	}
+
 	svr, err := client.NewService(client.ServiceOptions{
-		Common:         cfg,
-		ProxyCfgs:      nil,
-		VisitorCfgs:    nil,
-		ConfigFilePath: "",
+		Common:              cfg,
+		ProxyCfgs:           nil,
+		VisitorCfgs:         nil,
+		RuntimeProxyCfgs:    state.Proxies,
+		RuntimeVisitorCfgs:  state.Visitors,
+		OnRuntimeCfgsChange: saveStateFunc(st),
+		Inspector:           inspect.NewRecorder(c.Inspect.MaxRequests, c.Inspect.MaxBodySize),
+		ConfigFilePath:      "",
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	shouldGracefulClose := cfg.Transport.Protocol == "kcp" || cfg.Transport.Protocol == "quic"
+	shouldGracefulClose := usesUDPProtocol(&cfg.Transport) ||
+		lo.SomeBy(cfg.Servers, func(s v1.ServerEndpointConfig) bool {
+			return s.Transport != nil && usesUDPProtocol(s.Transport)
+		})
 
 	// Capture the exit signal if we use kcp or quic.
 	if shouldGracefulClose {
@@ -53,6 +90,24 @@ func CreateService(c gconfig.Config) (*client.Service, error) {
 	return svr, nil
 }
 
+func saveStateFunc(st store.Store) func([]v1.ProxyConfigurer, []v1.VisitorConfigurer) {
+	if st == nil {
+		return nil
+	}
+
+	return func(proxyCfgs []v1.ProxyConfigurer, visitorCfgs []v1.VisitorConfigurer) {
+		if err := st.Save(store.NewState(proxyCfgs, visitorCfgs)); err != nil {
+			logx.Errorf("save state error: %v", err)
+		}
+	}
+}
+
+func usesUDPProtocol(c *v1.ClientTransportConfig) bool {
+	return lo.SomeBy(append([]string{c.Protocol}, c.Protocols...), func(protocol string) bool {
+		return protocol == "kcp" || protocol == "quic"
+	})
+}
+
 func handleTermSignal(svr *client.Service) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"frpgo/config"
+)
+
+func init() {
+	Register(TypeFile, func(c config.StoreConf) (Store, error) {
+		return NewFileStore(c.Path), nil
+	})
+}
+
+// FileStore saves the state as a local json file.
+//
+// Save writes to a temp file in the same directory and renames it over the target,
+// so a crash in the middle of writing never leaves a half written file behind.
+// If the file is corrupted anyway, Load moves it aside and starts with an empty state.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+	}
+}
+
+func (fs *FileStore) Load() (*State, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	content, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewState(nil, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	if err = json.Unmarshal(content, state); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+			return nil, err
+		}
+
+		// keep the broken file for troubleshooting
+		backup := fmt.Sprintf("%s.corrupt-%s", fs.path, time.Now().Format("20060102150405"))
+		logx.Errorf("state file [%s] is corrupted: %v, move it to [%s]", fs.path, err, backup)
+		if err = os.Rename(fs.path, backup); err != nil {
+			return nil, err
+		}
+		return NewState(nil, nil), nil
+	}
+	return state, nil
+}
+
+func (fs *FileStore) Save(state *State) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(fs.path, content)
+}
+
+// WriteFileAtomic writes content to a temp file in the same directory and renames it over path.
+func WriteFileAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
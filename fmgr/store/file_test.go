@@ -0,0 +1,75 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "frpgo/pkg/config/v1"
+)
+
+func TestFileStoreSaveAndLoad(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+	fs := NewFileStore(path)
+
+	state, err := fs.Load()
+	require.NoError(err)
+	require.Equal(CurrentVersion, state.Version)
+	require.Empty(state.Proxies)
+
+	pxyCfg := v1.NewProxyConfigurerByType(v1.ProxyTypeTCP).(*v1.TCPProxyConfig)
+	pxyCfg.Name = "ssh"
+	pxyCfg.LocalIP = "127.0.0.1"
+	pxyCfg.LocalPort = 22
+	pxyCfg.RemotePort = 6000
+	visitorCfg := v1.NewVisitorConfigurerByType(v1.VisitorTypeSTCP).(*v1.STCPVisitorConfig)
+	visitorCfg.Name = "ssh-visitor"
+	visitorCfg.ServerName = "ssh"
+	visitorCfg.BindPort = 9000
+
+	require.NoError(fs.Save(NewState([]v1.ProxyConfigurer{pxyCfg}, []v1.VisitorConfigurer{visitorCfg})))
+
+	state, err = fs.Load()
+	require.NoError(err)
+	require.Len(state.Proxies, 1)
+	require.Equal(pxyCfg, state.Proxies[0])
+	require.Len(state.Visitors, 1)
+	require.Equal(visitorCfg, state.Visitors[0])
+
+	// no temp files left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(err)
+	require.Len(entries, 1)
+}
+
+func TestFileStoreLoadBrokenContent(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	fs := NewFileStore(path)
+
+	// a broken entry is skipped, others are kept
+	content := `{"version": 1, "proxies": [{"type": "unknown"}, {"type": "tcp", "name": "ssh", "localPort": 22}]}`
+	require.NoError(os.WriteFile(path, []byte(content), 0o600))
+	state, err := fs.Load()
+	require.NoError(err)
+	require.Len(state.Proxies, 1)
+	require.Equal("ssh", state.Proxies[0].GetBaseConfig().Name)
+
+	// a truncated file is moved aside
+	require.NoError(os.WriteFile(path, []byte(content[:20]), 0o600))
+	state, err = fs.Load()
+	require.NoError(err)
+	require.Empty(state.Proxies)
+	matches, err := filepath.Glob(path + ".corrupt-*")
+	require.NoError(err)
+	require.Len(matches, 1)
+
+	// never overwrite a state written by a newer version
+	require.NoError(os.WriteFile(path, []byte(`{"version": 99}`), 0o600))
+	_, err = fs.Load()
+	require.Error(err)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"frpgo/config"
+	v1 "frpgo/pkg/config/v1"
+)
+
+// CurrentVersion is the schema version written by this build.
+// Bump it and add a migration in State.migrate when the layout of State changes.
+const CurrentVersion = 1
+
+const (
+	TypeNone = "none"
+	TypeFile = "file"
+)
+
+// Store persists proxies and visitors created at runtime, so that they can be
+// restored after the process restarts.
+type Store interface {
+	// Load returns the saved state. An empty state is returned if nothing has been saved yet.
+	Load() (*State, error)
+	// Save replaces the saved state.
+	Save(*State) error
+}
+
+// State is the content saved in a store.
+type State struct {
+	Version  int
+	Proxies  []v1.ProxyConfigurer
+	Visitors []v1.VisitorConfigurer
+}
+
+// stateFile is the serialized form of State. Proxies and visitors are kept as raw json,
+// so that a broken entry can be skipped instead of losing the whole state.
+type stateFile struct {
+	Version  int               `json:"version"`
+	Proxies  []json.RawMessage `json:"proxies,omitempty"`
+	Visitors []json.RawMessage `json:"visitors,omitempty"`
+}
+
+func NewState(proxyCfgs []v1.ProxyConfigurer, visitorCfgs []v1.VisitorConfigurer) *State {
+	return &State{
+		Version:  CurrentVersion,
+		Proxies:  proxyCfgs,
+		Visitors: visitorCfgs,
+	}
+}
+
+func (s *State) MarshalJSON() ([]byte, error) {
+	out := stateFile{
+		Version:  s.Version,
+		Proxies:  make([]json.RawMessage, 0, len(s.Proxies)),
+		Visitors: make([]json.RawMessage, 0, len(s.Visitors)),
+	}
+	for _, c := range s.Proxies {
+		b, err := json.Marshal(&v1.TypedProxyConfig{Type: c.GetBaseConfig().Type, ProxyConfigurer: c})
+		if err != nil {
+			return nil, err
+		}
+		out.Proxies = append(out.Proxies, b)
+	}
+	for _, c := range s.Visitors {
+		b, err := json.Marshal(&v1.TypedVisitorConfig{Type: c.GetBaseConfig().Type, VisitorConfigurer: c})
+		if err != nil {
+			return nil, err
+		}
+		out.Visitors = append(out.Visitors, b)
+	}
+	return json.Marshal(&out)
+}
+
+func (s *State) UnmarshalJSON(b []byte) error {
+	var in stateFile
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	if in.Version > CurrentVersion {
+		return fmt.Errorf("state version %d is newer than supported version %d", in.Version, CurrentVersion)
+	}
+
+	s.Version = in.Version
+	s.Proxies = make([]v1.ProxyConfigurer, 0, len(in.Proxies))
+	s.Visitors = make([]v1.VisitorConfigurer, 0, len(in.Visitors))
+	for i, raw := range in.Proxies {
+		var c v1.TypedProxyConfig
+		if err := json.Unmarshal(raw, &c); err != nil {
+			logx.Errorf("skip broken proxy [%d] in state: %v", i, err)
+			continue
+		}
+		s.Proxies = append(s.Proxies, c.ProxyConfigurer)
+	}
+	for i, raw := range in.Visitors {
+		var c v1.TypedVisitorConfig
+		if err := json.Unmarshal(raw, &c); err != nil {
+			logx.Errorf("skip broken visitor [%d] in state: %v", i, err)
+			continue
+		}
+		s.Visitors = append(s.Visitors, c.VisitorConfigurer)
+	}
+	s.migrate()
+	return nil
+}
+
+// migrate upgrades a state loaded from an older schema version to CurrentVersion.
+func (s *State) migrate() {
+	// version 0 means the field is missing, it has the same layout with version 1
+	s.Version = CurrentVersion
+}
+
+var storeCreators = map[string]func(config.StoreConf) (Store, error){}
+
+// Register makes a store implementation available by the type name used in StoreConf.Type.
+func Register(storeType string, creator func(config.StoreConf) (Store, error)) {
+	storeCreators[storeType] = creator
+}
+
+// New creates the store configured by c. It returns nil if the store is disabled.
+func New(c config.StoreConf) (Store, error) {
+	if c.Type == "" || c.Type == TypeNone {
+		return nil, nil
+	}
+
+	creator, ok := storeCreators[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown state store type [%s]", c.Type)
+	}
+	return creator(c)
+}
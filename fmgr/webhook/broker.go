@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	defaultBrokerSize = 1000
+	// 订阅者未及时读取的事件数超过后断开，客户端可通过Last-Event-ID续传
+	streamBufferSize = 256
+)
+
+// StreamFilter 事件流过滤条件，均为空时接收全部事件
+type StreamFilter struct {
+	// 事件类型，支持glob如proxy.*
+	Events []string
+	// 代理及访问者名，支持glob。不带名称的事件（如control.*）不受影响
+	Names []string
+	// 代理及访问者类型，如tcp、http。不带类型的事件不受影响
+	Types []string
+}
+
+func (f *StreamFilter) match(e *Event) bool {
+	if len(f.Events) > 0 && !matchAny(f.Events, string(e.Type)) {
+		return false
+	}
+
+	name, typ := e.ProxyName, e.ProxyType
+	if name == "" {
+		name, typ = e.VisitorName, e.VisitorType
+	}
+	if len(f.Names) > 0 && name != "" && !matchAny(f.Names, name) {
+		return false
+	}
+	if len(f.Types) > 0 && typ != "" && !matchAny(f.Types, strings.ToLower(typ)) {
+		return false
+	}
+	return true
+}
+
+// StreamEvent 带序号的事件，序号单调递增，用作SSE的id
+type StreamEvent struct {
+	Seq   uint64
+	Event *Event
+}
+
+// Stream 实时事件订阅
+type Stream struct {
+	C <-chan StreamEvent
+	// 因读取过慢被断开时关闭C，Dropped为true
+	Dropped bool
+
+	ch     chan StreamEvent
+	filter StreamFilter
+	closed bool
+}
+
+// Broker 在内存中保留最近的事件，并分发给实时订阅者
+type Broker struct {
+	size int
+	// 环形缓冲，按序号从旧到新
+	events []StreamEvent
+	seq    uint64
+	// 订阅者
+	streams map[*Stream]struct{}
+	mu      sync.Mutex
+}
+
+func NewBroker(size int) *Broker {
+	if size <= 0 {
+		size = defaultBrokerSize
+	}
+	return &Broker{
+		size:    size,
+		events:  make([]StreamEvent, 0, size),
+		streams: make(map[*Stream]struct{}),
+	}
+}
+
+func (b *Broker) Publish(e *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	se := StreamEvent{Seq: b.seq, Event: e}
+	if len(b.events) >= b.size {
+		b.events = b.events[1:]
+	}
+	b.events = append(b.events, se)
+
+	for s := range b.streams {
+		if !s.filter.match(e) {
+			continue
+		}
+		select {
+		case s.ch <- se:
+		default:
+			s.Dropped = true
+			b.closeStream(s)
+		}
+	}
+}
+
+// Subscribe 订阅事件，lastSeq不为0时先返回缓冲中序号大于lastSeq的事件。
+// truncated表示部分事件已移出缓冲或来自重启前，无法续传
+func (b *Broker) Subscribe(filter StreamFilter, lastSeq uint64) (s *Stream, replay []StreamEvent, truncated bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan StreamEvent, streamBufferSize)
+	s = &Stream{C: ch, ch: ch, filter: filter}
+	b.streams[s] = struct{}{}
+
+	if lastSeq == 0 {
+		return s, nil, false
+	}
+	if lastSeq > b.seq {
+		// 序号来自重启前
+		lastSeq, truncated = 0, true
+	} else if len(b.events) > 0 && lastSeq+1 < b.events[0].Seq {
+		truncated = true
+	}
+	for _, se := range b.events {
+		if se.Seq > lastSeq && filter.match(se.Event) {
+			replay = append(replay, se)
+		}
+	}
+	return s, replay, truncated
+}
+
+func (b *Broker) Unsubscribe(s *Stream) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeStream(s)
+}
+
+// Hold lock before calling this function.
+func (b *Broker) closeStream(s *Stream) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(b.streams, s)
+	close(s.ch)
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newProxyEvent(typ EventType, name string, proxyType string) *Event {
+	e := NewEvent(typ, "")
+	e.ProxyName = name
+	e.ProxyType = proxyType
+	return e
+}
+
+func TestBrokerFilter(t *testing.T) {
+	require := require.New(t)
+	b := NewBroker(10)
+
+	s, replay, truncated := b.Subscribe(StreamFilter{
+		Events: []string{"proxy.*", "control.*"},
+		Names:  []string{"web-*"},
+		Types:  []string{"http"},
+	}, 0)
+	require.Empty(replay)
+	require.False(truncated)
+
+	b.Publish(newProxyEvent(EventProxyStarted, "web-1", "http"))
+	b.Publish(newProxyEvent(EventProxyStarted, "ssh", "tcp"))
+	b.Publish(newProxyEvent(EventProxyStarted, "web-2", "tcp"))
+	b.Publish(newProxyEvent(EventRequestCaptured, "web-1", "http"))
+	b.Publish(NewEvent(EventControlReconnected, ""))
+
+	se := <-s.C
+	require.EqualValues(1, se.Seq)
+	require.Equal("web-1", se.Event.ProxyName)
+	se = <-s.C
+	require.EqualValues(5, se.Seq)
+	require.Equal(EventControlReconnected, se.Event.Type)
+
+	b.Unsubscribe(s)
+	_, ok := <-s.C
+	require.False(ok)
+	require.False(s.Dropped)
+}
+
+func TestBrokerReplay(t *testing.T) {
+	require := require.New(t)
+	b := NewBroker(3)
+	for i := 0; i < 5; i++ {
+		b.Publish(newProxyEvent(EventProxyStarted, "web", "http"))
+	}
+
+	// seq 3, 4 and 5 are kept
+	_, replay, truncated := b.Subscribe(StreamFilter{}, 3)
+	require.False(truncated)
+	require.Len(replay, 2)
+	require.EqualValues(4, replay[0].Seq)
+	require.EqualValues(5, replay[1].Seq)
+
+	_, replay, truncated = b.Subscribe(StreamFilter{}, 2)
+	require.False(truncated)
+	require.Len(replay, 3)
+
+	_, replay, truncated = b.Subscribe(StreamFilter{}, 1)
+	require.True(truncated)
+	require.Len(replay, 3)
+
+	// id from before restart
+	_, replay, truncated = b.Subscribe(StreamFilter{}, 100)
+	require.True(truncated)
+	require.Len(replay, 3)
+}
+
+func TestBrokerDropSlowStream(t *testing.T) {
+	require := require.New(t)
+	b := NewBroker(10)
+
+	s, _, _ := b.Subscribe(StreamFilter{}, 0)
+	for i := 0; i < streamBufferSize+1; i++ {
+		b.Publish(NewEvent(EventControlLogin, ""))
+	}
+
+	n := 0
+	for range s.C {
+		n++
+	}
+	require.Equal(streamBufferSize, n)
+	require.True(s.Dropped)
+}
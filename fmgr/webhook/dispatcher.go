@@ -0,0 +1,611 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"frpgo/client/metrics"
+	"frpgo/client/tracing"
+	"frpgo/config"
+	"frpgo/fmgr/webhook/signature"
+	"frpgo/pkg/util/util"
+	"frpgo/pkg/util/wait"
+)
+
+var (
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrQueueFull        = errors.New("webhook subscriber queue is full")
+)
+
+const defaultQueueSize = 1000
+
+// Delivery 一个事件对一个订阅者的投递
+type Delivery struct {
+	ID            string    `json:"id"`
+	Subscriber    string    `json:"subscriber"`
+	URL           string    `json:"url"`
+	Event         *Event    `json:"event"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+}
+
+// Dispatcher 将事件分发给匹配的订阅者。每个订阅者有一个有界队列，由单独的goroutine按顺序投递，
+// 失败后按指数退避重试。Push先将投递保存到outbox再入队，重启后继续投递；
+// 队列满时投递只留在outbox中，队列空出后再加载，未配置outbox时丢弃。
+// 超过最大尝试次数的投递进入死信列表，可通过Redrive重新投递
+type Dispatcher struct {
+	conf config.WebhookConf
+	// 为nil时不持久化
+	outbox *outbox
+
+	subscribers map[string]*subscriber
+	// 从旧到新
+	deadLetters []*Delivery
+	mu          sync.Mutex
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func NewDispatcher(c config.WebhookConf) *Dispatcher {
+	d := &Dispatcher{
+		conf:        c,
+		subscribers: make(map[string]*subscriber),
+		stopCh:      make(chan struct{}),
+	}
+	if c.OutboxDir != "" {
+		d.outbox = &outbox{dir: c.OutboxDir}
+	}
+	return d
+}
+
+// Start 加载订阅者及死信列表，并继续投递上次退出时未完成的事件
+func (d *Dispatcher) Start() error {
+	confs := slices.Clone(d.conf.Subscribers)
+	if d.conf.Url != "" {
+		confs = append([]config.WebhookSubscriberConf{{Name: DefaultSubscriber, Url: d.conf.Url}}, confs...)
+	}
+	for _, c := range confs {
+		if err := d.addSubscriber(c, true); err != nil {
+			return err
+		}
+	}
+
+	if d.outbox == nil {
+		return nil
+	}
+
+	runtimeConfs, err := d.outbox.loadSubscribers()
+	if err != nil {
+		return err
+	}
+	for _, c := range runtimeConfs {
+		if err := d.addSubscriber(c, false); err != nil {
+			logx.Errorf("restore webhook subscriber [%s] error: %v", c.Name, err)
+		}
+	}
+
+	dead, err := d.outbox.load(deadDir)
+	if err != nil {
+		return err
+	}
+	pending, err := d.outbox.load(pendingDir)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.deadLetters = dead
+	d.mu.Unlock()
+
+	if len(pending) > 0 {
+		logx.Infof("resume %d pending webhook deliveries", len(pending))
+	}
+	// 可能超过队列长度，作为溢出的投递由run加载
+	for _, dl := range pending {
+		s, ok := d.getSubscriber(dl.Subscriber)
+		if !ok {
+			d.drop(dl)
+			continue
+		}
+		s.updateStats(func(stats *SubscriberStats) { stats.Pending++ })
+		s.spillMu.Lock()
+		s.spilled = true
+		s.spillMu.Unlock()
+		s.wake()
+	}
+	return nil
+}
+
+// Stop 停止投递，未完成的投递留在outbox中，重启后继续投递
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for _, s := range d.subscribers {
+			s.stop()
+		}
+	})
+}
+
+// Push 为每个匹配的订阅者创建一个投递并放入其队列，不阻塞
+func (d *Dispatcher) Push(e *Event) {
+	d.mu.Lock()
+	subs := make([]*subscriber, 0, len(d.subscribers))
+	for _, s := range d.subscribers {
+		if s.match(e) {
+			subs = append(subs, s)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, s := range subs {
+		id, _ := util.RandID()
+		dl := &Delivery{
+			ID:         id,
+			Subscriber: s.conf.Name,
+			URL:        s.conf.Url,
+			Event:      e,
+			CreatedAt:  time.Now(),
+		}
+		if !d.enqueue(s, dl) {
+			logx.Errorf("webhook subscriber [%s] queue is full, drop event [%s] %s", s.conf.Name, e.ID, e.Type)
+			metrics.Client.WebhookDelivery(dl.Subscriber, metrics.WebhookDropped)
+		}
+	}
+}
+
+// Subscribers 返回所有订阅者，按名称排序
+func (d *Dispatcher) Subscribers() []SubscriberInfo {
+	d.mu.Lock()
+	subs := make([]*subscriber, 0, len(d.subscribers))
+	for _, s := range d.subscribers {
+		subs = append(subs, s)
+	}
+	d.mu.Unlock()
+
+	out := make([]SubscriberInfo, 0, len(subs))
+	for _, s := range subs {
+		out = append(out, s.info())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Conf.Name < out[j].Conf.Name
+	})
+	return out
+}
+
+// AddSubscriber 运行时添加订阅者，配置outbox时持久化
+func (d *Dispatcher) AddSubscriber(c config.WebhookSubscriberConf) error {
+	if err := d.addSubscriber(c, false); err != nil {
+		return err
+	}
+	d.saveSubscribers()
+	return nil
+}
+
+// RemoveSubscriber 删除运行时添加的订阅者，其未完成的投递被丢弃
+func (d *Dispatcher) RemoveSubscriber(name string) error {
+	d.mu.Lock()
+	s, ok := d.subscribers[name]
+	if !ok {
+		d.mu.Unlock()
+		return ErrSubscriberNotFound
+	}
+	if s.static {
+		d.mu.Unlock()
+		return ErrSubscriberStatic
+	}
+	delete(d.subscribers, name)
+	d.mu.Unlock()
+
+	s.stop()
+	d.saveSubscribers()
+	return nil
+}
+
+func (d *Dispatcher) addSubscriber(c config.WebhookSubscriberConf, static bool) error {
+	s, err := newSubscriber(c, d.conf, static)
+	if err != nil {
+		return err
+	}
+
+	queueSize := d.conf.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	s.queue = make(chan *Delivery, queueSize)
+	s.wakeCh = make(chan struct{}, 1)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.subscribers[c.Name]; ok {
+		return ErrSubscriberExist
+	}
+	d.subscribers[c.Name] = s
+	go d.run(s)
+	return nil
+}
+
+func (d *Dispatcher) saveSubscribers() {
+	if d.outbox == nil {
+		return
+	}
+
+	d.mu.Lock()
+	confs := make([]config.WebhookSubscriberConf, 0, len(d.subscribers))
+	for _, s := range d.subscribers {
+		if !s.static {
+			confs = append(confs, s.conf)
+		}
+	}
+	d.mu.Unlock()
+
+	sort.Slice(confs, func(i, j int) bool {
+		return confs[i].Name < confs[j].Name
+	})
+	if err := d.outbox.saveSubscribers(confs); err != nil {
+		logx.Errorf("save webhook subscribers error: %v", err)
+	}
+}
+
+func (d *Dispatcher) getSubscriber(name string) (*subscriber, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.subscribers[name]
+	return s, ok
+}
+
+// DeadLetters 返回死信列表，从新到旧
+func (d *Dispatcher) DeadLetters() []*Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]*Delivery, 0, len(d.deadLetters))
+	for i := len(d.deadLetters) - 1; i >= 0; i-- {
+		dl := *d.deadLetters[i]
+		out = append(out, &dl)
+	}
+	return out
+}
+
+// Redrive 将死信重新投递，尝试次数清零
+func (d *Dispatcher) Redrive(id string) error {
+	d.mu.Lock()
+	idx := slices.IndexFunc(d.deadLetters, func(dl *Delivery) bool { return dl.ID == id })
+	if idx < 0 {
+		d.mu.Unlock()
+		return ErrDeliveryNotFound
+	}
+	dl := d.deadLetters[idx]
+	s, ok := d.subscribers[dl.Subscriber]
+	if !ok {
+		d.mu.Unlock()
+		return fmt.Errorf("%w: [%s]", ErrSubscriberNotFound, dl.Subscriber)
+	}
+	d.deadLetters = slices.Delete(d.deadLetters, idx, idx+1)
+	d.mu.Unlock()
+
+	if !d.redrive(s, dl) {
+		return fmt.Errorf("%w: [%s]", ErrQueueFull, dl.Subscriber)
+	}
+	return nil
+}
+
+// RedriveAll 重新投递所有订阅者仍存在的死信，返回数量。队列满时其余死信保留
+func (d *Dispatcher) RedriveAll() int {
+	d.mu.Lock()
+	var redrives, keeps []*Delivery
+	subs := make(map[string]*subscriber)
+	for _, dl := range d.deadLetters {
+		if s, ok := d.subscribers[dl.Subscriber]; ok {
+			redrives = append(redrives, dl)
+			subs[dl.ID] = s
+		} else {
+			keeps = append(keeps, dl)
+		}
+	}
+	d.deadLetters = keeps
+	d.mu.Unlock()
+
+	n := 0
+	for _, dl := range redrives {
+		if d.redrive(subs[dl.ID], dl) {
+			n++
+		}
+	}
+	return n
+}
+
+// redrive 将死信重新投递，先保存到pending再删除死信文件。未配置outbox且队列满时放回死信列表
+func (d *Dispatcher) redrive(s *subscriber, dl *Delivery) bool {
+	attempts, lastErr := dl.Attempts, dl.LastError
+	dl.Attempts = 0
+	dl.LastError = ""
+	if !d.enqueue(s, dl) {
+		dl.Attempts, dl.LastError = attempts, lastErr
+		d.mu.Lock()
+		d.deadLetters = append(d.deadLetters, dl)
+		d.mu.Unlock()
+		return false
+	}
+	d.unpersist(deadDir, dl.ID)
+	return true
+}
+
+// enqueue 将投递保存到outbox并放入订阅者队列。队列满或有溢出的投递时只保存在outbox中，
+// 未配置outbox时返回false
+func (d *Dispatcher) enqueue(s *subscriber, dl *Delivery) bool {
+	s.spillMu.Lock()
+	defer s.spillMu.Unlock()
+
+	d.persist(pendingDir, dl)
+	s.updateStats(func(stats *SubscriberStats) { stats.Pending++ })
+	if !s.spilled {
+		select {
+		case <-s.stopCh:
+		default:
+			select {
+			case s.queue <- dl:
+				return true
+			default:
+			}
+		}
+	}
+	if d.outbox != nil {
+		if !s.spilled {
+			logx.Infof("webhook subscriber [%s] queue is full, keep deliveries in outbox", s.conf.Name)
+			s.spilled = true
+		}
+		s.wake()
+		return true
+	}
+	s.updateStats(func(stats *SubscriberStats) { stats.Pending-- })
+	return false
+}
+
+// reload 队列为空时从outbox加载溢出的投递，全部入队后恢复直接入队。返回是否有投递入队
+func (d *Dispatcher) reload(s *subscriber) bool {
+	s.spillMu.Lock()
+	defer s.spillMu.Unlock()
+	if !s.spilled {
+		return false
+	}
+	// 溢出前入队的投递尚未投递完
+	if len(s.queue) > 0 {
+		return true
+	}
+
+	// 队列为空且没有进行中的投递，pending中该订阅者的投递都是溢出的
+	pending, err := d.outbox.load(pendingDir)
+	if err != nil {
+		logx.Errorf("load webhook deliveries of subscriber [%s] error: %v", s.conf.Name, err)
+		return false
+	}
+	n := 0
+	for _, dl := range pending {
+		if dl.Subscriber != s.conf.Name {
+			continue
+		}
+		if n == cap(s.queue) {
+			return true
+		}
+		s.queue <- dl
+		n++
+	}
+	s.spilled = false
+	return n > 0
+}
+
+// run 按顺序投递订阅者队列中的事件，队列为空时加载溢出的投递，订阅者被删除或Dispatcher停止时退出
+func (d *Dispatcher) run(s *subscriber) {
+	for {
+		select {
+		case <-s.stopCh:
+			d.stopped(s)
+			return
+		case dl := <-s.queue:
+			d.deliver(s, dl)
+			continue
+		default:
+		}
+		if d.reload(s) {
+			continue
+		}
+
+		select {
+		case <-s.stopCh:
+			d.stopped(s)
+			return
+		case <-s.wakeCh:
+		case dl := <-s.queue:
+			d.deliver(s, dl)
+		}
+	}
+}
+
+// stopped 订阅者被删除时丢弃其所有投递，Dispatcher停止时保留outbox
+func (d *Dispatcher) stopped(s *subscriber) {
+	if cur, ok := d.getSubscriber(s.conf.Name); ok && cur == s {
+		return
+	}
+	for {
+		select {
+		case dl := <-s.queue:
+			d.drop(dl)
+		default:
+			s.spillMu.Lock()
+			defer s.spillMu.Unlock()
+			if !s.spilled || d.outbox == nil {
+				return
+			}
+			pending, err := d.outbox.load(pendingDir)
+			if err != nil {
+				logx.Errorf("load webhook deliveries of subscriber [%s] error: %v", s.conf.Name, err)
+				return
+			}
+			for _, dl := range pending {
+				if dl.Subscriber == s.conf.Name {
+					d.drop(dl)
+				}
+			}
+			return
+		}
+	}
+}
+
+// drop 丢弃订阅者已被删除的投递
+func (d *Dispatcher) drop(dl *Delivery) {
+	logx.Infof("webhook subscriber [%s] is removed, drop delivery [%s]", dl.Subscriber, dl.ID)
+	d.unpersist(pendingDir, dl.ID)
+	metrics.Client.WebhookDelivery(dl.Subscriber, metrics.WebhookDropped)
+}
+
+// deliver 投递已保存在outbox中的dl，每次失败后更新outbox
+func (d *Dispatcher) deliver(s *subscriber, dl *Delivery) {
+	finish := func(fn func(stats *SubscriberStats)) {
+		s.updateStats(func(stats *SubscriberStats) {
+			stats.Pending--
+			if fn != nil {
+				fn(stats)
+			}
+		})
+	}
+
+	done := false
+	wait.BackoffUntil(func() (bool, error) {
+		dl.Attempts++
+		dl.LastAttemptAt = time.Now()
+		err := d.post(s, dl)
+		if err == nil {
+			done = true
+			d.unpersist(pendingDir, dl.ID)
+			metrics.Client.WebhookDelivery(dl.Subscriber, metrics.WebhookDelivered)
+			finish(func(stats *SubscriberStats) {
+				stats.Delivered++
+				stats.LastDeliveredAt = dl.LastAttemptAt
+			})
+			return true, nil
+		}
+
+		dl.LastError = err.Error()
+		metrics.Client.WebhookDelivery(dl.Subscriber, metrics.WebhookFailed)
+		s.updateStats(func(stats *SubscriberStats) {
+			stats.Failed++
+			stats.LastError = dl.LastError
+			stats.LastErrorAt = dl.LastAttemptAt
+		})
+		logx.Errorf("webhook delivery [%s] of event [%s] to [%s] attempt %d error: %v",
+			dl.ID, dl.Event.Type, dl.Subscriber, dl.Attempts, err)
+		if dl.Attempts >= d.conf.MaxAttempts {
+			done = true
+			d.addDeadLetter(dl)
+			metrics.Client.WebhookDelivery(dl.Subscriber, metrics.WebhookDeadLetter)
+			finish(func(stats *SubscriberStats) { stats.DeadLetters++ })
+			return true, nil
+		}
+		d.persist(pendingDir, dl)
+		return false, err
+	}, wait.NewFastBackoffManager(wait.FastBackoffOptions{
+		Duration:           time.Second,
+		InitDurationIfFail: time.Second,
+		Factor:             2,
+		Jitter:             0.2,
+		MaxDuration:        d.conf.MaxBackoff,
+	}), true, s.stopCh)
+
+	if !done {
+		if cur, ok := d.getSubscriber(s.conf.Name); !ok || cur != s {
+			d.drop(dl)
+		}
+	}
+}
+
+func (d *Dispatcher) addDeadLetter(dl *Delivery) {
+	logx.Errorf("webhook delivery [%s] failed after %d attempts, move it to dead letters", dl.ID, dl.Attempts)
+	d.persist(deadDir, dl)
+	d.unpersist(pendingDir, dl.ID)
+
+	d.mu.Lock()
+	d.deadLetters = append(d.deadLetters, dl)
+	var dropped []*Delivery
+	if d.conf.MaxDeadLetters > 0 && len(d.deadLetters) > d.conf.MaxDeadLetters {
+		n := len(d.deadLetters) - d.conf.MaxDeadLetters
+		dropped = slices.Clone(d.deadLetters[:n])
+		d.deadLetters = slices.Delete(d.deadLetters, 0, n)
+	}
+	d.mu.Unlock()
+
+	for _, old := range dropped {
+		d.unpersist(deadDir, old.ID)
+	}
+}
+
+func (d *Dispatcher) post(s *subscriber, dl *Delivery) (err error) {
+	ctx, span := tracing.Start(context.Background(), "webhook.deliver", trace.WithAttributes(
+		attribute.String("webhook.subscriber", dl.Subscriber),
+		attribute.String("webhook.delivery_id", dl.ID),
+		attribute.String("event.id", dl.Event.ID),
+		attribute.String("event.type", string(dl.Event.Type)),
+		attribute.Int("webhook.attempt", dl.Attempts),
+	), trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	body, contentType, err := s.encoder.Encode(dl.Event)
+	if err != nil {
+		return fmt.Errorf("encode event error: %v", err)
+	}
+
+	req := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", contentType).
+		SetHeaders(s.conf.Headers).
+		SetBody(body)
+	if len(s.secrets) > 0 {
+		signature.SignRequest(req.Header, s.secrets, time.Now(), body)
+	}
+	// let the receiver continue the trace
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := req.Post(dl.URL)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode()))
+	logx.Debugf("webhook response: %v", string(resp.Body()))
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status())
+	}
+	return nil
+}
+
+func (d *Dispatcher) persist(kind string, dl *Delivery) {
+	if d.outbox == nil {
+		return
+	}
+	if err := d.outbox.save(kind, dl); err != nil {
+		logx.Errorf("save webhook delivery [%s] error: %v", dl.ID, err)
+	}
+}
+
+func (d *Dispatcher) unpersist(kind string, id string) {
+	if d.outbox == nil {
+		return
+	}
+	if err := d.outbox.remove(kind, id); err != nil {
+		logx.Errorf("remove webhook delivery [%s] error: %v", id, err)
+	}
+}
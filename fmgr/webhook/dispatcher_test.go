@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"frpgo/config"
+	"frpgo/fmgr/webhook/signature"
+)
+
+type testReceiver struct {
+	// 前failN次请求返回500
+	failN    atomic.Int32
+	received atomic.Int32
+	lastType atomic.Value
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.failN.Add(-1) >= 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	e := &Event{}
+	_ = json.NewDecoder(req.Body).Decode(e)
+	r.lastType.Store(e.Type)
+	r.received.Add(1)
+}
+
+func testConf(url string, dir string) config.WebhookConf {
+	return config.WebhookConf{
+		Url:         url,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		MaxBackoff:  10 * time.Millisecond,
+		OutboxDir:   dir,
+	}
+}
+
+func pendingFiles(t *testing.T, dir string) int {
+	entries, err := os.ReadDir(filepath.Join(dir, pendingDir))
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	require := require.New(t)
+	receiver := &testReceiver{}
+	receiver.failN.Store(2)
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d := NewDispatcher(testConf(srv.URL, dir))
+	require.NoError(d.Start())
+	defer d.Stop()
+
+	d.Push(NewEvent(EventProxyStarted, "run"))
+	require.Eventually(func() bool { return receiver.received.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(EventProxyStarted, receiver.lastType.Load())
+	require.Eventually(func() bool { return pendingFiles(t, dir) == 0 }, time.Second, 10*time.Millisecond)
+	require.Empty(d.DeadLetters())
+}
+
+func TestDispatcherDeadLetterAndRedrive(t *testing.T) {
+	require := require.New(t)
+	receiver := &testReceiver{}
+	receiver.failN.Store(1 << 20)
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d := NewDispatcher(testConf(srv.URL, dir))
+	require.NoError(d.Start())
+	d.Push(NewEvent(EventProxyClosed, "run"))
+	require.Eventually(func() bool { return len(d.DeadLetters()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	d.Stop()
+
+	dead := d.DeadLetters()[0]
+	require.Equal(3, dead.Attempts)
+	require.NotEmpty(dead.LastError)
+	require.Equal(0, pendingFiles(t, dir))
+
+	// 重启后死信仍在
+	receiver.failN.Store(0)
+	d = NewDispatcher(testConf(srv.URL, dir))
+	require.NoError(d.Start())
+	defer d.Stop()
+	require.Len(d.DeadLetters(), 1)
+
+	require.ErrorIs(d.Redrive("unknown"), ErrDeliveryNotFound)
+	require.NoError(d.Redrive(dead.ID))
+	require.Eventually(func() bool { return receiver.received.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Empty(d.DeadLetters())
+	require.Eventually(func() bool { return pendingFiles(t, dir) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcherResumePending(t *testing.T) {
+	require := require.New(t)
+	receiver := &testReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	ob := &outbox{dir: dir}
+	require.NoError(ob.save(pendingDir, &Delivery{
+		ID:        "1",
+		URL:       srv.URL,
+		Event:     NewEvent(EventControlLogin, "run"),
+		CreatedAt: time.Now(),
+	}))
+
+	d := NewDispatcher(testConf(srv.URL, dir))
+	require.NoError(d.Start())
+	defer d.Stop()
+	require.Eventually(func() bool { return receiver.received.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(EventControlLogin, receiver.lastType.Load())
+}
+
+func TestDispatcherSign(t *testing.T) {
+	require := require.New(t)
+	verified := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := signature.VerifyRequest(r, []string{"old"}, 0)
+		verified <- err
+	}))
+	defer srv.Close()
+
+	c := testConf(srv.URL, "")
+	c.Secret = "new"
+	c.Secrets = []string{"old"}
+	d := NewDispatcher(c)
+	require.NoError(d.Start())
+	defer d.Stop()
+
+	d.Push(NewEvent(EventProxyCreated, "run"))
+	select {
+	case err := <-verified:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received")
+	}
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	require := require.New(t)
+	release := make(chan struct{})
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		e := &Event{}
+		_ = json.NewDecoder(r.Body).Decode(e)
+		mu.Lock()
+		received = append(received, e.ID)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := testConf(srv.URL, dir)
+	c.QueueSize = 2
+	d := NewDispatcher(c)
+	require.NoError(d.Start())
+	defer d.Stop()
+
+	// 第一个事件投递中，后两个在队列中，其余只保存在outbox中，Push不阻塞
+	var pushed []string
+	push := func() {
+		e := NewEvent(EventProxyStarted, "run")
+		pushed = append(pushed, e.ID)
+		d.Push(e)
+	}
+	push()
+	require.Eventually(func() bool { return d.Subscribers()[0].Stats.Pending == 1 }, time.Second, 10*time.Millisecond)
+	for i := 0; i < 10; i++ {
+		push()
+	}
+	require.Equal(int64(11), d.Subscribers()[0].Stats.Pending)
+	require.Equal(11, pendingFiles(t, dir))
+
+	// 全部按顺序投递
+	close(release)
+	require.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 11
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(pushed, received)
+	require.Eventually(func() bool { return d.Subscribers()[0].Stats.Pending == 0 }, time.Second, 10*time.Millisecond)
+	require.Eventually(func() bool { return pendingFiles(t, dir) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcherQueueFullRestart(t *testing.T) {
+	require := require.New(t)
+	receiver := &testReceiver{}
+	receiver.failN.Store(1 << 20)
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := testConf(srv.URL, dir)
+	c.QueueSize = 1
+	c.MaxAttempts = 1 << 20
+	d := NewDispatcher(c)
+	require.NoError(d.Start())
+	for i := 0; i < 5; i++ {
+		d.Push(NewEvent(EventProxyStarted, "run"))
+	}
+	// 投递失败期间入队及溢出的投递都已保存
+	require.Equal(5, pendingFiles(t, dir))
+	d.Stop()
+
+	receiver.failN.Store(0)
+	d = NewDispatcher(c)
+	require.NoError(d.Start())
+	defer d.Stop()
+	require.Eventually(func() bool { return receiver.received.Load() == 5 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(func() bool { return pendingFiles(t, dir) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcherQueueFullWithoutOutbox(t *testing.T) {
+	require := require.New(t)
+	release := make(chan struct{})
+	var started atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Add(1)
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := testConf(srv.URL, "")
+	c.QueueSize = 2
+	d := NewDispatcher(c)
+	require.NoError(d.Start())
+	defer d.Stop()
+
+	// 第一个事件投递中，后两个在队列中，其余被丢弃
+	d.Push(NewEvent(EventProxyStarted, "run"))
+	require.Eventually(func() bool { return started.Load() == 1 }, time.Second, 10*time.Millisecond)
+	for i := 0; i < 10; i++ {
+		d.Push(NewEvent(EventProxyStarted, "run"))
+	}
+	require.Equal(int64(3), d.Subscribers()[0].Stats.Pending)
+}
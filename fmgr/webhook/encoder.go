@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"text/template"
+	"text/template/parse"
+
+	"frpgo/config"
+	pkgconfig "frpgo/pkg/config"
+)
+
+const (
+	FormatRaw         = "raw"
+	FormatCloudEvents = "cloudevents"
+	FormatTemplate    = "template"
+
+	contentTypeJSON        = "application/json"
+	contentTypeCloudEvents = "application/cloudevents+json; charset=UTF-8"
+)
+
+// Encoder 将事件编码为请求body
+type Encoder interface {
+	// Encode 返回body及其Content-Type
+	Encode(e *Event) ([]byte, string, error)
+}
+
+// newEncoder 仅配置文件中的订阅者（static）的模板可引用环境变量
+func newEncoder(c config.WebhookSubscriberConf, static bool) (Encoder, error) {
+	switch c.Format {
+	case "", FormatRaw:
+		return rawEncoder{}, nil
+	case FormatCloudEvents:
+		return cloudEventsEncoder{}, nil
+	case FormatTemplate:
+		return newTemplateEncoder(c.Template, c.ContentType, static)
+	}
+	return nil, fmt.Errorf("unsupported format [%s]", c.Format)
+}
+
+// rawEncoder 事件json
+type rawEncoder struct{}
+
+func (rawEncoder) Encode(e *Event) ([]byte, string, error) {
+	body, err := json.Marshal(e)
+	return body, contentTypeJSON, err
+}
+
+// cloudEvent CloudEvents 1.0 structured mode, https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/formats/json-format.md
+type cloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	Data            *Event `json:"data"`
+}
+
+type cloudEventsEncoder struct{}
+
+func (cloudEventsEncoder) Encode(e *Event) ([]byte, string, error) {
+	source := "/frpgo"
+	if e.RunID != "" {
+		source += "/" + e.RunID
+	}
+	subject := e.ProxyName
+	if subject == "" {
+		subject = e.VisitorName
+	}
+	body, err := json.Marshal(&cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              e.ID,
+		Source:          source,
+		Type:            "frpgo." + string(e.Type),
+		Subject:         subject,
+		Time:            e.Timestamp.UTC().Format("2006-01-02T15:04:05.999999999Z07:00"),
+		DataContentType: contentTypeJSON,
+		Data:            e,
+	})
+	return body, contentTypeCloudEvents, err
+}
+
+// TemplateData 模板数据，可直接引用事件字段如{{ .ProxyName }}，环境变量通过{{ .Envs.NAME }}引用。
+// 环境变量中可能有token等凭据，通过接口添加的订阅者不能引用
+type TemplateData struct {
+	*Event
+	Envs map[string]string
+}
+
+// templateEncoder 使用text/template渲染body，除配置模板的函数外提供json函数
+type templateEncoder struct {
+	tmpl        *template.Template
+	contentType string
+	allowEnvs   bool
+}
+
+func newTemplateEncoder(text string, contentType string, allowEnvs bool) (*templateEncoder, error) {
+	if text == "" {
+		return nil, fmt.Errorf("template is required")
+	}
+	funcs := template.FuncMap(pkgconfig.TemplateFuncs())
+	funcs["json"] = func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	tmpl, err := template.New("webhook").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template error: %v", err)
+	}
+	if !allowEnvs {
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil && usesEnvs(t.Tree.Root) {
+				return nil, fmt.Errorf(".Envs is only allowed in templates of subscribers in config file")
+			}
+		}
+	}
+	if contentType == "" {
+		contentType = contentTypeJSON
+	}
+	return &templateEncoder{tmpl: tmpl, contentType: contentType, allowEnvs: allowEnvs}, nil
+}
+
+func (t *templateEncoder) Encode(e *Event) ([]byte, string, error) {
+	data := &TemplateData{Event: e}
+	if t.allowEnvs {
+		data.Envs = pkgconfig.GetValues().Envs
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := t.tmpl.Execute(buf, data); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), t.contentType, nil
+}
+
+// usesEnvs 模板是否引用了Envs字段，如.Envs.NAME、$.Envs或(.).Envs
+func usesEnvs(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Nodes {
+			if usesEnvs(c) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return usesEnvs(n.Pipe)
+	case *parse.IfNode:
+		return usesEnvs(&n.BranchNode)
+	case *parse.RangeNode:
+		return usesEnvs(&n.BranchNode)
+	case *parse.WithNode:
+		return usesEnvs(&n.BranchNode)
+	case *parse.BranchNode:
+		return usesEnvs(n.Pipe) || usesEnvs(n.List) || usesEnvs(n.ElseList)
+	case *parse.TemplateNode:
+		return usesEnvs(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Cmds {
+			if usesEnvs(c) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if usesEnvs(arg) {
+				return true
+			}
+		}
+	case *parse.FieldNode:
+		return slices.Contains(n.Ident, "Envs")
+	case *parse.VariableNode:
+		return slices.Contains(n.Ident, "Envs")
+	case *parse.ChainNode:
+		return slices.Contains(n.Field, "Envs") || usesEnvs(n.Node)
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"frpgo/config"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func testEvent() *Event {
+	return &Event{
+		ID:        "6f1c2e",
+		Type:      EventProxyStartError,
+		Timestamp: time.Date(2024, 5, 1, 8, 30, 0, 0, time.FixedZone("CST", 8*3600)),
+		RunID:     "a1b2c3",
+		ProxyName: "web",
+		PrevPhase: "wait start",
+		Phase:     "start error",
+		Error:     "port already used",
+	}
+}
+
+const slackTemplate = `{"text": {{ printf "[%s] %s: %s -> %s" .Type .ProxyName .PrevPhase .Phase | json }}` +
+	`{{ if .Error }}, "attachments": [{"color": "danger", "text": {{ json .Error }}}]{{ end }}}`
+
+func TestEncoders(t *testing.T) {
+	cases := []struct {
+		name        string
+		conf        config.WebhookSubscriberConf
+		contentType string
+	}{
+		{"raw", config.WebhookSubscriberConf{}, "application/json"},
+		{"cloudevents", config.WebhookSubscriberConf{Format: FormatCloudEvents}, "application/cloudevents+json; charset=UTF-8"},
+		{"template", config.WebhookSubscriberConf{Format: FormatTemplate, Template: slackTemplate}, "application/json"},
+		{"template_text", config.WebhookSubscriberConf{
+			Format:      FormatTemplate,
+			Template:    "{{ .Type }} {{ .ProxyName }} at {{ .Timestamp.UTC.Format \"15:04:05\" }}\n",
+			ContentType: "text/plain",
+		}, "text/plain"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require := require.New(t)
+			encoder, err := newEncoder(c.conf, true)
+			require.NoError(err)
+			body, contentType, err := encoder.Encode(testEvent())
+			require.NoError(err)
+			require.Equal(c.contentType, contentType)
+
+			golden := filepath.Join("testdata", c.name+".golden")
+			if *update {
+				require.NoError(os.WriteFile(golden, body, 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(err)
+			require.Equal(string(expected), string(body))
+		})
+	}
+}
+
+func TestTemplateEncoderError(t *testing.T) {
+	require := require.New(t)
+	_, err := newEncoder(config.WebhookSubscriberConf{Format: FormatTemplate}, true)
+	require.Error(err)
+	_, err = newEncoder(config.WebhookSubscriberConf{Format: FormatTemplate, Template: "{{ .Type "}, true)
+	require.Error(err)
+	_, err = newEncoder(config.WebhookSubscriberConf{Format: "xml"}, true)
+	require.Error(err)
+}
+
+func TestTemplateEncoderEnvs(t *testing.T) {
+	require := require.New(t)
+
+	// 通过接口添加的订阅者不能引用环境变量
+	for _, text := range []string{
+		"{{ .Envs.FRP_TOKEN }}",
+		"{{ $.Envs }}",
+		"{{ if .Error }}{{ json .Envs }}{{ end }}",
+		"{{ with .Event }}{{ end }}{{ range $k, $v := (.).Envs }}{{ $v }}{{ end }}",
+		`{{ define "t" }}{{ .Envs }}{{ end }}{{ template "t" . }}`,
+	} {
+		_, err := newEncoder(config.WebhookSubscriberConf{Format: FormatTemplate, Template: text}, false)
+		require.Error(err, text)
+	}
+
+	encoder, err := newEncoder(config.WebhookSubscriberConf{Format: FormatTemplate, Template: "{{ .Type }}"}, false)
+	require.NoError(err)
+	body, _, err := encoder.Encode(testEvent())
+	require.NoError(err)
+	require.Equal("proxy.start_error", string(body))
+
+	// 配置文件中的订阅者可以引用
+	_, err = newEncoder(config.WebhookSubscriberConf{Format: FormatTemplate, Template: "{{ .Envs.FRP_TOKEN }}"}, true)
+	require.NoError(err)
+}
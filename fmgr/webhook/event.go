@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"time"
+
+	"frpgo/pkg/util/util"
+)
+
+type EventType string
+
+const (
+	// 代理生命周期。created仅在代理被添加或从配置文件加载时推送，重连后重新注册不推送
+	EventProxyCreated     EventType = "proxy.created"
+	EventProxyWaitStart   EventType = "proxy.wait_start"
+	EventProxyStarted     EventType = "proxy.started"
+	EventProxyStartError  EventType = "proxy.start_error"
+	EventProxyCheckFailed EventType = "proxy.check_failed"
+	EventProxyClosed      EventType = "proxy.closed"
+	EventProxyPaused      EventType = "proxy.paused"
+	EventProxyResumed     EventType = "proxy.resumed"
+	// 代理配置被修改
+	EventProxyUpdated EventType = "proxy.updated"
+	// 健康检查状态变化
+	EventProxyHealthy   EventType = "proxy.healthy"
+	EventProxyUnhealthy EventType = "proxy.unhealthy"
+	// 创建时代理已存在
+	EventProxyExists EventType = "proxy.exists"
+	// 查询代理详情
+	EventProxyQueried EventType = "proxy.queried"
+
+	// 与服务端的控制连接
+	EventControlLogin       EventType = "control.login"
+	EventControlLoginFailed EventType = "control.login_failed"
+	EventControlClosed      EventType = "control.closed"
+	EventControlReconnected EventType = "control.reconnected"
+	// 控制连接切换到另一个服务端，Detail为client.FailoverRecord
+	EventControlFailover EventType = "control.failover"
+
+	EventVisitorStarted    EventType = "visitor.started"
+	EventVisitorStartError EventType = "visitor.start_error"
+	EventVisitorStopped    EventType = "visitor.stopped"
+
+	// http隧道抓取到请求，Detail为请求摘要
+	EventRequestCaptured EventType = "request.captured"
+)
+
+// Event webhook推送的事件
+type Event struct {
+	// 唯一id，重试时不变，接收方可用于去重
+	ID          string    `json:"id"`
+	Type        EventType `json:"type"`
+	Timestamp   time.Time `json:"timestamp"`
+	RunID       string    `json:"run_id"`
+	ProxyName   string    `json:"proxy_name,omitempty"`
+	ProxyType   string    `json:"proxy_type,omitempty"`
+	VisitorName string    `json:"visitor_name,omitempty"`
+	VisitorType string    `json:"visitor_type,omitempty"`
+	PrevPhase   string    `json:"prev_phase,omitempty"`
+	Phase       string    `json:"phase,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	// 代理事件携带proxy.WorkingDetial
+	Detail any `json:"detail,omitempty"`
+}
+
+func NewEvent(typ EventType, runID string) *Event {
+	id, _ := util.RandID()
+	return &Event{
+		ID:        id,
+		Type:      typ,
+		Timestamp: time.Now(),
+		RunID:     runID,
+	}
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"frpgo/config"
+	"frpgo/fmgr/store"
+)
+
+const (
+	pendingDir = "pending"
+	deadDir    = "dead"
+)
+
+// outbox 每个投递保存为一个文件，待投递的在pending目录，投递失败的在dead目录
+type outbox struct {
+	dir string
+}
+
+func (o *outbox) path(kind string, id string) string {
+	return filepath.Join(o.dir, kind, id+".json")
+}
+
+func (o *outbox) save(kind string, d *Delivery) error {
+	content, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return store.WriteFileAtomic(o.path(kind, d.ID), content)
+}
+
+func (o *outbox) remove(kind string, id string) error {
+	err := os.Remove(o.path(kind, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// load 按创建时间返回kind目录下的投递，无法解析的文件被跳过
+func (o *outbox) load(kind string) ([]*Delivery, error) {
+	entries, err := os.ReadDir(filepath.Join(o.dir, kind))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*Delivery, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name := filepath.Join(o.dir, kind, entry.Name())
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		d := &Delivery{}
+		if err = json.Unmarshal(content, d); err != nil || d.ID == "" {
+			logx.Errorf("skip broken webhook delivery file [%s]: %v", name, err)
+			continue
+		}
+		// 升级前的投递没有订阅者
+		if d.Subscriber == "" {
+			d.Subscriber = DefaultSubscriber
+		}
+		out = append(out, d)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+const subscribersFile = "subscribers.json"
+
+// loadSubscribers 返回运行时添加的订阅者
+func (o *outbox) loadSubscribers() ([]config.WebhookSubscriberConf, error) {
+	content, err := os.ReadFile(filepath.Join(o.dir, subscribersFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var confs []config.WebhookSubscriberConf
+	if err = json.Unmarshal(content, &confs); err != nil {
+		return nil, fmt.Errorf("parse %s error: %v", subscribersFile, err)
+	}
+	return confs, nil
+}
+
+func (o *outbox) saveSubscribers(confs []config.WebhookSubscriberConf) error {
+	content, err := json.MarshalIndent(confs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.WriteFileAtomic(filepath.Join(o.dir, subscribersFile), content)
+}
@@ -0,0 +1,109 @@
+// Package signature signs and verifies webhook requests.
+//
+// Each request carries the unix timestamp in TimestampHeader and one signature per
+// secret in SignatureHeader, formatted as "v1=<hex>,v1=<hex>". A signature is the
+// hex encoded HMAC-SHA256 of "<timestamp>.<body>". Configuring both the old and the
+// new secret on the sender rotates the secret without rejecting any request.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimestampHeader = "X-Frpgo-Timestamp"
+	SignatureHeader = "X-Frpgo-Signature"
+
+	// DefaultTolerance is the max age of a request accepted by Verify.
+	DefaultTolerance = 5 * time.Minute
+
+	version = "v1"
+)
+
+var (
+	ErrNoSignature       = errors.New("no signature")
+	ErrInvalidTimestamp  = errors.New("invalid timestamp")
+	ErrTimestampExpired  = errors.New("timestamp out of tolerance")
+	ErrSignatureMismatch = errors.New("signature mismatch")
+)
+
+// Sign returns the hex encoded signature of body with secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header returns the value of SignatureHeader, with one signature for each secret.
+func Header(secrets []string, timestamp int64, body []byte) string {
+	sigs := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		sigs = append(sigs, version+"="+Sign(secret, timestamp, body))
+	}
+	return strings.Join(sigs, ",")
+}
+
+// SignRequest sets the timestamp and signature headers of a request with body.
+func SignRequest(header http.Header, secrets []string, now time.Time, body []byte) {
+	ts := now.Unix()
+	header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	header.Set(SignatureHeader, Header(secrets, ts, body))
+}
+
+// Verify checks that any signature in signatureHeader matches any of secrets,
+// and the timestamp is within tolerance of now. Zero tolerance means DefaultTolerance.
+func Verify(secrets []string, timestampHeader string, signatureHeader string, body []byte, tolerance time.Duration) error {
+	if signatureHeader == "" {
+		return ErrNoSignature
+	}
+	ts, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return ErrTimestampExpired
+	}
+
+	for _, secret := range secrets {
+		expected := []byte(Sign(secret, ts, body))
+		for _, sig := range strings.Split(signatureHeader, ",") {
+			v, s, ok := strings.Cut(strings.TrimSpace(sig), "=")
+			if !ok || v != version {
+				continue
+			}
+			if hmac.Equal(expected, []byte(s)) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest reads and verifies the body of r. The body is restored so it can be read again.
+func VerifyRequest(r *http.Request, secrets []string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err = Verify(secrets, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, tolerance); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,51 @@
+package signature
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	require := require.New(t)
+	body := []byte(`{"type":"proxy.started"}`)
+	now := time.Now().Unix()
+	ts := strconv.FormatInt(now, 10)
+
+	// rotating from old to new, the receiver only knows one of them
+	header := Header([]string{"new", "old"}, now, body)
+	require.NoError(Verify([]string{"old"}, ts, header, body, 0))
+	require.NoError(Verify([]string{"new"}, ts, header, body, 0))
+
+	require.ErrorIs(Verify([]string{"other"}, ts, header, body, 0), ErrSignatureMismatch)
+	require.ErrorIs(Verify([]string{"old"}, ts, header, []byte("{}"), 0), ErrSignatureMismatch)
+	require.ErrorIs(Verify([]string{"old"}, strconv.FormatInt(now+1, 10), header, body, 0), ErrSignatureMismatch)
+	require.ErrorIs(Verify([]string{"old"}, ts, "", body, 0), ErrNoSignature)
+	require.ErrorIs(Verify([]string{"old"}, "abc", header, body, 0), ErrInvalidTimestamp)
+
+	past := now - 3600
+	require.ErrorIs(Verify([]string{"old"}, strconv.FormatInt(past, 10), Header([]string{"old"}, past, body), body, time.Minute),
+		ErrTimestampExpired)
+}
+
+func TestVerifyRequest(t *testing.T) {
+	require := require.New(t)
+	body := []byte(`{"type":"proxy.closed"}`)
+
+	r, err := http.NewRequest("POST", "http://127.0.0.1/webhook", bytes.NewReader(body))
+	require.NoError(err)
+	SignRequest(r.Header, []string{"secret"}, time.Now(), body)
+
+	got, err := VerifyRequest(r, []string{"secret"}, 0)
+	require.NoError(err)
+	require.Equal(body, got)
+	// body is still readable
+	again, err := io.ReadAll(r.Body)
+	require.NoError(err)
+	require.Equal(body, again)
+}
@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"frpgo/config"
+)
+
+const (
+	// Url配置生成的订阅者名
+	DefaultSubscriber = "default"
+)
+
+var (
+	ErrSubscriberExist    = errors.New("webhook subscriber is already exist")
+	ErrSubscriberNotFound = errors.New("webhook subscriber not found")
+	ErrSubscriberStatic   = errors.New("webhook subscriber is defined in config file")
+	ErrInvalidSubscriber  = errors.New("invalid webhook subscriber")
+)
+
+type SubscriberStats struct {
+	Delivered int64 `json:"delivered"`
+	// 失败的尝试次数
+	Failed      int64 `json:"failed"`
+	DeadLetters int64 `json:"dead_letters"`
+	// 未完成的投递
+	Pending         int64     `json:"pending"`
+	LastDeliveredAt time.Time `json:"last_delivered_at"`
+	LastError       string    `json:"last_error"`
+	LastErrorAt     time.Time `json:"last_error_at"`
+}
+
+// SubscriberInfo 订阅者配置及投递统计
+type SubscriberInfo struct {
+	Conf config.WebhookSubscriberConf
+	// 是否来自配置文件，配置文件中的订阅者不能通过接口删除
+	Static bool
+	// 是否签名
+	Signed bool
+	Stats  SubscriberStats
+}
+
+type subscriber struct {
+	conf    config.WebhookSubscriberConf
+	static  bool
+	client  *resty.Client
+	encoder Encoder
+	secrets []string
+
+	// 待投递队列，由Dispatcher.run按顺序投递
+	queue chan *Delivery
+	// 队列满后新的投递只保存在outbox中，队列空出后由Dispatcher.run加载，
+	// 加载完之前的投递也不入队，以保持顺序
+	spilled bool
+	spillMu sync.Mutex
+	// 有溢出的投递时通知Dispatcher.run
+	wakeCh   chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
+	stats SubscriberStats
+	mu    sync.Mutex
+}
+
+// newSubscriber 校验配置，未配置的超时及密钥使用全局配置
+func newSubscriber(c config.WebhookSubscriberConf, global config.WebhookConf, static bool) (*subscriber, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSubscriber)
+	}
+	if c.Url == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidSubscriber)
+	}
+	for _, p := range append(append([]string{}, c.Events...), c.Names...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%w: bad pattern [%s]", ErrInvalidSubscriber, p)
+		}
+	}
+	encoder, err := newEncoder(c, static)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscriber, err)
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = global.Timeout
+	}
+	s := &subscriber{
+		conf:    c,
+		static:  static,
+		client:  resty.New().SetTimeout(timeout),
+		encoder: encoder,
+		stopCh:  make(chan struct{}),
+	}
+	if c.Secret != "" || len(c.Secrets) > 0 {
+		s.secrets = secretsOf(c.Secret, c.Secrets)
+	} else {
+		s.secrets = secretsOf(global.Secret, global.Secrets)
+	}
+	return s, nil
+}
+
+func secretsOf(secret string, secrets []string) []string {
+	out := make([]string, 0, len(secrets)+1)
+	if secret != "" {
+		out = append(out, secret)
+	}
+	return append(out, secrets...)
+}
+
+func (s *subscriber) match(e *Event) bool {
+	if len(s.conf.Events) > 0 && !matchAny(s.conf.Events, string(e.Type)) {
+		return false
+	}
+
+	name := e.ProxyName
+	if name == "" {
+		name = e.VisitorName
+	}
+	if len(s.conf.Names) > 0 && name != "" && !matchAny(s.conf.Names, name) {
+		return false
+	}
+	return true
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscriber) info() SubscriberInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SubscriberInfo{
+		Conf:   s.conf,
+		Static: s.static,
+		Signed: len(s.secrets) > 0,
+		Stats:  s.stats,
+	}
+}
+
+// wake 通知Dispatcher.run加载溢出的投递
+func (s *subscriber) wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+func (s *subscriber) updateStats(fn func(stats *SubscriberStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.stats)
+}
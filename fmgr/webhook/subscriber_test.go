@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"frpgo/config"
+)
+
+func TestSubscriberMatch(t *testing.T) {
+	require := require.New(t)
+	s, err := newSubscriber(config.WebhookSubscriberConf{
+		Name:   "audit",
+		Url:    "http://127.0.0.1",
+		Events: []string{"proxy.*", "visitor.started"},
+		Names:  []string{"web-*"},
+	}, config.WebhookConf{}, false)
+	require.NoError(err)
+
+	event := func(typ EventType, proxyName string) *Event {
+		e := NewEvent(typ, "")
+		e.ProxyName = proxyName
+		return e
+	}
+	require.True(s.match(event(EventProxyStarted, "web-1")))
+	require.False(s.match(event(EventProxyStarted, "ssh")))
+	require.False(s.match(event(EventControlLogin, "")))
+	require.False(s.match(event(EventVisitorStopped, "")))
+
+	s, err = newSubscriber(config.WebhookSubscriberConf{Name: "all", Url: "http://127.0.0.1", Names: []string{"web"}},
+		config.WebhookConf{}, false)
+	require.NoError(err)
+	// events without name are not filtered by names
+	require.True(s.match(event(EventControlLogin, "")))
+
+	_, err = newSubscriber(config.WebhookSubscriberConf{Name: "bad", Url: "http://127.0.0.1", Events: []string{"["}},
+		config.WebhookConf{}, false)
+	require.ErrorIs(err, ErrInvalidSubscriber)
+	_, err = newSubscriber(config.WebhookSubscriberConf{Name: "bad"}, config.WebhookConf{}, false)
+	require.ErrorIs(err, ErrInvalidSubscriber)
+}
+
+func TestDispatcherSubscribers(t *testing.T) {
+	require := require.New(t)
+	type received struct {
+		typ    EventType
+		header string
+	}
+	newServer := func(ch chan received) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e := &Event{}
+			_ = json.NewDecoder(r.Body).Decode(e)
+			ch <- received{typ: e.Type, header: r.Header.Get("X-Token")}
+		}))
+	}
+	orchestratorCh := make(chan received, 10)
+	orchestrator := newServer(orchestratorCh)
+	defer orchestrator.Close()
+	auditCh := make(chan received, 10)
+	audit := newServer(auditCh)
+	defer audit.Close()
+
+	dir := t.TempDir()
+	c := testConf("", dir)
+	c.Subscribers = []config.WebhookSubscriberConf{{
+		Name:    "orchestrator",
+		Url:     orchestrator.URL,
+		Events:  []string{"proxy.started"},
+		Headers: map[string]string{"X-Token": "abc"},
+	}}
+	d := NewDispatcher(c)
+	require.NoError(d.Start())
+
+	require.NoError(d.AddSubscriber(config.WebhookSubscriberConf{Name: "audit", Url: audit.URL}))
+	require.ErrorIs(d.AddSubscriber(config.WebhookSubscriberConf{Name: "audit", Url: audit.URL}), ErrSubscriberExist)
+
+	d.Push(NewEvent(EventProxyCreated, ""))
+	d.Push(NewEvent(EventProxyStarted, ""))
+
+	got := <-orchestratorCh
+	require.Equal(EventProxyStarted, got.typ)
+	require.Equal("abc", got.header)
+	types := []EventType{(<-auditCh).typ, (<-auditCh).typ}
+	require.ElementsMatch([]EventType{EventProxyCreated, EventProxyStarted}, types)
+	select {
+	case r := <-orchestratorCh:
+		t.Fatalf("unexpected event %s", r.typ)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.Eventually(func() bool {
+		subs := d.Subscribers()
+		return len(subs) == 2 && subs[0].Stats.Delivered == 2 && subs[1].Stats.Delivered == 1
+	}, time.Second, 10*time.Millisecond)
+	subs := d.Subscribers()
+	require.Equal("audit", subs[0].Conf.Name)
+	require.False(subs[0].Static)
+	require.True(subs[1].Static)
+	require.Zero(subs[0].Stats.Pending)
+
+	require.ErrorIs(d.RemoveSubscriber("orchestrator"), ErrSubscriberStatic)
+	d.Stop()
+
+	// runtime subscribers are restored after restart
+	d = NewDispatcher(c)
+	require.NoError(d.Start())
+	defer d.Stop()
+	require.Len(d.Subscribers(), 2)
+	require.NoError(d.RemoveSubscriber("audit"))
+	require.ErrorIs(d.RemoveSubscriber("audit"), ErrSubscriberNotFound)
+	require.Len(d.Subscribers(), 1)
+}
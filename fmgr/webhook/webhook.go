@@ -1,60 +1,90 @@
 package webhook
 
 import (
+	"errors"
+
 	"frpgo/config"
-	"frpgo/pkg2/utils2"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
-var (
-	whClient *resty.Client
-	whUrl    string
-)
+var ErrNotSetup = errors.New("webhook is not setup")
 
-func init() {
-	whClient = resty.New()
-}
+// Setup前为nil
+var std *Dispatcher
 
-func Setup(c config.Config) {
-	whUrl = c.Webhook.Url
+// 事件流，Setup前使用默认缓冲大小
+var broker = NewBroker(0)
 
-	logx.Debugf("Setup url: %v", whUrl)
+func Setup(c config.Config) error {
+	logx.Debugf("Setup url: %v, subscribers: %d", c.Webhook.Url, len(c.Webhook.Subscribers))
+
+	broker = NewBroker(c.Events.BufferSize)
+
+	d := NewDispatcher(c.Webhook)
+	if err := d.Start(); err != nil {
+		return err
+	}
+	std = d
+	return nil
 }
 
-func PushProxyDetail(data interface{}) {
-	go webhook(whUrl, data)
+// Push 投递事件给匹配的订阅者及事件流
+func Push(e *Event) {
+	broker.Publish(e)
+	if std == nil {
+		return
+	}
+	std.Push(e)
 }
 
-func webhook(url string, data interface{}) error {
-	logx.Debugf("PushProxyDetail url: %v, data: %v", url, data)
+func Subscribers() []SubscriberInfo {
+	if std == nil {
+		return []SubscriberInfo{}
+	}
+	return std.Subscribers()
+}
 
-	r, err := post(url, data)
-	if err != nil {
-		rstr := utils2.PrettyJson(r)
-		logx.Errorf("err: %v, resp: %v", err, rstr)
-		return err
+func AddSubscriber(c config.WebhookSubscriberConf) error {
+	if std == nil {
+		return ErrNotSetup
 	}
+	return std.AddSubscriber(c)
+}
 
-	return nil
+func RemoveSubscriber(name string) error {
+	if std == nil {
+		return ErrSubscriberNotFound
+	}
+	return std.RemoveSubscriber(name)
 }
 
-func post(url string, data interface{}) (r *resty.Response, e error) {
-	request, err := whClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(data).
-		Post(url)
-	if err != nil {
-		return request, err
+func DeadLetters() []*Delivery {
+	if std == nil {
+		return []*Delivery{}
 	}
+	return std.DeadLetters()
+}
 
-	logx.Debugf("post response : %v", string(request.Body()))
+func Redrive(id string) error {
+	if std == nil {
+		return ErrDeliveryNotFound
+	}
+	return std.Redrive(id)
+}
 
-	statusCode := request.StatusCode()
-	if statusCode == 0 {
-		return request, err
+func RedriveAll() int {
+	if std == nil {
+		return 0
 	}
+	return std.RedriveAll()
+}
+
+// Subscribe 订阅事件流，见Broker.Subscribe
+func Subscribe(filter StreamFilter, lastSeq uint64) (*Stream, []StreamEvent, bool) {
+	return broker.Subscribe(filter, lastSeq)
+}
 
-	return request, err
+func Unsubscribe(s *Stream) {
+	broker.Unsubscribe(s)
 }
@@ -19,6 +19,14 @@ import (
 	"frpgo/pkg/util/util"
 )
 
+// TemplateFuncs returns the functions available in config templates.
+func TemplateFuncs() map[string]any {
+	return map[string]any{
+		"parseNumberRange":     parseNumberRange,
+		"parseNumberRangePair": parseNumberRangePair,
+	}
+}
+
 type NumberPair struct {
 	First  int64
 	Second int64
@@ -15,10 +15,13 @@
 package v1
 
 import (
-	"frpgo/pkg/util/util"
+	"net"
 	"os"
+	"strconv"
 
 	"github.com/samber/lo"
+
+	"frpgo/pkg/util/util"
 )
 
 type ClientConfig struct {
@@ -43,6 +46,13 @@ type ClientCommonConfig struct {
 	// ServerPort specifies the port to connect to the server on. By default,
 	// this value is 7000.
 	ServerPort int `json:"serverPort,omitempty"`
+	// Servers specifies a list of servers to fail over between. If it is not
+	// empty, ServerAddr and ServerPort are ignored.
+	Servers []ServerEndpointConfig `json:"servers,omitempty"`
+	// ServerStrategy specifies how to choose a server from Servers to login.
+	// Valid values are "ordered" and "weighted". By default, this value is
+	// "ordered".
+	ServerStrategy string `json:"serverStrategy,omitempty"`
 	// STUN server to help penetrate NAT hole.
 	NatHoleSTUNServer string `json:"natHoleStunServer,omitempty"`
 	// DNSServer specifies a DNS server address for FRPC to use. If this value
@@ -77,6 +87,11 @@ func (c *ClientCommonConfig) Complete() {
 	c.LoginFailExit = util.EmptyOr(c.LoginFailExit, lo.ToPtr(true))
 	c.NatHoleSTUNServer = util.EmptyOr(c.NatHoleSTUNServer, "stun.easyvoip.com:3478")
 
+	c.ServerStrategy = util.EmptyOr(c.ServerStrategy, "ordered")
+	for i := range c.Servers {
+		c.Servers[i].Complete()
+	}
+
 	c.Auth.Complete()
 	c.Log.Complete()
 	c.Transport.Complete()
@@ -85,11 +100,50 @@ func (c *ClientCommonConfig) Complete() {
 	c.UDPPacketSize = util.EmptyOr(c.UDPPacketSize, 1500)
 }
 
+// ServerEndpointConfig is one of the servers to fail over between. The
+// transport and auth settings of the common config are used if Transport or
+// Auth is not set.
+type ServerEndpointConfig struct {
+	// Name identifies the server in status and logs. By default, this value
+	// is "{serverAddr}:{serverPort}".
+	Name       string `json:"name,omitempty"`
+	ServerAddr string `json:"serverAddr,omitempty"`
+	// ServerPort specifies the port to connect to the server on. By default,
+	// this value is 7000.
+	ServerPort int `json:"serverPort,omitempty"`
+	// Weight is used by the "weighted" server strategy, servers with a higher
+	// weight are chosen more often. By default, this value is 1.
+	Weight    int                    `json:"weight,omitempty"`
+	Transport *ClientTransportConfig `json:"transport,omitempty"`
+	Auth      *AuthClientConfig      `json:"auth,omitempty"`
+}
+
+func (c *ServerEndpointConfig) Complete() {
+	c.ServerPort = util.EmptyOr(c.ServerPort, 7000)
+	c.Name = util.EmptyOr(c.Name, net.JoinHostPort(c.ServerAddr, strconv.Itoa(c.ServerPort)))
+	c.Weight = util.EmptyOr(c.Weight, 1)
+	if c.Transport != nil {
+		c.Transport.Complete()
+	}
+	if c.Auth != nil {
+		c.Auth.Complete()
+	}
+}
+
 type ClientTransportConfig struct {
 	// Protocol specifies the protocol to use when interacting with the server.
 	// Valid values are "tcp", "kcp", "quic", "websocket" and "wss". By default, this value
 	// is "tcp".
 	Protocol string `json:"protocol,omitempty"`
+	// Protocols specifies the protocols to fall back through in order, such as
+	// ["quic", "tcp", "wss"]. If it is set, Protocol is ignored. When the
+	// connection with a protocol fails, the next one is tried, and the more
+	// preferred protocols are probed every ProtocolProbeInterval seconds.
+	Protocols []string `json:"protocols,omitempty"`
+	// ProtocolProbeInterval specifies the interval in seconds to probe the more
+	// preferred protocols after falling back. By default, this value is 300.
+	// Set negative value to disable it.
+	ProtocolProbeInterval int64 `json:"protocolProbeInterval,omitempty"`
 	// The maximum amount of time a dial to server will wait for a connect to complete.
 	DialServerTimeout int64 `json:"dialServerTimeout,omitempty"`
 	// DialServerKeepAlive specifies the interval between keep-alive probes for an active network connection between frpc and frps.
@@ -115,6 +169,8 @@ type ClientTransportConfig struct {
 	TCPMuxKeepaliveInterval int64 `json:"tcpMuxKeepaliveInterval,omitempty"`
 	// QUIC protocol options.
 	QUIC QUICOptions `json:"quic,omitempty"`
+	// KCP protocol options.
+	KCP KCPOptions `json:"kcp,omitempty"`
 	// HeartBeatInterval specifies at what interval heartbeats are sent to the
 	// server, in seconds. It is not recommended to change this value. By
 	// default, this value is 30. Set negative value to disable it.
@@ -128,7 +184,11 @@ type ClientTransportConfig struct {
 }
 
 func (c *ClientTransportConfig) Complete() {
+	if len(c.Protocols) > 0 {
+		c.Protocol = c.Protocols[0]
+	}
 	c.Protocol = util.EmptyOr(c.Protocol, "tcp")
+	c.ProtocolProbeInterval = util.EmptyOr(c.ProtocolProbeInterval, 300)
 	c.DialServerTimeout = util.EmptyOr(c.DialServerTimeout, 10)
 	c.DialServerKeepAlive = util.EmptyOr(c.DialServerKeepAlive, 7200)
 	c.ProxyURL = util.EmptyOr(c.ProxyURL, os.Getenv("http_proxy"))
@@ -144,6 +204,7 @@ func (c *ClientTransportConfig) Complete() {
 		c.HeartbeatTimeout = util.EmptyOr(c.HeartbeatTimeout, 90)
 	}
 	c.QUIC.Complete()
+	c.KCP.Complete()
 	c.TLS.Complete()
 }
 
@@ -15,8 +15,11 @@
 package v1
 
 import (
-	"frpgo/pkg/util/util"
 	"sync"
+
+	"github.com/samber/lo"
+
+	"frpgo/pkg/util/util"
 )
 
 // TODO(fatedier): Due to the current implementation issue of the go json library, the UnmarshalJSON method
@@ -58,6 +61,45 @@ func (c *QUICOptions) Complete() {
 	c.MaxIncomingStreams = util.EmptyOr(c.MaxIncomingStreams, 100000)
 }
 
+// KCP protocol options.
+// DataShards and ParityShards must be the same as the server, frps uses 10 and 3.
+type KCPOptions struct {
+	// NoDelay enables the nodelay mode of kcp. By default, this value is true.
+	NoDelay *bool `json:"noDelay,omitempty"`
+	// Interval specifies the internal update timer interval in milliseconds.
+	Interval int `json:"interval,omitempty"`
+	// Resend specifies the number of duplicate acks that trigger a fast
+	// resend. Set negative value to disable fast resend.
+	Resend int `json:"resend,omitempty"`
+	// NoCongestion disables the congestion control. By default, this value is true.
+	NoCongestion *bool `json:"noCongestion,omitempty"`
+	// SendWindow and RecvWindow specify the window sizes in packets.
+	SendWindow int `json:"sendWindow,omitempty"`
+	RecvWindow int `json:"recvWindow,omitempty"`
+	MTU        int `json:"mtu,omitempty"`
+	// DataShards and ParityShards specify the forward error correction
+	// shards. Set negative value to disable FEC.
+	DataShards   int `json:"dataShards,omitempty"`
+	ParityShards int `json:"parityShards,omitempty"`
+	// ReadBuffer and WriteBuffer specify the socket buffer sizes in bytes.
+	ReadBuffer  int `json:"readBuffer,omitempty"`
+	WriteBuffer int `json:"writeBuffer,omitempty"`
+}
+
+func (c *KCPOptions) Complete() {
+	c.NoDelay = util.EmptyOr(c.NoDelay, lo.ToPtr(true))
+	c.Interval = util.EmptyOr(c.Interval, 20)
+	c.Resend = util.EmptyOr(c.Resend, 2)
+	c.NoCongestion = util.EmptyOr(c.NoCongestion, lo.ToPtr(true))
+	c.SendWindow = util.EmptyOr(c.SendWindow, 128)
+	c.RecvWindow = util.EmptyOr(c.RecvWindow, 512)
+	c.MTU = util.EmptyOr(c.MTU, 1350)
+	c.DataShards = util.EmptyOr(c.DataShards, 10)
+	c.ParityShards = util.EmptyOr(c.ParityShards, 3)
+	c.ReadBuffer = util.EmptyOr(c.ReadBuffer, 4194304)
+	c.WriteBuffer = util.EmptyOr(c.WriteBuffer, 4194304)
+}
+
 type WebServerConfig struct {
 	// This is the network address to bind on for serving the web interface and API.
 	// By default, this value is "127.0.0.1".
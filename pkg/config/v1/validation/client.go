@@ -64,9 +64,9 @@ func ValidateClientCommonConfig(c *v1.ClientCommonConfig) (Warning, error) {
 		warnings = AppendError(warnings, checkTLSConfig("transport.tls.trustedCaFile", c.Transport.TLS.TrustedCaFile))
 	}
 
-	if !slices.Contains(SupportedTransportProtocols, c.Transport.Protocol) {
-		errs = AppendError(errs, fmt.Errorf("invalid transport.protocol, optional values are %v", SupportedTransportProtocols))
-	}
+	errs = AppendError(errs, validateTransportProtocols(&c.Transport, "transport"))
+
+	errs = AppendError(errs, validateServerEndpoints(c))
 
 	for _, f := range c.IncludeConfigFiles {
 		absDir, err := filepath.Abs(filepath.Dir(f))
@@ -104,3 +104,65 @@ func ValidateAllClientConfig(c *v1.ClientCommonConfig, proxyCfgs []v1.ProxyConfi
 	}
 	return warnings, nil
 }
+
+func validateKCPOptions(c *v1.KCPOptions) error {
+	var errs error
+	if c.MTU < 50 || c.MTU > 1500 {
+		errs = AppendError(errs, fmt.Errorf("invalid transport.kcp.mtu, should be between 50 and 1500"))
+	}
+	if c.SendWindow <= 0 || c.RecvWindow <= 0 {
+		errs = AppendError(errs, fmt.Errorf("invalid transport.kcp.sendWindow or transport.kcp.recvWindow, should be greater than 0"))
+	}
+	if c.Interval < 0 {
+		errs = AppendError(errs, fmt.Errorf("invalid transport.kcp.interval, should not be negative"))
+	}
+	if (c.DataShards > 0) != (c.ParityShards > 0) {
+		errs = AppendError(errs, fmt.Errorf("transport.kcp.dataShards and transport.kcp.parityShards should be enabled or disabled together"))
+	}
+	return errs
+}
+
+func validateServerEndpoints(c *v1.ClientCommonConfig) error {
+	var errs error
+	if !slices.Contains([]string{"ordered", "weighted"}, c.ServerStrategy) {
+		errs = AppendError(errs, fmt.Errorf("invalid serverStrategy, optional values are ordered and weighted"))
+	}
+
+	names := make(map[string]struct{})
+	for _, s := range c.Servers {
+		if s.ServerAddr == "" {
+			errs = AppendError(errs, fmt.Errorf("servers: serverAddr of [%s] is empty", s.Name))
+		}
+		if _, ok := names[s.Name]; ok {
+			errs = AppendError(errs, fmt.Errorf("servers: duplicate name [%s]", s.Name))
+		}
+		names[s.Name] = struct{}{}
+		if s.Weight < 0 {
+			errs = AppendError(errs, fmt.Errorf("servers: weight of [%s] should not be negative", s.Name))
+		}
+		if s.Auth != nil && !slices.Contains(SupportedAuthMethods, s.Auth.Method) {
+			errs = AppendError(errs, fmt.Errorf("servers: invalid auth method of [%s], optional values are %v", s.Name, SupportedAuthMethods))
+		}
+		if s.Transport != nil {
+			errs = AppendError(errs, validateTransportProtocols(s.Transport, fmt.Sprintf("servers [%s]: transport", s.Name)))
+		}
+	}
+	return errs
+}
+
+func validateTransportProtocols(c *v1.ClientTransportConfig, prefix string) error {
+	var errs error
+	protocols := c.Protocols
+	if len(protocols) == 0 {
+		protocols = []string{c.Protocol}
+	}
+	for _, protocol := range protocols {
+		if !slices.Contains(SupportedTransportProtocols, protocol) {
+			errs = AppendError(errs, fmt.Errorf("invalid %s.protocol [%s], optional values are %v", prefix, protocol, SupportedTransportProtocols))
+		}
+	}
+	if slices.Contains(protocols, "kcp") {
+		errs = AppendError(errs, validateKCPOptions(&c.KCP))
+	}
+	return errs
+}
@@ -0,0 +1,170 @@
+package frpstest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"frpgo/pkg/msg"
+	plugin "frpgo/pkg/plugin/server"
+	netpkg "frpgo/pkg/util/net"
+)
+
+const workConnTimeout = 10 * time.Second
+
+var errControlClosed = errors.New("control is closed")
+
+// control is the control connection of a logged in client.
+type control struct {
+	s          *Server
+	conn       net.Conn
+	login      *msg.Login
+	runID      string
+	dispatcher *msg.Dispatcher
+	workConnCh chan net.Conn
+
+	closeOnce sync.Once
+	// closed after the control and its proxies are closed
+	doneCh chan struct{}
+}
+
+func newControl(s *Server, conn net.Conn, login *msg.Login) (*control, error) {
+	ctl := &control{
+		s:          s,
+		conn:       conn,
+		login:      login,
+		runID:      login.RunID,
+		workConnCh: make(chan net.Conn, 16),
+		doneCh:     make(chan struct{}),
+	}
+
+	// messages of ssh tunnel clients are not encrypted
+	var rw io.ReadWriter = conn
+	if login.ClientSpec.Type != "ssh-tunnel" {
+		cryptoRW, err := netpkg.NewCryptoReadWriter(conn, []byte(s.opts.Token))
+		if err != nil {
+			return nil, err
+		}
+		rw = cryptoRW
+	}
+	ctl.dispatcher = msg.NewDispatcher(rw)
+	ctl.dispatcher.RegisterHandler(&msg.NewProxy{}, ctl.handleNewProxy)
+	ctl.dispatcher.RegisterHandler(&msg.CloseProxy{}, ctl.handleCloseProxy)
+	ctl.dispatcher.RegisterHandler(&msg.Ping{}, ctl.handlePing)
+	ctl.dispatcher.RegisterHandler(&msg.NatHoleVisitor{}, ctl.handleNatHoleVisitor)
+	return ctl, nil
+}
+
+func (ctl *control) run() {
+	ctl.dispatcher.Run()
+	<-ctl.dispatcher.Done()
+	ctl.conn.Close()
+
+	for _, pxy := range ctl.s.removeControl(ctl) {
+		pxy.close()
+	}
+	for {
+		select {
+		case conn := <-ctl.workConnCh:
+			conn.Close()
+		default:
+			close(ctl.doneCh)
+			return
+		}
+	}
+}
+
+func (ctl *control) close() {
+	ctl.closeOnce.Do(func() {
+		ctl.conn.Close()
+	})
+}
+
+func (ctl *control) userInfo() plugin.UserInfo {
+	return plugin.UserInfo{
+		User:  ctl.login.User,
+		Metas: ctl.login.Metas,
+		RunID: ctl.runID,
+	}
+}
+
+func (ctl *control) putWorkConn(conn net.Conn) {
+	select {
+	case ctl.workConnCh <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// getWorkConn requests a work connection from the client and waits for it.
+func (ctl *control) getWorkConn(cancel <-chan struct{}) (net.Conn, error) {
+	if err := ctl.dispatcher.Send(&msg.ReqWorkConn{}); err != nil {
+		return nil, errControlClosed
+	}
+	select {
+	case conn := <-ctl.workConnCh:
+		return conn, nil
+	case <-ctl.dispatcher.Done():
+		return nil, errControlClosed
+	case <-cancel:
+		return nil, errProxyClosed
+	case <-time.After(workConnTimeout):
+		return nil, errors.New("timeout waiting for work connection")
+	}
+}
+
+func (ctl *control) handleNewProxy(m msg.Message) {
+	inMsg := m.(*msg.NewProxy)
+	resp := &msg.NewProxyResp{ProxyName: inMsg.ProxyName}
+
+	content, err := ctl.s.pluginManager.NewProxy(&plugin.NewProxyContent{
+		User:     ctl.userInfo(),
+		NewProxy: *inMsg,
+	})
+	if err == nil {
+		resp.RemoteAddr, err = ctl.s.registerProxy(ctl, &content.NewProxy)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	_ = ctl.dispatcher.Send(resp)
+}
+
+func (ctl *control) handleCloseProxy(m msg.Message) {
+	inMsg := m.(*msg.CloseProxy)
+	_ = ctl.s.pluginManager.CloseProxy(&plugin.CloseProxyContent{
+		User:       ctl.userInfo(),
+		CloseProxy: *inMsg,
+	})
+	if pxy := ctl.s.removeProxy(ctl, inMsg.ProxyName); pxy != nil {
+		pxy.close()
+	}
+}
+
+// handleNatHoleVisitor rejects xtcp visitors, the visitor stops waiting for the
+// nat hole response.
+func (ctl *control) handleNatHoleVisitor(m msg.Message) {
+	inMsg := m.(*msg.NatHoleVisitor)
+	_ = ctl.dispatcher.Send(&msg.NatHoleResp{
+		TransactionID: inMsg.TransactionID,
+		Error:         ErrVisitorNotSupported.Error(),
+	})
+}
+
+func (ctl *control) handlePing(m msg.Message) {
+	inMsg := m.(*msg.Ping)
+	content, err := ctl.s.pluginManager.Ping(&plugin.PingContent{
+		User: ctl.userInfo(),
+		Ping: *inMsg,
+	})
+	if err == nil {
+		err = ctl.s.verifier.VerifyPing(&content.Ping)
+	}
+	resp := &msg.Pong{}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	_ = ctl.dispatcher.Send(resp)
+}
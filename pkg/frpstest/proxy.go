@@ -0,0 +1,226 @@
+package frpstest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	libio "github.com/fatedier/golib/io"
+
+	"frpgo/pkg/msg"
+	"frpgo/pkg/proto/udp"
+	"frpgo/pkg/util/log"
+	netpkg "frpgo/pkg/util/net"
+)
+
+var errProxyClosed = errors.New("proxy is closed")
+
+// serverProxy is a proxy registered by a client, with its listener on 127.0.0.1.
+type serverProxy struct {
+	ctl  *control
+	cfg  *msg.NewProxy
+	addr string
+
+	closeFn   func()
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+// registerProxy starts the proxy and returns the address of its listener.
+func (s *Server) registerProxy(ctl *control, cfg *msg.NewProxy) (string, error) {
+	pxy := &serverProxy{
+		ctl:     ctl,
+		cfg:     cfg,
+		closeCh: make(chan struct{}),
+	}
+	var err error
+	switch cfg.ProxyType {
+	case "tcp":
+		err = pxy.listenTCP()
+	case "udp":
+		err = pxy.listenUDP()
+	default:
+		err = fmt.Errorf("%w: %s", ErrProxyTypeNotSupported, cfg.ProxyType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	_, exist := s.proxies[cfg.ProxyName]
+	if !exist {
+		s.proxies[cfg.ProxyName] = pxy
+	}
+	s.mu.Unlock()
+	if exist {
+		pxy.close()
+		return "", fmt.Errorf("proxy [%s]: %w", cfg.ProxyName, errProxyExist)
+	}
+	return pxy.addr, nil
+}
+
+// removeProxy removes the proxy if it's registered by ctl.
+func (s *Server) removeProxy(ctl *control, name string) *serverProxy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pxy, ok := s.proxies[name]
+	if !ok || pxy.ctl != ctl {
+		return nil
+	}
+	delete(s.proxies, name)
+	return pxy
+}
+
+func (pxy *serverProxy) close() {
+	pxy.closeOnce.Do(func() {
+		close(pxy.closeCh)
+		pxy.closeFn()
+	})
+}
+
+func (pxy *serverProxy) listenTCP() error {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(pxy.cfg.RemotePort)))
+	if err != nil {
+		return err
+	}
+	pxy.addr = ln.Addr().String()
+	pxy.closeFn = func() { ln.Close() }
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go pxy.handleUserConn(c)
+		}
+	}()
+	return nil
+}
+
+func (pxy *serverProxy) handleUserConn(userConn net.Conn) {
+	defer userConn.Close()
+
+	srcAddr, srcPort := addrPort(userConn.RemoteAddr())
+	dstAddr, dstPort := addrPort(userConn.LocalAddr())
+	workConn, err := pxy.startWorkConn(srcAddr, srcPort, dstAddr, dstPort)
+	if err != nil {
+		log.Warnf("[%s] get work connection error: %v", pxy.cfg.ProxyName, err)
+		return
+	}
+	libio.Join(userConn, workConn)
+}
+
+// startWorkConn gets a work connection and starts it for the user connection from src.
+func (pxy *serverProxy) startWorkConn(srcAddr string, srcPort uint16, dstAddr string, dstPort uint16) (net.Conn, error) {
+	workConn, err := pxy.ctl.getWorkConn(pxy.closeCh)
+	if err != nil {
+		return nil, err
+	}
+	if err := msg.WriteMsg(workConn, &msg.StartWorkConn{
+		ProxyName: pxy.cfg.ProxyName,
+		SrcAddr:   srcAddr,
+		SrcPort:   srcPort,
+		DstAddr:   dstAddr,
+		DstPort:   dstPort,
+	}); err != nil {
+		workConn.Close()
+		return nil, err
+	}
+
+	var rwc io.ReadWriteCloser = workConn
+	if pxy.cfg.UseEncryption {
+		rwc, err = libio.WithEncryption(rwc, []byte(pxy.ctl.s.opts.Token))
+		if err != nil {
+			workConn.Close()
+			return nil, err
+		}
+	}
+	if pxy.cfg.UseCompression {
+		rwc = libio.WithCompression(rwc)
+	}
+	return netpkg.WrapReadWriteCloserToConn(rwc, workConn), nil
+}
+
+func (pxy *serverProxy) listenUDP() error {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(pxy.cfg.RemotePort)))
+	if err != nil {
+		return err
+	}
+	udpConn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	pxy.addr = udpConn.LocalAddr().String()
+	pxy.closeFn = func() { udpConn.Close() }
+
+	// packets from the client to users
+	readCh := make(chan *msg.UDPPacket, 1024)
+	// packets from users to the client
+	sendCh := make(chan *msg.UDPPacket, 1024)
+	go udp.ForwardUserConn(udpConn, readCh, sendCh, 1500)
+	go func() {
+		defer close(readCh)
+		for {
+			select {
+			case <-pxy.closeCh:
+				return
+			default:
+			}
+			workConn, err := pxy.startWorkConn("", 0, "", 0)
+			if err != nil {
+				log.Warnf("[%s] get work connection error: %v", pxy.cfg.ProxyName, err)
+				select {
+				case <-pxy.closeCh:
+					return
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+			pxy.serveUDPWorkConn(workConn, readCh, sendCh)
+		}
+	}()
+	return nil
+}
+
+// serveUDPWorkConn forwards packets over the work connection until it's broken or the
+// proxy is closed.
+func (pxy *serverProxy) serveUDPWorkConn(workConn net.Conn, readCh chan<- *msg.UDPPacket, sendCh <-chan *msg.UDPPacket) {
+	defer workConn.Close()
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for {
+			m, err := msg.ReadMsg(workConn)
+			if err != nil {
+				return
+			}
+			// the client sends Ping to keep the work connection alive
+			if p, ok := m.(*msg.UDPPacket); ok {
+				readCh <- p
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-doneCh:
+			return
+		case <-pxy.closeCh:
+			workConn.Close()
+			<-doneCh
+			return
+		case p := <-sendCh:
+			if err := msg.WriteMsg(workConn, p); err != nil {
+				workConn.Close()
+				<-doneCh
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package frpstest provides an in-process frps for tests. It speaks the control protocol
+// with clients over loopback or in-memory pipes, and serves tcp and udp proxies on
+// loopback listeners.
+//
+// Only tcp and udp proxies are supported, proxies of other types fail to start with
+// ErrProxyTypeNotSupported. Visitors aren't supported either: visitor connections of
+// stcp and sudp visitors and nat hole requests of xtcp visitors are answered with
+// ErrVisitorNotSupported, so visitors fail to connect instead of hanging.
+package frpstest
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	libnet "github.com/fatedier/golib/net"
+	fmux "github.com/hashicorp/yamux"
+
+	"frpgo/pkg/auth"
+	v1 "frpgo/pkg/config/v1"
+	"frpgo/pkg/msg"
+	plugin "frpgo/pkg/plugin/server"
+	"frpgo/pkg/transport"
+	netpkg "frpgo/pkg/util/net"
+	"frpgo/pkg/util/util"
+	"frpgo/pkg/util/version"
+)
+
+const connReadTimeout = 10 * time.Second
+
+type Options struct {
+	// Token authenticates clients, it's also the key of encrypted connections.
+	Token string
+	// PluginManager is called on login, new proxy, close proxy, ping and new work
+	// connection like frps does. If it's nil, no plugins are called.
+	PluginManager *plugin.Manager
+}
+
+// Server is an in-process frps listening on a random loopback port. Clients can connect
+// to it over tcp, with or without tls and tcp mux, or through in-memory pipes by
+// NewConnector.
+type Server struct {
+	opts          Options
+	verifier      auth.Verifier
+	pluginManager *plugin.Manager
+	tlsConfig     *tls.Config
+
+	ln     net.Listener
+	pipeLn *netpkg.InternalListener
+
+	mu sync.Mutex
+	// controls of logged in clients, keyed by run id
+	controls map[string]*control
+	// proxies of all clients, keyed by proxy name
+	proxies map[string]*serverProxy
+	closed  bool
+}
+
+// NewServer starts a server on 127.0.0.1 with a random port.
+func NewServer(opts Options) (*Server, error) {
+	tlsConfig, err := transport.NewServerTLSConfig("", "", "")
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		opts: opts,
+		verifier: auth.NewAuthVerifier(v1.AuthServerConfig{
+			Method: v1.AuthMethodToken,
+			Token:  opts.Token,
+		}),
+		pluginManager: opts.PluginManager,
+		tlsConfig:     tlsConfig,
+		ln:            ln,
+		pipeLn:        netpkg.NewInternalListener(),
+		controls:      make(map[string]*control),
+		proxies:       make(map[string]*serverProxy),
+	}
+	if s.pluginManager == nil {
+		s.pluginManager = plugin.NewManager()
+	}
+	go s.serve(s.ln)
+	go s.serve(s.pipeLn)
+	return s, nil
+}
+
+// Port returns the port clients connect to.
+func (s *Server) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// ClientConfig returns a client config to connect to the server. It's not completed.
+func (s *Server) ClientConfig() *v1.ClientCommonConfig {
+	return &v1.ClientCommonConfig{
+		ServerAddr: "127.0.0.1",
+		ServerPort: s.Port(),
+		Auth: v1.AuthClientConfig{
+			Method: v1.AuthMethodToken,
+			Token:  s.opts.Token,
+		},
+	}
+}
+
+// NewConnector returns a connector which connects to the server through in-memory pipes.
+// It can be returned by ServiceOptions.ConnectorCreator.
+func (s *Server) NewConnector() *Connector {
+	return &Connector{ln: s.pipeLn}
+}
+
+// ProxyAddr returns the address of the proxy listener, it's false if the proxy is not
+// registered.
+func (s *Server) ProxyAddr(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pxy, ok := s.proxies[name]
+	if !ok {
+		return "", false
+	}
+	return pxy.addr, true
+}
+
+// RunIDs returns the run ids of logged in clients.
+func (s *Server) RunIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.controls))
+	for id := range s.controls {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// CloseClient closes the control connection of the client, like the server is restarted.
+func (s *Server) CloseClient(runID string) bool {
+	s.mu.Lock()
+	ctl, ok := s.controls[runID]
+	s.mu.Unlock()
+	if ok {
+		ctl.close()
+	}
+	return ok
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	ctls := make([]*control, 0, len(s.controls))
+	for _, ctl := range s.controls {
+		ctls = append(ctls, ctl)
+	}
+	s.mu.Unlock()
+
+	s.ln.Close()
+	s.pipeLn.Close()
+	for _, ctl := range ctls {
+		ctl.close()
+	}
+	return nil
+}
+
+func (s *Server) serve(ln net.Listener) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(c)
+	}
+}
+
+func (s *Server) handleConn(raw net.Conn) {
+	c, _, _, err := netpkg.CheckAndEnableTLSServerConnWithTimeout(raw, s.tlsConfig, false, connReadTimeout)
+	if err != nil {
+		raw.Close()
+		return
+	}
+
+	// A yamux session starts with the protocol version 0, while a message starts
+	// with its type byte.
+	sc, r := libnet.NewSharedConnSize(c, 2)
+	buf := make([]byte, 1)
+	_ = c.SetReadDeadline(time.Now().Add(connReadTimeout))
+	_, err = io.ReadFull(r, buf)
+	_ = c.SetReadDeadline(time.Time{})
+	if err != nil {
+		c.Close()
+		return
+	}
+	if buf[0] != 0 {
+		s.handleStream(sc)
+		return
+	}
+
+	fmuxCfg := fmux.DefaultConfig()
+	fmuxCfg.LogOutput = io.Discard
+	fmuxCfg.MaxStreamWindowSize = 6 * 1024 * 1024
+	session, err := fmux.Server(sc, fmuxCfg)
+	if err != nil {
+		c.Close()
+		return
+	}
+	for {
+		stream, err := session.AcceptStream()
+		if err != nil {
+			session.Close()
+			return
+		}
+		go s.handleStream(stream)
+	}
+}
+
+func (s *Server) handleStream(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(connReadTimeout))
+	m, err := msg.ReadMsg(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	switch m := m.(type) {
+	case *msg.Login:
+		s.handleLogin(conn, m)
+	case *msg.NewWorkConn:
+		s.handleNewWorkConn(conn, m)
+	case *msg.NewVisitorConn:
+		_ = msg.WriteMsg(conn, &msg.NewVisitorConnResp{
+			ProxyName: m.ProxyName,
+			Error:     ErrVisitorNotSupported.Error(),
+		})
+		conn.Close()
+	default:
+		conn.Close()
+	}
+}
+
+func (s *Server) handleLogin(conn net.Conn, m *msg.Login) {
+	content, err := s.pluginManager.Login(&plugin.LoginContent{
+		Login:         *m,
+		ClientAddress: conn.RemoteAddr().String(),
+	})
+	if err == nil {
+		m = &content.Login
+		err = s.verifier.VerifyLogin(m)
+	}
+	if err == nil && m.RunID == "" {
+		m.RunID, err = util.RandID()
+	}
+	if err != nil {
+		_ = msg.WriteMsg(conn, &msg.LoginResp{
+			Version: version.Full(),
+			Error:   err.Error(),
+		})
+		conn.Close()
+		return
+	}
+
+	ctl, err := newControl(s, conn, m)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if err := msg.WriteMsg(conn, &msg.LoginResp{
+		Version: version.Full(),
+		RunID:   m.RunID,
+	}); err != nil {
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	old := s.controls[m.RunID]
+	s.controls[m.RunID] = ctl
+	s.mu.Unlock()
+	// The client reconnects with the same run id, wait until proxies of the old
+	// control are removed, so they can be registered again.
+	if old != nil {
+		old.close()
+		<-old.doneCh
+	}
+	go ctl.run()
+}
+
+func (s *Server) handleNewWorkConn(conn net.Conn, m *msg.NewWorkConn) {
+	s.mu.Lock()
+	ctl, ok := s.controls[m.RunID]
+	s.mu.Unlock()
+	if !ok {
+		conn.Close()
+		return
+	}
+
+	content, err := s.pluginManager.NewWorkConn(&plugin.NewWorkConnContent{
+		User:        ctl.userInfo(),
+		NewWorkConn: *m,
+	})
+	if err == nil {
+		err = s.verifier.VerifyNewWorkConn(&content.NewWorkConn)
+	}
+	if err != nil {
+		_ = msg.WriteMsg(conn, &msg.StartWorkConn{Error: err.Error()})
+		conn.Close()
+		return
+	}
+	ctl.putWorkConn(conn)
+}
+
+// removeControl removes ctl and its proxies after it's closed.
+func (s *Server) removeControl(ctl *control) []*serverProxy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.controls[ctl.runID] == ctl {
+		delete(s.controls, ctl.runID)
+	}
+	var pxys []*serverProxy
+	for name, pxy := range s.proxies {
+		if pxy.ctl == ctl {
+			delete(s.proxies, name)
+			pxys = append(pxys, pxy)
+		}
+	}
+	return pxys
+}
+
+// Connector connects to the server through in-memory pipes. It implements client.Connector.
+type Connector struct {
+	ln *netpkg.InternalListener
+}
+
+func (c *Connector) Open() error {
+	return nil
+}
+
+func (c *Connector) Connect() (net.Conn, error) {
+	c1, c2 := net.Pipe()
+	if err := c.ln.PutConn(c1); err != nil {
+		c1.Close()
+		c2.Close()
+		return nil, err
+	}
+	return c2, nil
+}
+
+func (c *Connector) Close() error {
+	return nil
+}
+
+var (
+	// ErrProxyTypeNotSupported is returned to clients for proxies other than tcp and udp.
+	ErrProxyTypeNotSupported = errors.New("proxy type is not supported by frpstest")
+	// ErrVisitorNotSupported is returned to visitors of all types.
+	ErrVisitorNotSupported = errors.New("visitor is not supported by frpstest")
+
+	errProxyExist = errors.New("proxy is already in use")
+)
+
+func addrPort(addr net.Addr) (string, uint16) {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", 0
+	}
+	p, _ := strconv.Atoi(port)
+	return host, uint16(p)
+}
@@ -0,0 +1,151 @@
+package frpstest
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"frpgo/client"
+	"frpgo/client/proxy"
+	v1 "frpgo/pkg/config/v1"
+)
+
+func newProxyCfg(t *testing.T, proxyType, name string, localPort int) v1.ProxyConfigurer {
+	cfg, err := proxy.NewProxyConfigurer(proxyType, name, "127.0.0.1", localPort, 0)
+	require.NoError(t, err)
+	cfg.Complete("")
+	cfg.GetBaseConfig().Transport.UseEncryption = true
+	cfg.GetBaseConfig().Transport.UseCompression = true
+	return cfg
+}
+
+func TestServerTCPProxy(t *testing.T) {
+	require := require.New(t)
+	s, err := NewServer(Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	// connect over loopback with the default tls and tcp mux
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "tcp", "echo", StartTCPEcho(t))},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+	require.Equal(client.StateLoggedIn, svr.Status().State)
+
+	// the client logs in again with the same run id after the control is closed
+	runIDs := s.RunIDs()
+	require.Len(runIDs, 1)
+	require.True(s.CloseClient(runIDs[0]))
+	require.Eventually(func() bool { return svr.Status().Reconnects == 1 }, 10*time.Second, 50*time.Millisecond)
+	RequireEcho(t, "tcp", s.WaitProxyAddr(t, "echo"))
+	require.Equal(runIDs, s.RunIDs())
+}
+
+func TestServerUDPProxy(t *testing.T) {
+	require := require.New(t)
+	s, err := NewServer(Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	// connect through in-memory pipes
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:    s.ClientConfig(),
+		ProxyCfgs: []v1.ProxyConfigurer{newProxyCfg(t, "udp", "dns", StartUDPEcho(t))},
+		ConnectorCreator: func(context.Context, *v1.ClientCommonConfig) client.Connector {
+			return s.NewConnector()
+		},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	RequireEcho(t, "udp", s.WaitProxyAddr(t, "dns"))
+
+	// traffic of the udp work connection is counted
+	detail, ok := svr.GetProxyDetail("dns")
+	require.True(ok)
+	require.EqualValues(1, detail.Stats.CurConns)
+	require.Positive(detail.Stats.TrafficIn)
+	require.Positive(detail.Stats.TrafficOut)
+}
+
+func TestServerLoginRejected(t *testing.T) {
+	require := require.New(t)
+	s, err := NewServer(Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	cfg := s.ClientConfig()
+	cfg.Auth.Token = "wrong"
+	cfg.LoginFailExit = lo.ToPtr(true)
+	svr, err := client.NewService(client.ServiceOptions{Common: cfg})
+	require.NoError(err)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- svr.Run(context.Background()) }()
+	select {
+	case err := <-errCh:
+		require.ErrorContains(err, "token in login doesn't match token from configuration")
+	case <-time.After(10 * time.Second):
+		t.Fatal("login is not rejected")
+	}
+	require.Empty(s.RunIDs())
+}
+
+func TestServerVisitorNotSupported(t *testing.T) {
+	require := require.New(t)
+	s, err := NewServer(Options{Token: "token"})
+	require.NoError(err)
+	defer s.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	bindPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	visitorCfg := &v1.STCPVisitorConfig{VisitorBaseConfig: v1.VisitorBaseConfig{
+		Name:       "visitor",
+		Type:       "stcp",
+		SecretKey:  "secret",
+		ServerName: "secret-echo",
+		BindAddr:   "127.0.0.1",
+		BindPort:   bindPort,
+	}}
+	svr, err := client.NewService(client.ServiceOptions{
+		Common:      s.ClientConfig(),
+		ProxyCfgs:   []v1.ProxyConfigurer{newProxyCfg(t, "stcp", "secret-echo", StartTCPEcho(t))},
+		VisitorCfgs: []v1.VisitorConfigurer{visitorCfg},
+	})
+	require.NoError(err)
+	go func() { _ = svr.Run(context.Background()) }()
+	defer svr.Close()
+
+	// proxies other than tcp and udp fail to start
+	require.Eventually(func() bool {
+		status, ok := svr.StatusExporter().GetProxyStatus("secret-echo")
+		return ok && status.Phase == proxy.ProxyPhaseStartErr
+	}, 5*time.Second, 20*time.Millisecond)
+	status, _ := svr.StatusExporter().GetProxyStatus("secret-echo")
+	require.Contains(status.Err, ErrProxyTypeNotSupported.Error())
+
+	// the visitor is rejected instead of waiting for the response
+	var conn net.Conn
+	require.Eventually(func() bool {
+		conn, err = net.Dial("tcp", ln.Addr().String())
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(err, io.EOF)
+}
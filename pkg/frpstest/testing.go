@@ -0,0 +1,74 @@
+package frpstest
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// StartTCPEcho starts a tcp echo service on loopback for local services of proxies,
+// it's closed when the test finishes. It returns the port of the service.
+func StartTCPEcho(t testing.TB) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, _ = io.Copy(c, c)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// StartUDPEcho is like StartTCPEcho but for udp.
+func StartUDPEcho(t testing.TB) int {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteToUDP(buf[:n], addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// WaitProxyAddr waits until the proxy is registered and returns its address.
+func (s *Server) WaitProxyAddr(t testing.TB, name string) string {
+	var addr string
+	require.Eventually(t, func() bool {
+		var ok bool
+		addr, ok = s.ProxyAddr(name)
+		return ok
+	}, 5*time.Second, 20*time.Millisecond, "proxy [%s] is not registered", name)
+	return addr
+}
+
+// RequireEcho sends a message to addr and requires it's echoed back.
+func RequireEcho(t testing.TB, network, addr string) {
+	conn, err := net.DialTimeout(network, addr, time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+}
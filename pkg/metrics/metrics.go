@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"frpgo/client/metrics"
+	"frpgo/pkg/metrics/prometheus"
+)
+
+var (
+	prometheusEnabled atomic.Bool
+	enableMu          sync.Mutex
+)
+
+// EnablePrometheus collects metrics of the client agent, exported by Handler.
+// Metrics are registered to the default registry here instead of at init, it returns an error
+// if any of them are already registered.
+func EnablePrometheus() error {
+	enableMu.Lock()
+	defer enableMu.Unlock()
+	if prometheusEnabled.Load() {
+		return nil
+	}
+	m, err := prometheus.NewClientMetrics(promclient.DefaultRegisterer)
+	if err != nil {
+		return err
+	}
+	metrics.Register(m)
+	prometheusEnabled.Store(true)
+	return nil
+}
+
+func PrometheusEnabled() bool {
+	return prometheusEnabled.Load()
+}
+
+// Handler serves metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
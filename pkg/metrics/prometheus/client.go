@@ -0,0 +1,314 @@
+package prometheus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"frpgo/client/metrics"
+	"frpgo/pkg/util/metric"
+)
+
+const (
+	namespace        = "frpgo"
+	clientSubsystem  = "client"
+	webhookSubsystem = "webhook"
+)
+
+type clientMetrics struct {
+	controlConnected metric.GaugeMetric
+	loginAttempts    metric.CounterMetric
+	loginFailures    metric.CounterMetric
+	heartbeatRTT     metric.HistogramMetric
+	workConnLatency  metric.HistogramMetric
+
+	proxyPhase        *prometheus.GaugeVec
+	proxyConns        *prometheus.GaugeVec
+	proxyConnsTotal   *prometheus.CounterVec
+	proxyTrafficIn    *prometheus.CounterVec
+	proxyTrafficOut   *prometheus.CounterVec
+	proxyDialFailures *prometheus.CounterVec
+
+	visitorUp          *prometheus.GaugeVec
+	visitorStartErrors *prometheus.CounterVec
+	visitorConns       *prometheus.CounterVec
+
+	webhookDeliveries *prometheus.CounterVec
+
+	// proxies with metrics, updates of connections and traffic of other proxies are dropped,
+	// so they don't bring back series of removed proxies
+	proxies map[string]*proxyState
+	mu      sync.RWMutex
+}
+
+type proxyState struct {
+	proxyType string
+	phase     string
+	conns     atomic.Int64
+}
+
+func (m *clientMetrics) ControlConnected(connected bool) {
+	if connected {
+		m.controlConnected.Set(1)
+	} else {
+		m.controlConnected.Set(0)
+	}
+}
+
+func (m *clientMetrics) Login(err error) {
+	m.loginAttempts.Inc()
+	if err != nil {
+		m.loginFailures.Inc()
+	}
+}
+
+func (m *clientMetrics) HeartbeatRTT(rtt time.Duration) {
+	m.heartbeatRTT.Observe(rtt.Seconds())
+}
+
+func (m *clientMetrics) WorkConnLatency(d time.Duration) {
+	m.workConnLatency.Observe(d.Seconds())
+}
+
+// ProxyPhase adds the proxy to metrics if it's not added yet.
+func (m *clientMetrics) ProxyPhase(name string, proxyType string, phase string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.proxies[name]
+	if !ok {
+		st = &proxyState{proxyType: proxyType}
+		m.proxies[name] = st
+	}
+	// only the current phase is exported
+	if st.phase != "" {
+		m.proxyPhase.DeleteLabelValues(name, st.proxyType, st.phase)
+	}
+	st.proxyType, st.phase = proxyType, phase
+	m.gauge(m.proxyPhase, name, proxyType, phase).Set(1)
+}
+
+func (m *clientMetrics) OpenConnection(name string, proxyType string) {
+	m.withProxy(name, func(st *proxyState) {
+		st.conns.Add(1)
+		m.gauge(m.proxyConns, name, proxyType).Inc()
+		m.counter(m.proxyConnsTotal, name, proxyType).Inc()
+	})
+}
+
+func (m *clientMetrics) CloseConnection(name string, proxyType string) {
+	m.withProxy(name, func(st *proxyState) {
+		// opened before the proxy is added again
+		for {
+			n := st.conns.Load()
+			if n <= 0 {
+				return
+			}
+			if st.conns.CompareAndSwap(n, n-1) {
+				break
+			}
+		}
+		m.gauge(m.proxyConns, name, proxyType).Dec()
+	})
+}
+
+func (m *clientMetrics) AddTraffic(name string, proxyType string, trafficIn int64, trafficOut int64) {
+	m.withProxy(name, func(*proxyState) {
+		if trafficIn > 0 {
+			m.counter(m.proxyTrafficIn, name, proxyType).Add(float64(trafficIn))
+		}
+		if trafficOut > 0 {
+			m.counter(m.proxyTrafficOut, name, proxyType).Add(float64(trafficOut))
+		}
+	})
+}
+
+func (m *clientMetrics) DialLocalFailed(name string, proxyType string) {
+	m.withProxy(name, func(*proxyState) {
+		m.counter(m.proxyDialFailures, name, proxyType).Inc()
+	})
+}
+
+// withProxy calls fn if the proxy is added, RemoveProxy waits for fn to return.
+func (m *clientMetrics) withProxy(name string, fn func(st *proxyState)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if st, ok := m.proxies[name]; ok {
+		fn(st)
+	}
+}
+
+func (m *clientMetrics) RemoveProxy(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.proxies, name)
+
+	labels := prometheus.Labels{"name": name}
+	m.proxyPhase.DeletePartialMatch(labels)
+	m.proxyConns.DeletePartialMatch(labels)
+	m.proxyConnsTotal.DeletePartialMatch(labels)
+	m.proxyTrafficIn.DeletePartialMatch(labels)
+	m.proxyTrafficOut.DeletePartialMatch(labels)
+	m.proxyDialFailures.DeletePartialMatch(labels)
+}
+
+func (m *clientMetrics) VisitorStarted(name string, visitorType string) {
+	m.gauge(m.visitorUp, name, visitorType).Set(1)
+}
+
+func (m *clientMetrics) VisitorStartFailed(name string, visitorType string) {
+	m.gauge(m.visitorUp, name, visitorType).Set(0)
+	m.counter(m.visitorStartErrors, name, visitorType).Inc()
+}
+
+func (m *clientMetrics) VisitorStopped(name string, visitorType string) {
+	m.visitorUp.DeleteLabelValues(name, visitorType)
+}
+
+func (m *clientMetrics) VisitorConnection(name string, visitorType string) {
+	m.counter(m.visitorConns, name, visitorType).Inc()
+}
+
+func (m *clientMetrics) WebhookDelivery(subscriber string, outcome string) {
+	m.counter(m.webhookDeliveries, subscriber, outcome).Inc()
+}
+
+func (m *clientMetrics) gauge(vec *prometheus.GaugeVec, lvs ...string) metric.GaugeMetric {
+	return vec.WithLabelValues(lvs...)
+}
+
+func (m *clientMetrics) counter(vec *prometheus.CounterVec, lvs ...string) metric.CounterMetric {
+	return vec.WithLabelValues(lvs...)
+}
+
+// NewClientMetrics creates client metrics registered to reg.
+func NewClientMetrics(reg prometheus.Registerer) (metrics.ClientMetrics, error) {
+	m := newClientMetrics()
+	for _, c := range []prometheus.Collector{
+		m.controlConnected.(prometheus.Collector),
+		m.loginAttempts.(prometheus.Collector),
+		m.loginFailures.(prometheus.Collector),
+		m.heartbeatRTT.(prometheus.Collector),
+		m.workConnLatency.(prometheus.Collector),
+		m.proxyPhase,
+		m.proxyConns,
+		m.proxyConnsTotal,
+		m.proxyTrafficIn,
+		m.proxyTrafficOut,
+		m.proxyDialFailures,
+		m.visitorUp,
+		m.visitorStartErrors,
+		m.visitorConns,
+		m.webhookDeliveries,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func newClientMetrics() *clientMetrics {
+	controlConnected := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: clientSubsystem,
+		Name:      "control_connected",
+		Help:      "Whether the control connection to the server is established",
+	})
+	loginAttempts := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: clientSubsystem,
+		Name:      "login_attempts_total",
+		Help:      "The total number of login attempts",
+	})
+	loginFailures := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: clientSubsystem,
+		Name:      "login_failures_total",
+		Help:      "The total number of failed login attempts",
+	})
+	heartbeatRTT := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: clientSubsystem,
+		Name:      "heartbeat_rtt_seconds",
+		Help:      "The round trip time of heartbeats to the server",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	workConnLatency := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: clientSubsystem,
+		Name:      "work_conn_latency_seconds",
+		Help:      "The time of creating a work connection to the server",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	m := &clientMetrics{
+		controlConnected: controlConnected,
+		loginAttempts:    loginAttempts,
+		loginFailures:    loginFailures,
+		heartbeatRTT:     heartbeatRTT,
+		workConnLatency:  workConnLatency,
+		proxyPhase: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "proxy_phase",
+			Help:      "The current phase of proxies, always 1",
+		}, []string{"name", "type", "phase"}),
+		proxyConns: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "proxy_connections",
+			Help:      "The current work connections of proxies",
+		}, []string{"name", "type"}),
+		proxyConnsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "proxy_connections_total",
+			Help:      "The total number of work connections of proxies",
+		}, []string{"name", "type"}),
+		proxyTrafficIn: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "proxy_traffic_in_bytes_total",
+			Help:      "The total bytes read from work connections of proxies",
+		}, []string{"name", "type"}),
+		proxyTrafficOut: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "proxy_traffic_out_bytes_total",
+			Help:      "The total bytes written to work connections of proxies",
+		}, []string{"name", "type"}),
+		proxyDialFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "proxy_dial_failures_total",
+			Help:      "The total number of failures of dialing to local services",
+		}, []string{"name", "type"}),
+		visitorUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "visitor_up",
+			Help:      "Whether visitors are running",
+		}, []string{"name", "type"}),
+		visitorStartErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "visitor_start_errors_total",
+			Help:      "The total number of failures of starting visitors",
+		}, []string{"name", "type"}),
+		visitorConns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: clientSubsystem,
+			Name:      "visitor_connections_total",
+			Help:      "The total number of user connections of visitors",
+		}, []string{"name", "type"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: webhookSubsystem,
+			Name:      "deliveries_total",
+			Help:      "The total number of webhook delivery attempts by outcome",
+		}, []string{"subscriber", "outcome"}),
+		proxies: make(map[string]*proxyState),
+	}
+	return m
+}
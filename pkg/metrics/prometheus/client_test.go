@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMetrics(t *testing.T) {
+	require := require.New(t)
+	reg := prometheus.NewRegistry()
+	cm, err := NewClientMetrics(reg)
+	require.NoError(err)
+	m := cm.(*clientMetrics)
+
+	m.Login(nil)
+	m.Login(errors.New("refused"))
+	require.EqualValues(2, testutil.ToFloat64(m.loginAttempts.(prometheus.Counter)))
+	require.EqualValues(1, testutil.ToFloat64(m.loginFailures.(prometheus.Counter)))
+
+	m.ProxyPhase("web", "http", "wait start")
+	m.ProxyPhase("web", "http", "running")
+	require.EqualValues(1, testutil.CollectAndCount(m.proxyPhase))
+	require.EqualValues(1, testutil.ToFloat64(m.proxyPhase.WithLabelValues("web", "http", "running")))
+
+	m.OpenConnection("web", "http")
+	m.OpenConnection("web", "http")
+	m.AddTraffic("web", "http", 10, 0)
+	m.AddTraffic("web", "http", 0, 20)
+	m.CloseConnection("web", "http")
+	require.EqualValues(1, testutil.ToFloat64(m.proxyConns.WithLabelValues("web", "http")))
+	require.EqualValues(2, testutil.ToFloat64(m.proxyConnsTotal.WithLabelValues("web", "http")))
+	require.EqualValues(20, testutil.ToFloat64(m.proxyTrafficOut.WithLabelValues("web", "http")))
+
+	expected := `
+# HELP frpgo_client_proxy_traffic_in_bytes_total The total bytes read from work connections of proxies
+# TYPE frpgo_client_proxy_traffic_in_bytes_total counter
+frpgo_client_proxy_traffic_in_bytes_total{name="web",type="http"} 10
+`
+	require.NoError(testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"frpgo_client_proxy_traffic_in_bytes_total"))
+
+	m.RemoveProxy("web")
+	require.EqualValues(0, testutil.CollectAndCount(m.proxyPhase))
+	require.EqualValues(0, testutil.CollectAndCount(m.proxyTrafficIn))
+
+	// updates of removed proxies don't bring back their series
+	m.CloseConnection("web", "http")
+	m.AddTraffic("web", "http", 10, 10)
+	m.DialLocalFailed("web", "http")
+	require.EqualValues(0, testutil.CollectAndCount(m.proxyConns))
+	require.EqualValues(0, testutil.CollectAndCount(m.proxyTrafficIn))
+	require.EqualValues(0, testutil.CollectAndCount(m.proxyDialFailures))
+
+	// connections opened before the proxy is added again are not counted
+	m.ProxyPhase("web", "http", "running")
+	m.CloseConnection("web", "http")
+	require.EqualValues(0, testutil.CollectAndCount(m.proxyConns))
+
+	_, err = NewClientMetrics(reg)
+	require.Error(err)
+}
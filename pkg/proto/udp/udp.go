@@ -103,8 +103,15 @@ func Forwarder(dstAddr *net.UDPAddr, readCh <-chan *msg.UDPPacket, sendCh chan<-
 		}
 	}
 
-	// read from readCh
+	// read from readCh, connections to dstAddr are closed after readCh is closed
 	go func() {
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, udpConn := range udpConnMap {
+				udpConn.Close()
+			}
+		}()
 		for udpMsg := range readCh {
 			buf, err := GetContent(udpMsg)
 			if err != nil {
@@ -26,6 +26,7 @@ type GaugeMetric interface {
 // goes up.
 type CounterMetric interface {
 	Inc()
+	Add(float64)
 }
 
 // HistogramMetric counts individual observations.
@@ -175,13 +175,13 @@ func WrapStatsConn(conn net.Conn, statsFunc func(total, totalWrite int64)) *Stat
 
 func (statsConn *StatsConn) Read(p []byte) (n int, err error) {
 	n, err = statsConn.Conn.Read(p)
-	statsConn.totalRead += int64(n)
+	atomic.AddInt64(&statsConn.totalRead, int64(n))
 	return
 }
 
 func (statsConn *StatsConn) Write(p []byte) (n int, err error) {
 	n, err = statsConn.Conn.Write(p)
-	statsConn.totalWrite += int64(n)
+	atomic.AddInt64(&statsConn.totalWrite, int64(n))
 	return
 }
 
@@ -190,7 +190,7 @@ func (statsConn *StatsConn) Close() (err error) {
 	if old != 1 {
 		err = statsConn.Conn.Close()
 		if statsConn.statsFunc != nil {
-			statsConn.statsFunc(statsConn.totalRead, statsConn.totalWrite)
+			statsConn.statsFunc(atomic.LoadInt64(&statsConn.totalRead), atomic.LoadInt64(&statsConn.totalWrite))
 		}
 	}
 	return